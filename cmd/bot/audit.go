@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// runAuditCommand runs "audit verify [path...]", checking the given audit
+// stores, or the configured ones, for tampering. It returns the exit code.
+func runAuditCommand(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintf(os.Stderr, "Usage: %s audit verify [path...]\n", os.Args[0])
+		return 2
+	}
+
+	paths := args[1:]
+	if len(paths) == 0 {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+		for _, path := range []string{cfg.Audit.File, cfg.Audit.Database} {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "No audit log configured")
+		return 1
+	}
+
+	code := 0
+	for _, path := range paths {
+		count, err := verifyAuditStore(path)
+		if err != nil {
+			fmt.Printf("%s: FAILED after %d entries: %v\n", path, count, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: OK, %d entries\n", path, count)
+	}
+	return code
+}
+
+// verifyAuditStore opens and verifies one audit store
+func verifyAuditStore(path string) (int, error) {
+	s, err := audit.OpenStore(path)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	return audit.Verify(s)
+}
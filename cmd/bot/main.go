@@ -1,12 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/bot"
 	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
 )
 
 var (
@@ -39,34 +55,150 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "audit" {
+		os.Exit(runAuditCommand(flag.Args()[1:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if err := setupLogging(&cfg.Logging); err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+
 	fmt.Printf("Discord Command Executor v%s\n", version)
 	fmt.Printf("Starting bot with config: %s\n", *configFile)
-	fmt.Printf("Bot token configured: %t\n", cfg.Bot.Token != "")
 
-	// TODO: Initialize and start the Discord bot
-	// This is a placeholder until the actual bot implementation is ready
-	fmt.Println("Bot initialization would happen here...")
+	exec, err := executor.New(context.Background(), cfg.Executor, cfg.Docker)
+	if err != nil {
+		log.Fatalf("Failed to initialize executor: %v", err)
+	}
+
+	auditLog, err := audit.Open(cfg.Audit)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+
+	var db *store.Store
+	if cfg.Storage.Path != "" {
+		if db, err = store.Open(cfg.Storage.Path); err != nil {
+			log.Fatalf("Failed to open store: %v", err)
+		}
+	}
+
+	b, err := bot.New(cfg.Bot, exec, auditLog, db, cfg.Storage.History)
+	if err != nil {
+		log.Fatalf("Failed to create bot: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := b.Start(); err != nil {
+		log.Fatalf("Failed to start bot: %v", err)
+	}
+
+	metrics := serveMetrics(&cfg.Server)
+
 	fmt.Println("Press Ctrl+C to stop")
 
-	// Keep the application running
-	select {}
+	<-ctx.Done()
+	// A second signal kills the process without waiting
+	stop()
+
+	grace := time.Duration(cfg.Bot.ShutdownGrace) * time.Second
+	logrus.WithField("grace", grace).Info("Received signal, shutting down")
+	shutdown(b, grace, metrics, exec, db, auditLog)
+}
+
+// shutdown drains the bot's executions and closes the Discord session and
+// metrics server within grace, then closes the executor, store and audit log
+func shutdown(b *bot.Bot, grace time.Duration, metrics *http.Server, exec executor.Backend,
+	db *store.Store, auditLog *audit.Log) {
+	started := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("Failed to close Discord session")
+	}
+
+	if err := metrics.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("Failed to stop metrics server")
+	}
+
+	// Containers of executions stopped after the grace period are removed here
+	if err := exec.Close(); err != nil {
+		logrus.WithError(err).Warn("Failed to close executor")
+	}
+	if err := db.Close(); err != nil {
+		logrus.WithError(err).Warn("Failed to close store")
+	}
+	if err := auditLog.Close(); err != nil {
+		logrus.WithError(err).Warn("Failed to close audit log")
+	}
+
+	logrus.WithField("duration", time.Since(started).Round(time.Millisecond)).Info("Shutdown complete")
+}
+
+// setupLogging configures the global logger from the logging configuration
+func setupLogging(cfg *config.LoggingConfig) error {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(level)
+	logrus.SetReportCaller(cfg.ReportCaller)
+
+	if strings.EqualFold(cfg.Format, "json") {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	if cfg.OutputFile != "" {
+		f, err := os.OpenFile(cfg.OutputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		logrus.SetOutput(f)
+	}
+
+	return nil
+}
+
+// serveMetrics exposes runtime metrics, including throttled requests, as
+// JSON at /debug/vars on the server address in the background
+func serveMetrics(cfg *config.ServerConfig) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{
+		Addr:         net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Handler:      mux,
+		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
+	}
+	logrus.WithField("addr", server.Addr).Info("Serving metrics")
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Error("Metrics server stopped")
+		}
+	}()
+	return server
 }
 
 func showHelpMessage() {
 	fmt.Printf("Discord Command Executor v%s\n\n", version)
 	fmt.Println("Usage:")
-	fmt.Printf("  %s [options]\n\n", os.Args[0])
+	fmt.Printf("  %s [options]\n", os.Args[0])
+	fmt.Printf("  %s audit verify [path...]\n\n", os.Args[0])
 	fmt.Println("Options:")
 	flag.PrintDefaults()
 	fmt.Println("\nCommands:")
 	fmt.Println("  health    Perform health check")
 	fmt.Println("  version   Show version information")
+	fmt.Println("  audit verify   Check the audit log for tampering; defaults to the configured stores")
 }
 
 func showVersion() {
@@ -0,0 +1,278 @@
+// Package audit keeps a tamper-evident record of executions. Each entry
+// carries the hash of the one before it, so editing, removing or reordering
+// entries breaks the chain and is caught by Verify.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Events recorded in the log
+const (
+	// Code ran to completion, failed to run or was cancelled
+	EventExecution = "execution"
+
+	// An interactive session ended
+	EventSession = "session"
+
+	// A policy rule refused to run the code
+	EventBlocked = "blocked"
+)
+
+// Outcomes of entries without a run phase termination reason
+const (
+	OutcomeBlocked       = "blocked"
+	OutcomeCompileFailed = "compile_failed"
+	OutcomeFailed        = "failed"
+)
+
+// Entry is one audited event. Times are UTC and durations are whole
+// milliseconds so that entries encode the same way after a round trip.
+type Entry struct {
+	// Position in the log, from 1
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+
+	Event       string `json:"event"`
+	ExecutionID string `json:"execution_id,omitempty"`
+
+	// Who ran the code and where; the guild is empty for direct messages
+	GuildID   string `json:"guild_id,omitempty"`
+	ChannelID string `json:"channel_id"`
+	UserID    string `json:"user_id"`
+
+	// What ran: the language and a SHA-256 of the code and uploaded files
+	Language   string `json:"language"`
+	SourceHash string `json:"source_hash,omitempty"`
+
+	// Limits the code ran with
+	Tier        string `json:"tier,omitempty"`
+	Network     string `json:"network,omitempty"`
+	MemoryLimit uint64 `json:"memory_limit,omitempty"`
+	TimeoutMS   int64  `json:"timeout_ms,omitempty"`
+
+	// Policy rule the code matched, if any
+	PolicyRule string `json:"policy_rule,omitempty"`
+
+	// Termination reason of the run phase, or why there was none
+	Outcome  string `json:"outcome"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+
+	// Time waiting for a slot, compiling and running, and CPU time used
+	QueuedMS   int64  `json:"queued_ms"`
+	CompileMS  int64  `json:"compile_ms,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	CPUTimeMS  int64  `json:"cpu_time_ms"`
+	PeakMemory uint64 `json:"peak_memory,omitempty"`
+
+	// Hash of the previous entry, empty for the first, and of this one
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash returns the SHA-256 of the entry encoded without its own hash
+func (e *Entry) computeHash() (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Store persists entries in order
+type Store interface {
+	// Append stores e after the last entry
+	Append(e *Entry) error
+
+	// Last returns the most recent entry, or nil if there are none
+	Last() (*Entry, error)
+
+	// Scan calls fn with every entry in order, stopping at the first error
+	Scan(fn func(*Entry) error) error
+
+	Close() error
+}
+
+// Log appends hash-chained entries to one or more stores
+type Log struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	stores []Store
+
+	// Sequence number and hash of the last entry
+	seq  uint64
+	prev string
+}
+
+// Open opens the stores in the configuration. A log without stores
+// records nothing.
+func Open(cfg config.AuditConfig) (*Log, error) {
+	var stores []Store
+	closeAll := func() {
+		for _, s := range stores {
+			s.Close()
+		}
+	}
+
+	if cfg.File != "" {
+		s, err := OpenFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, s)
+	}
+	if cfg.Database != "" {
+		s, err := OpenSQLite(cfg.Database)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		stores = append(stores, s)
+	}
+
+	l, err := NewLog(time.Now, stores...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return l, nil
+}
+
+// OpenStore opens an existing store by path: SQLite for .db, .sqlite and
+// .sqlite3 files, JSON Lines otherwise
+func OpenStore(path string) (Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return OpenSQLite(path)
+	}
+	return OpenFile(path)
+}
+
+// NewLog creates a log continuing the chain in stores, which must all end
+// with the same entry
+func NewLog(now func() time.Time, stores ...Store) (*Log, error) {
+	l := &Log{now: now, stores: stores}
+	for i, s := range stores {
+		last, err := s.Last()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		var seq uint64
+		var hash string
+		if last != nil {
+			seq, hash = last.Seq, last.Hash
+		}
+		if i > 0 && (seq != l.seq || hash != l.prev) {
+			return nil, errors.New("audit stores end with different entries; verify them with `bot audit verify`")
+		}
+		l.seq, l.prev = seq, hash
+	}
+	return l, nil
+}
+
+// Record chains e to the log and appends it to every store. It fills in
+// the sequence number, time and hashes.
+func (l *Log) Record(e Entry) error {
+	if l == nil || len(l.stores) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.Time = l.now().UTC()
+	e.PrevHash = l.prev
+	hash, err := e.computeHash()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+	e.Hash = hash
+
+	var errs []error
+	for _, s := range l.stores {
+		if err := s.Append(&e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// The chain moves on even if a store failed, so the others stay valid
+	l.seq, l.prev = e.Seq, e.Hash
+	return errors.Join(errs...)
+}
+
+// Close closes every store
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range l.stores {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Verify checks that the entries in s form an unbroken chain from the
+// first, returning how many there are. The error names the first entry
+// that was altered, removed or inserted.
+func Verify(s Store) (int, error) {
+	var count int
+	var prev string
+
+	err := s.Scan(func(e *Entry) error {
+		count++
+		if e.Seq != uint64(count) {
+			return fmt.Errorf("entry %d: expected sequence number %d; entries were removed or reordered",
+				e.Seq, count)
+		}
+		if e.PrevHash != prev {
+			return fmt.Errorf("entry %d: does not follow the previous entry", e.Seq)
+		}
+		hash, err := e.computeHash()
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", e.Seq, err)
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("entry %d: contents do not match its hash", e.Seq)
+		}
+		prev = e.Hash
+		return nil
+	})
+	return count, err
+}
+
+// SourceHash returns the SHA-256 of inline code and uploaded files, in
+// path order, so the same submission always hashes the same
+func SourceHash(code string, files []executor.File) string {
+	sorted := append([]executor.File(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	h := sha256.New()
+	// Lengths keep the boundaries between parts unambiguous
+	fmt.Fprintf(h, "code %d\n%s", len(code), code)
+	for _, f := range sorted {
+		fmt.Fprintf(h, "\nfile %s %d\n", f.Path, len(f.Content))
+		h.Write(f.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// recordEntries records n executions and closes the log
+func recordEntries(t *testing.T, cfg config.AuditConfig, n int) {
+	t.Helper()
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < n; i++ {
+		err := l.Record(Entry{
+			Event:      EventExecution,
+			ChannelID:  "c1",
+			UserID:     "u1",
+			Language:   "python",
+			SourceHash: SourceHash("print(1)", nil),
+			Outcome:    string(executor.TerminationSuccess),
+			DurationMS: 120,
+		})
+		if err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+}
+
+// verifyPath verifies the store at path
+func verifyPath(t *testing.T, path string) (int, error) {
+	t.Helper()
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer s.Close()
+	return Verify(s)
+}
+
+func TestLogChainsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AuditConfig{File: filepath.Join(dir, "audit.jsonl"), Database: filepath.Join(dir, "audit.db")}
+
+	recordEntries(t, cfg, 2)
+	recordEntries(t, cfg, 1)
+
+	for _, path := range []string{cfg.File, cfg.Database} {
+		count, err := verifyPath(t, path)
+		if err != nil {
+			t.Errorf("Expected %s to verify, got %v", path, err)
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 entries in %s, got %d", path, count)
+		}
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		reason string
+	}{
+		{
+			name: "edited entry",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(`"user_id":"u1"`), []byte(`"user_id":"u2"`), 1)
+				return lines
+			},
+			reason: "entry 2: contents do not match its hash",
+		},
+		{
+			name:   "removed entry",
+			tamper: func(lines [][]byte) [][]byte { return append(lines[:1], lines[2:]...) },
+			reason: "entries were removed or reordered",
+		},
+		{
+			name:   "truncated line",
+			tamper: func(lines [][]byte) [][]byte { return append(lines[:2], lines[2][:10]) },
+			reason: "line 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			recordEntries(t, config.AuditConfig{File: path}, 3)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")))
+			if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := verifyPath(t, path); err == nil || !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("Expected error containing %q, got %v", tt.reason, err)
+			}
+		})
+	}
+}
+
+func TestSQLiteIsAppendOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	recordEntries(t, config.AuditConfig{Database: path}, 1)
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.db.Exec("UPDATE audit_log SET entry = '{}'"); err == nil {
+		t.Error("Expected updates to be refused")
+	}
+	if _, err := s.db.Exec("DELETE FROM audit_log"); err == nil {
+		t.Error("Expected deletes to be refused")
+	}
+}
+
+func TestNewLogRejectsDivergedStores(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "audit.jsonl")
+	recordEntries(t, config.AuditConfig{File: file}, 1)
+
+	_, err := Open(config.AuditConfig{File: file, Database: filepath.Join(dir, "audit.db")})
+	if err == nil {
+		t.Error("Expected a store missing entries to be rejected")
+	}
+}
+
+func TestLogWithoutStores(t *testing.T) {
+	l, err := NewLog(time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record(Entry{Event: EventExecution}); err != nil {
+		t.Errorf("Expected a log without stores to record nothing, got %v", err)
+	}
+
+	var nilLog *Log
+	if err := nilLog.Record(Entry{Event: EventExecution}); err != nil {
+		t.Errorf("Expected a nil log to record nothing, got %v", err)
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	a := SourceHash("", []executor.File{{Path: "a.py", Content: []byte("1")}, {Path: "b.py", Content: []byte("2")}})
+	b := SourceHash("", []executor.File{{Path: "b.py", Content: []byte("2")}, {Path: "a.py", Content: []byte("1")}})
+	if a != b {
+		t.Error("Expected file order not to change the hash")
+	}
+	if SourceHash("ab", nil) == SourceHash("a", []executor.File{{Path: "b"}}) {
+		t.Error("Expected code and files to hash differently")
+	}
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Longest line read back from a log file
+const maxLineBytes = 1 << 20
+
+// FileStore appends entries to a JSON Lines file, one entry per line
+type FileStore struct {
+	path string
+	file *os.File
+
+	// Most recent entry, read from the file on first use
+	last   *Entry
+	loaded bool
+}
+
+// OpenFile opens or creates a JSON Lines log file for appending
+func OpenFile(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileStore{path: path, file: file}, nil
+}
+
+// Append writes e as a line and syncs it to disk
+func (s *FileStore) Append(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	last := *e
+	s.last, s.loaded = &last, true
+	return nil
+}
+
+// Last returns the most recent entry, or nil if there are none
+func (s *FileStore) Last() (*Entry, error) {
+	if s.loaded {
+		return s.last, nil
+	}
+	err := s.Scan(func(e *Entry) error {
+		s.last = e
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.loaded = true
+	return s.last, nil
+}
+
+// Scan reads the file from the start and calls fn with every entry
+func (s *FileStore) Scan(fn func(*Entry) error) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Close closes the file
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
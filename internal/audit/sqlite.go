@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	// Registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// Schema of the audit table; triggers refuse changes to stored entries
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq   INTEGER PRIMARY KEY,
+	hash  TEXT NOT NULL,
+	entry TEXT NOT NULL
+);
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+`
+
+// SQLiteStore keeps entries in a SQLite database, one row per entry
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates a SQLite audit database
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit table: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Append inserts e as a row
+func (s *SQLiteStore) Append(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("INSERT INTO audit_log (seq, hash, entry) VALUES (?, ?, ?)", e.Seq, e.Hash, data); err != nil {
+		return fmt.Errorf("failed to write audit database: %w", err)
+	}
+	return nil
+}
+
+// Last returns the entry with the highest sequence number, or nil if there
+// are none
+func (s *SQLiteStore) Last() (*Entry, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT entry FROM audit_log ORDER BY seq DESC LIMIT 1").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Scan calls fn with every entry in sequence order
+func (s *SQLiteStore) Scan(fn func(*Entry) error) error {
+	rows, err := s.db.Query("SELECT seq, entry FROM audit_log ORDER BY seq")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seq uint64
+		var data []byte
+		if err := rows.Scan(&seq, &data); err != nil {
+			return err
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("row %d: %w", seq, err)
+		}
+		if e.Seq != seq {
+			return fmt.Errorf("row %d: holds entry %d", seq, e.Seq)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
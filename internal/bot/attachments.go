@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// downloadTimeout bounds fetching a single attachment from Discord's CDN
+const downloadTimeout = 30 * time.Second
+
+// uploadBudget tracks uploaded files and bytes against the configured limits
+type uploadBudget struct {
+	maxBytes int
+	maxFiles int
+	bytes    int
+	files    int
+}
+
+// remaining returns how many more bytes may be accepted
+func (u *uploadBudget) remaining() int {
+	return u.maxBytes - u.bytes
+}
+
+// add accounts for one more file of the given size
+func (u *uploadBudget) add(size int) error {
+	if u.files+1 > u.maxFiles {
+		return fmt.Errorf("too many files; the limit is %d", u.maxFiles)
+	}
+	if u.bytes+size > u.maxBytes {
+		return fmt.Errorf("uploads are too large; the limit is %d bytes in total", u.maxBytes)
+	}
+	u.files++
+	u.bytes += size
+	return nil
+}
+
+// collectAttachments downloads message attachments and expands zip and tar
+// archives into individual files, enforcing the upload limits throughout
+func (b *Bot) collectAttachments(ctx context.Context,
+	attachments []*discordgo.MessageAttachment) ([]executor.File, error) {
+	budget := &uploadBudget{maxBytes: b.cfg.MaxUploadBytes, maxFiles: b.cfg.MaxUploadFiles}
+
+	var files []executor.File
+	for _, att := range attachments {
+		if att.Size > budget.remaining() {
+			return nil, fmt.Errorf("%s is too large; the limit is %d bytes in total", att.Filename, budget.maxBytes)
+		}
+
+		data, err := download(ctx, att.URL, budget.remaining())
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", att.Filename, err)
+		}
+
+		expanded, err := expandUpload(att.Filename, data, budget)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, expanded...)
+	}
+
+	return files, nil
+}
+
+// download fetches url, failing if the body exceeds limit bytes
+func download(ctx context.Context, url string, limit int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("file exceeds the %d byte upload limit", limit)
+	}
+
+	return data, nil
+}
+
+// expandUpload turns an uploaded file into workspace files, extracting
+// archives by extension
+func expandUpload(name string, data []byte, budget *uploadBudget) ([]executor.File, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return extractZip(data, budget)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer gz.Close()
+		return extractTar(gz, budget)
+	case strings.HasSuffix(lower, ".tar"):
+		return extractTar(bytes.NewReader(data), budget)
+	}
+
+	cleaned, err := executor.CleanPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := budget.add(len(data)); err != nil {
+		return nil, err
+	}
+	return []executor.File{{Path: cleaned, Content: data}}, nil
+}
+
+// extractZip reads regular files from a zip archive
+func extractZip(data []byte, budget *uploadBudget) ([]executor.File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	var files []executor.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return nil, fmt.Errorf("archive entry %s is not a regular file", f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive entry %s: %w", f.Name, err)
+		}
+		file, err := readArchiveEntry(f.Name, rc, budget)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// extractTar reads regular files from a tar stream
+func extractTar(r io.Reader, budget *uploadBudget) ([]executor.File, error) {
+	tr := tar.NewReader(r)
+
+	var files []executor.File
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("archive entry %s is not a regular file", header.Name)
+		}
+
+		file, err := readArchiveEntry(header.Name, tr, budget)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+}
+
+// readArchiveEntry validates an entry's path and reads it within the budget.
+// Sizes declared in archive headers are not trusted; the read itself is bounded.
+func readArchiveEntry(name string, r io.Reader, budget *uploadBudget) (executor.File, error) {
+	cleaned, err := executor.CleanPath(name)
+	if err != nil {
+		return executor.File{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(budget.remaining())+1))
+	if err != nil {
+		return executor.File{}, fmt.Errorf("failed to read archive entry %s: %w", name, err)
+	}
+	if err := budget.add(len(data)); err != nil {
+		return executor.File{}, err
+	}
+
+	return executor.File{Path: cleaned, Content: data}, nil
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpandUploadPlainFile(t *testing.T) {
+	budget := &uploadBudget{maxBytes: 1024, maxFiles: 5}
+
+	files, err := expandUpload("util.py", []byte("x = 1"), budget)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "util.py" {
+		t.Errorf("Expected util.py, got %+v", files)
+	}
+}
+
+func TestExpandUploadZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"main.py": "import pkg.util", "pkg/util.py": "x = 1"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+
+	budget := &uploadBudget{maxBytes: 1024, maxFiles: 5}
+	files, err := expandUpload("project.zip", buf.Bytes(), budget)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(files))
+	}
+}
+
+func TestExpandUploadTarRejectsTraversal(t *testing.T) {
+	archive := buildTar(t, map[string]string{"../../etc/cron.d/evil": "* * * * * root sh"})
+
+	_, err := expandUpload("evil.tar", archive, &uploadBudget{maxBytes: 1024, maxFiles: 5})
+	if err == nil {
+		t.Fatal("Expected path traversal to be rejected")
+	}
+}
+
+func TestExpandUploadEnforcesLimits(t *testing.T) {
+	archive := buildTar(t, map[string]string{"big.txt": strings.Repeat("x", 2048)})
+	if _, err := expandUpload("big.tar", archive, &uploadBudget{maxBytes: 1024, maxFiles: 5}); err == nil {
+		t.Error("Expected byte limit to be enforced")
+	}
+
+	archive = buildTar(t, map[string]string{"a.py": "", "b.py": "", "c.py": ""})
+	if _, err := expandUpload("many.tar", archive, &uploadBudget{maxBytes: 1024, maxFiles: 2}); err == nil {
+		t.Error("Expected file count limit to be enforced")
+	}
+}
+
+// buildTar creates a tar archive holding the given files
+func buildTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	return buf.Bytes()
+}
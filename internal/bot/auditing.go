@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+)
+
+// record adds an entry to the audit log. Failures are logged rather than
+// refusing service, as the log can be checked for gaps with verify.
+func (b *Bot) record(e audit.Entry) {
+	if err := b.audit.Record(e); err != nil {
+		logrus.WithError(err).WithField("event", e.Event).Error("Failed to write audit log")
+	}
+}
+
+// recordRun audits a run that finished with res, failed with err, or was
+// cancelled, after waiting queued for a slot
+func (b *Bot) recordRun(exec *execution, who member, cmd *runCommand, queued time.Duration,
+	res *executor.Result, err error) {
+	e := audit.Entry{
+		Event:       audit.EventExecution,
+		ExecutionID: exec.ID,
+		GuildID:     who.GuildID,
+		ChannelID:   exec.ChannelID,
+		UserID:      who.UserID,
+		Language:    cmd.Language,
+		SourceHash:  audit.SourceHash(cmd.Code, cmd.Files),
+		Tier:        string(cmd.Tier),
+		Network:     string(cmd.Network),
+		QueuedMS:    queued.Milliseconds(),
+	}
+	if cmd.Policy != nil {
+		e.PolicyRule = cmd.Policy.Rule
+	}
+
+	switch {
+	case res != nil:
+		e.Language = res.Language
+		e.Outcome = string(res.Reason)
+		e.ExitCode = res.ExitCode
+		e.MemoryLimit = res.MemoryLimit
+		e.TimeoutMS = res.Timeout.Milliseconds()
+		e.DurationMS = res.Duration.Milliseconds()
+		e.CPUTimeMS = res.CPUTime.Milliseconds()
+		e.PeakMemory = res.PeakMemory
+		if res.Compile != nil {
+			e.CompileMS = res.Compile.Duration.Milliseconds()
+			e.CPUTimeMS += res.Compile.CPUTime.Milliseconds()
+			if res.CompileFailed() {
+				e.Outcome = audit.OutcomeCompileFailed
+				e.ExitCode = res.Compile.ExitCode
+			}
+		}
+	case exec.CanceledBy() != "" || exec.ctx.Err() != nil:
+		e.Outcome = string(executor.TerminationCanceled)
+	default:
+		e.Outcome = audit.OutcomeFailed
+		e.Error = err.Error()
+	}
+
+	b.record(e)
+}
+
+// recordSession audits an interactive session that ended for reason, or
+// failed to start with err
+func (b *Bot) recordSession(id string, who member, channelID, language string, started time.Time,
+	reason string, err error) {
+	e := audit.Entry{
+		Event:       audit.EventSession,
+		ExecutionID: id,
+		GuildID:     who.GuildID,
+		ChannelID:   channelID,
+		UserID:      who.UserID,
+		Language:    language,
+		Tier:        string(executor.TierStandard),
+		Network:     string(executor.NetworkIsolated),
+		Outcome:     reason,
+		DurationMS:  time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	b.record(e)
+}
+
+// recordBlocked audits code a policy rule refused to run
+func (b *Bot) recordBlocked(m member, channelID, language, source string, match *policy.Match) {
+	b.record(audit.Entry{
+		Event:      audit.EventBlocked,
+		GuildID:    m.GuildID,
+		ChannelID:  channelID,
+		UserID:     m.UserID,
+		Language:   language,
+		SourceHash: audit.SourceHash(source, nil),
+		PolicyRule: match.Rule,
+		Outcome:    audit.OutcomeBlocked,
+	})
+}
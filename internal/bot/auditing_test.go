@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+)
+
+// auditEntries reads back every entry in a JSON Lines audit file
+func auditEntries(t *testing.T, path string) []*audit.Entry {
+	t.Helper()
+	s, err := audit.OpenFile(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit file: %v", err)
+	}
+	defer s.Close()
+
+	var entries []*audit.Entry
+	if err := s.Scan(func(e *audit.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to read audit file: %v", err)
+	}
+	return entries
+}
+
+func TestRecordRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, err := audit.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog, err := audit.NewLog(time.Now, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	b := &Bot{audit: auditLog}
+
+	registry := newExecutionRegistry()
+	exec := registry.start("e1", "u1", "c1")
+	defer registry.finish(exec)
+	who := member{GuildID: "g1", UserID: "u1"}
+	cmd := &runCommand{
+		Language: "py",
+		Code:     "print(1)",
+		Tier:     executor.TierSmall,
+		Network:  executor.NetworkNone,
+		Policy:   &policy.Match{Rule: "sockets", Action: policy.ActionFlag},
+	}
+
+	res := &executor.Result{Language: "python"}
+	res.Reason = executor.TerminationTimeout
+	res.ExitCode = 137
+	res.Duration = 2 * time.Second
+	res.CPUTime = 1500 * time.Millisecond
+	res.Timeout = 2 * time.Second
+	b.recordRun(exec, who, cmd, 300*time.Millisecond, res, nil)
+	b.recordRun(exec, who, cmd, 0, nil, errors.New("image pull failed"))
+
+	entries := auditEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Event != audit.EventExecution || e.ExecutionID != "e1" || e.GuildID != "g1" || e.Language != "python" {
+		t.Errorf("Expected who, where and what to be recorded, got %+v", e)
+	}
+	if e.SourceHash != audit.SourceHash("print(1)", nil) || e.PolicyRule != "sockets" {
+		t.Errorf("Expected the source hash and policy rule, got %+v", e)
+	}
+	if e.Tier != "small" || e.Network != "none" || e.TimeoutMS != 2000 {
+		t.Errorf("Expected the limits, got %+v", e)
+	}
+	if e.Outcome != "timeout" || e.QueuedMS != 300 || e.DurationMS != 2000 || e.CPUTimeMS != 1500 {
+		t.Errorf("Expected the outcome and timings, got %+v", e)
+	}
+	if entries[1].Outcome != audit.OutcomeFailed || entries[1].Error != "image pull failed" {
+		t.Errorf("Expected a failed run, got %+v", entries[1])
+	}
+	if entries[1].PrevHash != e.Hash {
+		t.Error("Expected entries to be chained")
+	}
+}
+
+func TestScreenRecordsBlocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, err := audit.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog, err := audit.NewLog(time.Now, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	b := newScreeningBot(t)
+	b.audit = auditLog
+
+	b.screen(member{UserID: "u1"}, "c1", "python", "import socket")
+	b.screen(member{UserID: "u1"}, "c1", "bash", "./xmrig")
+
+	entries := auditEntries(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the block to be recorded, got %d entries", len(entries))
+	}
+	if entries[0].Event != audit.EventBlocked || entries[0].PolicyRule != "crypto-miner" {
+		t.Errorf("Expected the block and its rule, got %+v", entries[0])
+	}
+}
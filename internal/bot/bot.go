@@ -0,0 +1,359 @@
+// Package bot connects Discord events to the command executor.
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+	"github.com/anchitjain1234/discord-command-executor/internal/scheduler"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// Bot handles Discord commands and dispatches them to an executor
+type Bot struct {
+	cfg      config.BotConfig
+	session  *discordgo.Session
+	executor executor.Executor
+
+	// Shares the execution slots fairly between users and guilds
+	scheduler  *scheduler.Scheduler
+	priorities priorities
+
+	// Running executions, for cancellation
+	executions *executionRegistry
+
+	// Live interactive sessions by channel and owner
+	sessions *sessionRegistry
+
+	// Recent prefix commands and their results, for rerunning on edit
+	sources *sourceIndex
+
+	// Who may run what
+	permissions *permissions
+
+	// Rate limits and CPU quotas
+	limits *limits
+
+	// Screens code before it runs
+	policy *policy.Engine
+
+	// Records every execution; nil records nothing
+	audit *audit.Log
+
+	// Keeps users' past runs under the history retention policy; nil keeps
+	// no history
+	store   *store.Store
+	history config.HistoryConfig
+
+	// Aliases and templates expanded in prefix commands
+	macros *macros
+
+	// Schedules whose runs are in progress
+	scheduled *scheduleRunner
+
+	// Closed by Shutdown to end background work
+	done chan struct{}
+
+	// Set once Shutdown begins; new runs are then refused
+	closing atomic.Bool
+}
+
+// New creates a bot using the given configuration and executor, recording
+// executions in auditLog and keeping users' history in db, when not nil
+func New(cfg config.BotConfig, exec executor.Executor, auditLog *audit.Log, db *store.Store,
+	history config.HistoryConfig) (*Bot, error) {
+	session, err := discordgo.New("Bot " + cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discord session: %w", err)
+	}
+	perms, err := newPermissions(cfg.Permissions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid permissions: %w", err)
+	}
+	screen, err := policy.New(cfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	macros, err := newMacros(cfg.Macros)
+	if err != nil {
+		return nil, fmt.Errorf("invalid macros: %w", err)
+	}
+	if db != nil {
+		if err := macros.load(db); err != nil {
+			return nil, fmt.Errorf("failed to load macros: %w", err)
+		}
+	}
+
+	session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsDirectMessages |
+		discordgo.IntentsMessageContent |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsDirectMessageReactions
+
+	b := &Bot{
+		cfg:      cfg,
+		session:  session,
+		executor: exec,
+
+		scheduler:  newScheduler(cfg, time.Now),
+		priorities: newPriorities(cfg.Scheduler.Priorities),
+		executions: newExecutionRegistry(),
+		sessions:   newSessionRegistry(cfg.MaxSessions),
+		sources:    newSourceIndex(time.Duration(cfg.EditWindow) * time.Second),
+
+		permissions: perms,
+		limits:      newLimits(cfg.Limits, time.Now),
+		policy:      screen,
+		audit:       auditLog,
+		store:       db,
+		history:     history,
+		macros:      macros,
+		scheduled:   newScheduleRunner(),
+		done:        make(chan struct{}),
+	}
+	session.AddHandler(b.onReady)
+	session.AddHandler(b.onMessageCreate)
+	session.AddHandler(b.onInteractionCreate)
+	session.AddHandler(b.onReactionAdd)
+	session.AddHandler(b.onMessageUpdate)
+	session.AddHandler(b.onMessageDelete)
+
+	return b, nil
+}
+
+// Start opens the Discord gateway connection
+func (b *Bot) Start() error {
+	if err := b.session.Open(); err != nil {
+		return fmt.Errorf("failed to open discord session: %w", err)
+	}
+	logrus.Info("Connected to Discord")
+
+	go b.keepHistoryPruned(b.done)
+	go b.runSchedules(b.done)
+	return nil
+}
+
+// onMessageCreate handles prefix commands and feeds other messages to the
+// author's interactive session in the channel, if any
+func (b *Bot) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot {
+		return
+	}
+
+	if cmd, err := parseSnippetSave(b.cfg.Prefix, m.Content); !errors.Is(err, errNotSnippetCommand) {
+		b.onSnippetMessage(s, m.Message, cmd, err)
+		return
+	}
+
+	if _, err := b.parseMessage(m.GuildID, m.Content); errors.Is(err, errNotRunCommand) {
+		if session, ok := b.sessions.get(sessionKey{channelID: m.ChannelID, userID: m.Author.ID}); ok {
+			b.feedSession(s, m.Message, session)
+		}
+		return
+	}
+
+	source := b.sources.track(m.Message)
+	source.lock()
+	b.handleCommand(s, m.Message, source)
+}
+
+// handleCommand validates and runs the prefix command in m, posting its
+// result or updating the previous one. It is called with source locked and
+// unlocks it once the result is shown.
+func (b *Bot) handleCommand(s *discordgo.Session, m *discordgo.Message, source *trackedSource) {
+	cmd, err := b.parseMessage(m.GuildID, m.Content)
+	var msg *discordgo.MessageSend
+	who := messageMember(s, m)
+	switch {
+	case err != nil:
+		msg = &discordgo.MessageSend{Content: "❌ " + err.Error()}
+	case cmd.Code == "" && len(m.Attachments) == 0:
+		msg = &discordgo.MessageSend{Content: "❌ " + errMissingCode.Error()}
+	default:
+		msg = b.checkRun(who, m.ChannelID, cmd)
+	}
+	if msg != nil {
+		b.showResult(s, m, source, msg)
+		source.unlock()
+		return
+	}
+
+	go func() {
+		defer source.unlock()
+		b.runMessage(s, m, who, cmd, source)
+	}()
+}
+
+// runMessage runs a prefix command, showing a cancellable progress message
+// that is replaced by the result. Reruns reuse the previous result message.
+func (b *Bot) runMessage(s *discordgo.Session, m *discordgo.Message, who member, cmd *runCommand,
+	source *trackedSource) {
+	exec := b.executions.start(source.nextExecution(), m.Author.ID, m.ChannelID)
+	defer b.executions.finish(exec)
+
+	var progress *discordgo.Message
+	if resultID := source.result(); resultID != "" {
+		progress = b.resetProgress(s, m.ChannelID, resultID, exec, cmd.Language)
+	} else {
+		progress = b.sendProgress(s, m, exec, cmd.Language)
+	}
+	stopUpdates := func() {}
+	if progress != nil {
+		source.setResult(progress.ID)
+		exec.output = newLiveOutput()
+		stopUpdates = updateProgress(exec, cmd.Language, func(content string) error {
+			edit := discordgo.NewMessageEdit(progress.ChannelID, progress.ID).SetContent(content)
+			edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+			_, err := s.ChannelMessageEditComplex(edit)
+			return err
+		})
+	}
+
+	var msg *discordgo.MessageSend
+	files, err := b.collectAttachments(exec.ctx, m.Attachments)
+	if err != nil {
+		msg = &discordgo.MessageSend{Content: "❌ " + err.Error()}
+	} else {
+		cmd.Files = files
+		msg = b.run(exec, who, cmd)
+	}
+	stopUpdates()
+	msg.Components = resultButtons(m.Author.ID, cmd.Language, m.ID)
+
+	b.showResult(s, m, source, msg)
+}
+
+// showResult replaces the source's previous result or progress message
+// with msg, or replies with it when there is none yet
+func (b *Bot) showResult(s *discordgo.Session, m *discordgo.Message, source *trackedSource,
+	msg *discordgo.MessageSend) {
+	if resultID := source.result(); resultID != "" {
+		b.replaceProgress(s, &discordgo.Message{ID: resultID, ChannelID: m.ChannelID}, msg)
+		return
+	}
+	if sent := b.reply(s, m, msg); sent != nil {
+		source.setResult(sent.ID)
+	}
+}
+
+// checkLanguage returns an error reply for unsupported languages
+func checkLanguage(language string) *discordgo.MessageSend {
+	if _, ok := executor.LookupLanguage(language); ok {
+		return nil
+	}
+	return &discordgo.MessageSend{Content: fmt.Sprintf(
+		"❌ Unsupported language `%s`. Supported: %s",
+		language, strings.Join(executor.LanguageNames(), ", "),
+	)}
+}
+
+// run waits for an execution slot fairly shared with other users and
+// guilds, then executes a parsed command for who and renders the reply
+func (b *Bot) run(exec *execution, who member, cmd *runCommand) *discordgo.MessageSend {
+	log := logrus.WithFields(logrus.Fields{
+		"execution_id": exec.ID,
+		"user_id":      exec.UserID,
+		"channel_id":   exec.ChannelID,
+		"language":     cmd.Language,
+	})
+
+	// Uploads are only known now, but still checked before any container exists
+	if msg := b.screenFiles(who, exec.ChannelID, cmd); msg != nil {
+		return msg
+	}
+
+	queuedAt := time.Now()
+	ticket, err := b.scheduler.Acquire(exec.ctx, b.slotRequest(who))
+	queued := time.Since(queuedAt)
+	if err != nil {
+		b.recordRun(exec, who, cmd, queued, nil, err)
+		if exec.Interrupted() {
+			return interruptedReply(exec)
+		}
+		return canceledReply(exec)
+	}
+	defer b.scheduler.Release(ticket)
+	if !exec.begin() {
+		b.recordRun(exec, who, cmd, queued, nil, exec.ctx.Err())
+		return interruptedReply(exec)
+	}
+
+	req := &executor.Request{
+		ID:       exec.ID,
+		Language: cmd.Language,
+		Code:     cmd.Code,
+		Stdin:    cmd.Stdin,
+		Args:     cmd.Args,
+		Files:    cmd.Files,
+		Tier:     cmd.Tier,
+		Network:  cmd.Network,
+	}
+	if exec.output != nil {
+		req.Output = exec.output
+	}
+
+	if cmd.Policy != nil {
+		log = log.WithField("policy_rule", cmd.Policy.Rule)
+	}
+	log.Info("Executing code")
+	res, err := b.executor.Execute(exec.ctx, req)
+	b.recordRun(exec, who, cmd, queued, res, err)
+	if err != nil && exec.Interrupted() {
+		return interruptedReply(exec)
+	}
+	if err != nil && exec.CanceledBy() != "" {
+		return canceledReply(exec)
+	}
+	if err != nil {
+		log.WithError(err).Error("Execution failed")
+		return &discordgo.MessageSend{Content: "❌ Execution failed: " + err.Error()}
+	}
+
+	cpu := res.CPUTime
+	if res.Compile != nil {
+		cpu += res.Compile.CPUTime
+	}
+	b.limits.charge(exec.UserID, cpu)
+	b.saveHistory(exec, who, cmd, res)
+	exec.result = res
+
+	log.WithFields(logrus.Fields{
+		"exit_code":   res.ExitCode,
+		"reason":      res.Reason,
+		"duration":    res.Duration,
+		"cpu_time":    res.CPUTime,
+		"peak_memory": res.PeakMemory,
+	}).Info("Execution finished")
+
+	msg := b.render(exec.ChannelID, res)
+	if by := exec.CanceledBy(); by != "" {
+		appendNote(msg, fmt.Sprintf("Cancelled by <@%s>.", by))
+	}
+	if exec.Interrupted() {
+		appendNote(msg, "Stopped because the bot is shutting down.")
+	}
+	return msg
+}
+
+// reply sends msg as a reply to the source message, returning the sent
+// message or nil on failure
+func (b *Bot) reply(s *discordgo.Session, m *discordgo.Message, msg *discordgo.MessageSend) *discordgo.Message {
+	msg.Reference = m.Reference()
+	msg.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	sent, err := s.ChannelMessageSendComplex(m.ChannelID, msg)
+	if err != nil {
+		logrus.WithError(err).WithField("channel_id", m.ChannelID).Error("Failed to send reply")
+		return nil
+	}
+	return sent
+}
@@ -0,0 +1,399 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Result controls. Custom IDs carry everything a button needs, and the code
+// is read back from Discord, so buttons keep working across restarts.
+const (
+	// Custom ID prefix of result buttons:
+	// result:<action>:<requester>:<language>:<source>
+	resultButtonPrefix = "result:"
+
+	// Result button actions
+	actionRerun  = "rerun"
+	actionEdit   = "edit"
+	actionDelete = "delete"
+	actionSource = "source"
+
+	// Source reference for code attached to the result message itself;
+	// otherwise the reference is "m" followed by the prefix command's message ID
+	sourceAttached = "a"
+	sourceMessage  = "m"
+
+	// Custom ID prefix of the edit modal, followed by the language
+	editModalPrefix = "edit:"
+
+	// Edit modal text inputs
+	editInputCode  = "code"
+	editInputStdin = "input"
+
+	// Discord's limit on text input values
+	maxTextInputLength = 4000
+
+	// Name of the attachment holding stdin for runs without a source message
+	inputAttachmentName = "input.txt"
+
+	// How long the Edit button may spend loading the code; the modal has
+	// to answer the click within Discord's three second limit
+	editSourceTimeout = 2 * time.Second
+)
+
+// errNoInlineCode is returned when editing a run whose code came only from uploads
+var errNoInlineCode = errors.New("this run has no inline code to edit")
+
+// resultAction is a decoded result button custom ID
+type resultAction struct {
+	Action   string
+	UserID   string
+	Language string
+
+	// ID of the prefix command message holding the code; empty when the code
+	// is attached to the result message
+	MessageID string
+}
+
+// encode returns the custom ID for the action
+func (a resultAction) encode() string {
+	source := sourceAttached
+	if a.MessageID != "" {
+		source = sourceMessage + a.MessageID
+	}
+	return resultButtonPrefix + strings.Join([]string{a.Action, a.UserID, a.Language, source}, ":")
+}
+
+// parseResultAction decodes a result button custom ID
+func parseResultAction(customID string) (resultAction, error) {
+	parts := strings.Split(strings.TrimPrefix(customID, resultButtonPrefix), ":")
+	if len(parts) != 4 {
+		return resultAction{}, fmt.Errorf("malformed result button %q", customID)
+	}
+
+	a := resultAction{Action: parts[0], UserID: parts[1], Language: parts[2]}
+	switch source := parts[3]; {
+	case source == sourceAttached:
+	case strings.HasPrefix(source, sourceMessage) && len(source) > len(sourceMessage):
+		a.MessageID = strings.TrimPrefix(source, sourceMessage)
+	default:
+		return resultAction{}, fmt.Errorf("malformed result button %q", customID)
+	}
+	return a, nil
+}
+
+// resultButtons returns the component row attached to results
+func resultButtons(userID, language, sourceMessageID string) []discordgo.MessageComponent {
+	button := func(action, label, emoji string, style discordgo.ButtonStyle) discordgo.Button {
+		a := resultAction{Action: action, UserID: userID, Language: language, MessageID: sourceMessageID}
+		return discordgo.Button{
+			Label:    label,
+			Style:    style,
+			Emoji:    &discordgo.ComponentEmoji{Name: emoji},
+			CustomID: a.encode(),
+		}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			button(actionRerun, "Rerun", "🔁", discordgo.PrimaryButton),
+			button(actionEdit, "Edit", "✏️", discordgo.SecondaryButton),
+			button(actionSource, "Show source", "📄", discordgo.SecondaryButton),
+			button(actionDelete, "Delete", "🗑️", discordgo.DangerButton),
+		}},
+	}
+}
+
+// sourceFiles returns the attachments preserving code submitted without a
+// source message, so the result buttons can read it back
+func sourceFiles(cmd *runCommand) []*discordgo.File {
+	lang, ok := executor.LookupLanguage(cmd.Language)
+	if !ok || cmd.Code == "" {
+		return nil
+	}
+
+	files := []*discordgo.File{textFile(lang.FileName, cmd.Code)}
+	if cmd.Stdin != "" {
+		files = append(files, textFile(inputAttachmentName, cmd.Stdin))
+	}
+	return files
+}
+
+// onResultButton handles clicks on result buttons
+func (b *Bot) onResultButton(s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	action, err := parseResultAction(customID)
+	if err != nil {
+		logrus.WithError(err).Warn("Ignoring result button")
+		return
+	}
+
+	switch action.Action {
+	case actionDelete:
+		b.deleteResult(s, i, action)
+	case actionRerun:
+		b.rerunResult(s, i, action)
+	case actionEdit:
+		b.editResult(s, i, action)
+	case actionSource:
+		b.showSource(s, i, action)
+	}
+}
+
+// rerunResult runs the code behind a result again. Loading the code can
+// outlast Discord's three second limit, so the reply is deferred first.
+func (b *Bot) rerunResult(s *discordgo.Session, i *discordgo.InteractionCreate, action resultAction) {
+	reply, err := b.deferReply(s, i)
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to acknowledge interaction")
+		return
+	}
+
+	cmd, attachments, err := b.loadSource(context.Background(), s, i, action, true)
+	if err != nil {
+		reply.replyPrivately(s, "❌ "+err.Error())
+		return
+	}
+	if msg := b.checkRun(interactionMember(i), i.ChannelID, cmd); msg != nil {
+		reply.replyPrivately(s, msg.Content)
+		return
+	}
+	b.runDeferred(s, i, reply, cmd, attachments, action.MessageID)
+}
+
+// editResult opens the edit modal for a result. The modal has to be the
+// first response, so only the code is loaded, under a short deadline.
+func (b *Bot) editResult(s *discordgo.Session, i *discordgo.InteractionCreate, action resultAction) {
+	ctx, cancel := context.WithTimeout(context.Background(), editSourceTimeout)
+	defer cancel()
+
+	cmd, _, err := b.loadSource(ctx, s, i, action, false)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = errors.New("loading the code took too long; try again")
+		}
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	b.openEditModal(s, i, cmd)
+}
+
+// loadSource reads the code behind a result from the prefix command message
+// or from the source attachments of the result message. The attached input
+// is only downloaded when withInput is set.
+func (b *Bot) loadSource(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate,
+	action resultAction, withInput bool) (*runCommand, []*discordgo.MessageAttachment, error) {
+	if action.MessageID != "" {
+		m, err := s.ChannelMessage(i.ChannelID, action.MessageID, discordgo.WithContext(ctx))
+		if err != nil {
+			return nil, nil, fmt.Errorf("the original message is no longer available")
+		}
+		cmd, err := b.parseMessage(i.GuildID, m.Content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("the original message no longer contains a run command")
+		}
+		return cmd, m.Attachments, nil
+	}
+
+	lang, ok := executor.LookupLanguage(action.Language)
+	if !ok || i.Message == nil {
+		return nil, nil, fmt.Errorf("the source of this result is unavailable")
+	}
+
+	cmd := &runCommand{Language: lang.Name}
+	for _, att := range i.Message.Attachments {
+		input := att.Filename == inputAttachmentName
+		if att.Filename != lang.FileName && !(input && withInput) {
+			continue
+		}
+		data, err := download(ctx, att.URL, b.cfg.MaxUploadBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", att.Filename, err)
+		}
+		if input {
+			cmd.Stdin = string(data)
+		} else {
+			cmd.Code = string(data)
+		}
+	}
+	if cmd.Code == "" {
+		return nil, nil, fmt.Errorf("the source of this result is unavailable")
+	}
+	return cmd, nil, nil
+}
+
+// runInteraction runs cmd in answer to an interaction, showing progress in
+// a deferred response that is replaced by the result. Code without a source
+// message is attached to the result so its buttons can find it again.
+func (b *Bot) runInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, cmd *runCommand,
+	attachments []*discordgo.MessageAttachment, sourceMessageID string) {
+	// Queueing and image pulls easily exceed Discord's three second limit,
+	// so the response is deferred and filled in once the run finishes
+	reply, err := b.deferReply(s, i)
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to acknowledge interaction")
+		return
+	}
+	b.runDeferred(s, i, reply, cmd, attachments, sourceMessageID)
+}
+
+// runDeferred is runInteraction for an interaction whose reply is already deferred
+func (b *Bot) runDeferred(s *discordgo.Session, i *discordgo.InteractionCreate, reply *deferredReply,
+	cmd *runCommand, attachments []*discordgo.MessageAttachment, sourceMessageID string) {
+	exec := b.executions.start(i.ID, reply.userID, i.ChannelID)
+	defer b.executions.finish(exec)
+	exec.output = newLiveOutput()
+
+	components := stopButton(exec.ID)
+	content := progressContent(cmd.Language, exec.ID, 0, "")
+	if progress, err := reply.edit(s, &discordgo.WebhookEdit{Content: &content, Components: &components}); err == nil {
+		exec.setProgressMessage(progress.ID)
+	}
+	stopUpdates := updateProgress(exec, cmd.Language, func(content string) error {
+		_, err := reply.edit(s, &discordgo.WebhookEdit{Content: &content})
+		return err
+	})
+
+	var msg *discordgo.MessageSend
+	files, err := b.collectAttachments(exec.ctx, attachments)
+	if err != nil {
+		msg = &discordgo.MessageSend{Content: "❌ " + err.Error()}
+	} else {
+		cmd.Files = files
+		msg = b.run(exec, interactionMember(i), cmd)
+	}
+	stopUpdates()
+
+	if sourceMessageID == "" {
+		msg.Files = append(msg.Files, sourceFiles(cmd)...)
+	}
+	msg.Components = resultButtons(reply.userID, cmd.Language, sourceMessageID)
+	b.deliver(s, reply, msg)
+}
+
+// openEditModal shows a modal pre-filled with the code and input of a run
+func (b *Bot) openEditModal(s *discordgo.Session, i *discordgo.InteractionCreate, cmd *runCommand) {
+	if cmd.Code == "" {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errNoInlineCode.Error())
+		return
+	}
+	if len(cmd.Code) > maxTextInputLength || len(cmd.Stdin) > maxTextInputLength {
+		b.respondEphemeral(s, i.Interaction, "❌ This code is too long to edit here; edit it in a new message.")
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: editModalPrefix + cmd.Language,
+			Title:    "Edit and rerun",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  editInputCode,
+						Label:     "Code",
+						Style:     discordgo.TextInputParagraph,
+						Value:     cmd.Code,
+						Required:  true,
+						MaxLength: maxTextInputLength,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  editInputStdin,
+						Label:     "Standard input",
+						Style:     discordgo.TextInputParagraph,
+						Value:     cmd.Stdin,
+						MaxLength: maxTextInputLength,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to open edit modal")
+	}
+}
+
+// onEditSubmit runs the code submitted through the edit modal
+func (b *Bot) onEditSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	cmd := &runCommand{Language: strings.TrimPrefix(data.CustomID, editModalPrefix)}
+
+	for _, row := range data.Components {
+		actions, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actions.Components {
+			input, ok := component.(*discordgo.TextInput)
+			if !ok {
+				continue
+			}
+			switch input.CustomID {
+			case editInputCode:
+				cmd.Code = input.Value
+			case editInputStdin:
+				cmd.Stdin = terminateInput(input.Value)
+			}
+		}
+	}
+
+	if msg := b.checkRun(interactionMember(i), i.ChannelID, cmd); msg != nil {
+		b.respond(s, i.Interaction, msg)
+		return
+	}
+	b.runInteraction(s, i, cmd, nil, "")
+}
+
+// showSource replies privately with the code behind a result
+func (b *Bot) showSource(s *discordgo.Session, i *discordgo.InteractionCreate, action resultAction) {
+	reply, err := b.deferEphemeralReply(s, i)
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to acknowledge interaction")
+		return
+	}
+
+	cmd, attachments, err := b.loadSource(context.Background(), s, i, action, true)
+	if err != nil {
+		reply.reply(s, "❌ "+err.Error())
+		return
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "**%s** source", cmd.Language)
+	if cmd.Code != "" {
+		head, cut := headOfOutput(cmd.Code, maxMessageLength-messageOverhead)
+		fmt.Fprintf(&content, ":\n```%s\n%s\n```", cmd.Language, escapeCodeBlock(head))
+		if cut {
+			content.WriteString("\n(truncated)")
+		}
+	}
+	if len(attachments) > 0 {
+		fmt.Fprintf(&content, "\n📎 Plus %d uploaded file(s) on the original message.", len(attachments))
+	}
+	reply.reply(s, content.String())
+}
+
+// deleteResult deletes a result message for its requester or a moderator
+func (b *Bot) deleteResult(s *discordgo.Session, i *discordgo.InteractionCreate, action resultAction) {
+	who := interactionMember(i)
+	if who.UserID != action.UserID && !b.isAdmin(s, who, i.ChannelID, adminDelete) {
+		b.respondEphemeral(s, i.Interaction, "❌ Only the requester or a moderator can delete this result.")
+		return
+	}
+
+	if err := s.ChannelMessageDelete(i.ChannelID, i.Message.ID); err != nil {
+		logrus.WithError(err).WithField("message_id", i.Message.ID).Error("Failed to delete result")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to delete the result.")
+		return
+	}
+	b.respondEphemeral(s, i.Interaction, "🗑️ Result deleted.")
+}
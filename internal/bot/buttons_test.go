@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+func TestResultActionRoundTrip(t *testing.T) {
+	tests := []resultAction{
+		{Action: actionRerun, UserID: "123456789012345678", Language: "javascript", MessageID: "987654321098765432"},
+		{Action: actionSource, UserID: "123456789012345678", Language: "python"},
+	}
+
+	for _, want := range tests {
+		customID := want.encode()
+		if len(customID) > 100 {
+			t.Errorf("Expected custom ID within Discord's 100 character limit, got %d", len(customID))
+		}
+
+		got, err := parseResultAction(customID)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", customID, err)
+		}
+		if got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestParseResultActionMalformed(t *testing.T) {
+	for _, customID := range []string{
+		"result:rerun:1:python",
+		"result:rerun:1:python:m",
+		"result:rerun:1:python:x123",
+	} {
+		if _, err := parseResultAction(customID); err == nil {
+			t.Errorf("Expected error for %q", customID)
+		}
+	}
+}
+
+func TestResultButtons(t *testing.T) {
+	rows := resultButtons("1", "python", "2")
+	row, ok := rows[0].(discordgo.ActionsRow)
+	if !ok || len(row.Components) != 4 {
+		t.Fatalf("Expected one row of four buttons, got %+v", rows)
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	files := sourceFiles(&runCommand{Language: "py", Code: "print(input())", Stdin: "hi\n"})
+	if len(files) != 2 {
+		t.Fatalf("Expected code and input attachments, got %d", len(files))
+	}
+	if files[0].Name != "main.py" || files[1].Name != inputAttachmentName {
+		t.Errorf("Unexpected attachment names %s, %s", files[0].Name, files[1].Name)
+	}
+	data, err := io.ReadAll(files[0].Reader)
+	if err != nil || string(data) != "print(input())" {
+		t.Errorf("Expected code attachment, got %q (%v)", data, err)
+	}
+
+	if files := sourceFiles(&runCommand{Language: "python"}); len(files) != 0 {
+		t.Errorf("Expected no attachments without inline code, got %d", len(files))
+	}
+}
+
+func TestLoadSourceCodeOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+inputAttachmentName {
+			t.Error("Expected the input not to be downloaded")
+		}
+		io.WriteString(w, "print(input())")
+	}))
+	defer server.Close()
+
+	b := &Bot{cfg: config.BotConfig{MaxUploadBytes: 1024}}
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Message: &discordgo.Message{Attachments: []*discordgo.MessageAttachment{
+			{Filename: "main.py", URL: server.URL + "/main.py"},
+			{Filename: inputAttachmentName, URL: server.URL + "/" + inputAttachmentName},
+		}},
+	}}
+
+	cmd, _, err := b.loadSource(context.Background(), nil, i, resultAction{Language: "python"}, false)
+	if err != nil {
+		t.Fatalf("Failed to load source: %v", err)
+	}
+	if cmd.Code != "print(input())" || cmd.Stdin != "" {
+		t.Errorf("Expected only the code, got %+v", cmd)
+	}
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Cancellation controls
+const (
+	// Reaction added to progress messages; reacting with it cancels the run
+	stopEmoji = "⏹️"
+
+	// Custom ID prefix of the stop button, followed by the execution ID
+	cancelButtonPrefix = "cancel:"
+
+	// Slash command and option used to cancel by execution ID
+	cancelCommandName = "cancel"
+	optionExecutionID = "id"
+)
+
+// sendProgress posts a progress reply with a stop button and reaction.
+// It returns nil if the message could not be sent.
+func (b *Bot) sendProgress(s *discordgo.Session, m *discordgo.Message, exec *execution,
+	language string) *discordgo.Message {
+	progress, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         progressContent(language, exec.ID, 0, ""),
+		Components:      stopButton(exec.ID),
+		Reference:       m.Reference(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("execution_id", exec.ID).Warn("Failed to send progress message")
+		return nil
+	}
+	exec.setProgressMessage(progress.ID)
+
+	if err := s.MessageReactionAdd(progress.ChannelID, progress.ID, stopEmoji); err != nil {
+		logrus.WithError(err).WithField("execution_id", exec.ID).Debug("Failed to add stop reaction")
+	}
+
+	return progress
+}
+
+// replaceProgress edits the progress message into the final result,
+// replacing the stop controls with the result's own
+func (b *Bot) replaceProgress(s *discordgo.Session, progress *discordgo.Message, msg *discordgo.MessageSend) {
+	edit := discordgo.NewMessageEdit(progress.ChannelID, progress.ID).SetContent(msg.Content)
+	components := []discordgo.MessageComponent{}
+	if msg.Components != nil {
+		components = msg.Components
+	}
+	edit.Components = &components
+	// Attachments of a previous result are dropped in favor of the new files
+	edit.Attachments = &[]*discordgo.MessageAttachment{}
+	edit.Files = msg.Files
+	embeds := []*discordgo.MessageEmbed{}
+	if msg.Embeds != nil {
+		embeds = msg.Embeds
+	}
+	edit.Embeds = &embeds
+	edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		logrus.WithError(err).WithField("message_id", progress.ID).Error("Failed to edit progress message")
+		if _, err := s.ChannelMessageEditComplex(undeliveredMessageEdit(progress)); err != nil {
+			logrus.WithError(err).WithField("message_id", progress.ID).Error("Failed to report undelivered result")
+			return
+		}
+	}
+
+	if err := s.MessageReactionRemove(progress.ChannelID, progress.ID, stopEmoji, "@me"); err != nil {
+		logrus.WithError(err).WithField("message_id", progress.ID).Debug("Failed to remove stop reaction")
+	}
+}
+
+// undeliveredMessageEdit replaces a progress message with the plain
+// undelivered notice, clearing any files, embeds and controls
+func undeliveredMessageEdit(progress *discordgo.Message) *discordgo.MessageEdit {
+	edit := discordgo.NewMessageEdit(progress.ChannelID, progress.ID).SetContent(undeliveredContent)
+	edit.Components = &[]discordgo.MessageComponent{}
+	edit.Embeds = &[]*discordgo.MessageEmbed{}
+	edit.Attachments = &[]*discordgo.MessageAttachment{}
+	edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	return edit
+}
+
+// stopButton returns the component row holding the stop button
+func stopButton(executionID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Stop",
+				Style:    discordgo.DangerButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: stopEmoji},
+				CustomID: cancelButtonPrefix + executionID,
+			},
+		}},
+	}
+}
+
+// cancelExecution cancels the execution with the given ID on behalf of
+// who and returns a message describing the outcome
+func (b *Bot) cancelExecution(s *discordgo.Session, executionID string, who member) string {
+	log := logrus.WithFields(logrus.Fields{"execution_id": executionID, "user_id": who.UserID})
+
+	exec, ok := b.executions.get(executionID)
+	if !ok {
+		return fmt.Sprintf("No running execution `%s`.", executionID)
+	}
+	if !canCancel(s, exec, who.UserID) && !b.isAdmin(s, who, exec.ChannelID, adminCancel) {
+		log.Warn("Cancellation denied")
+		return "❌ Only the requester or a moderator can cancel this execution."
+	}
+	if !exec.Cancel(who.UserID) {
+		return fmt.Sprintf("Execution `%s` is already being cancelled.", executionID)
+	}
+
+	log.Info("Execution cancelled")
+	return fmt.Sprintf("🛑 Cancelled execution `%s`.", executionID)
+}
+
+// canceledReply reports an execution that was cancelled before producing a result
+func canceledReply(exec *execution) *discordgo.MessageSend {
+	return &discordgo.MessageSend{Content: fmt.Sprintf(
+		"🛑 Execution `%s` was cancelled by <@%s> before it started.", exec.ID, exec.CanceledBy(),
+	)}
+}
+
+// onReactionAdd cancels an execution when the stop reaction is added to its progress message
+func (b *Bot) onReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.Emoji.Name != stopEmoji || (s.State.User != nil && r.UserID == s.State.User.ID) {
+		return
+	}
+
+	exec, ok := b.executions.byProgressMessage(r.MessageID)
+	if !ok {
+		return
+	}
+
+	// Reactions have no reply channel, so the outcome is only logged
+	var roles []string
+	if r.Member != nil {
+		roles = r.Member.Roles
+	}
+	b.cancelExecution(s, exec.ID, lookupMember(s, r.GuildID, r.UserID, roles))
+}
+
+// onCancelButton handles clicks on the stop button
+func (b *Bot) onCancelButton(s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	executionID := strings.TrimPrefix(customID, cancelButtonPrefix)
+	b.respondEphemeral(s, i.Interaction, b.cancelExecution(s, executionID, interactionMember(i)))
+}
+
+// onCancelCommand handles /cancel <id>
+func (b *Bot) onCancelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var executionID string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == optionExecutionID {
+			executionID = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	b.respondEphemeral(s, i.Interaction, b.cancelExecution(s, executionID, interactionMember(i)))
+}
+
+// respondEphemeral answers an interaction with a message only the invoker sees
+func (b *Bot) respondEphemeral(s *discordgo.Session, i *discordgo.Interaction, content string) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to respond to interaction")
+	}
+}
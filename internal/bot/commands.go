@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Slash command option names
+const (
+	optionLanguage = "language"
+	optionCode     = "code"
+	optionInput    = "input"
+)
+
+// slashCommands returns the application commands registered by the bot
+func slashCommands() []*discordgo.ApplicationCommand {
+	commands := []*discordgo.ApplicationCommand{
+		{
+			Name:        runCommandName,
+			Description: "Run code in an isolated container",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionLanguage,
+					Description: "Programming language",
+					Required:    true,
+					Choices:     stringChoices(executor.LanguageNames()),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionCode,
+					Description: "Source code to run",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionInput,
+					Description: "Text passed to the program's standard input",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionTier,
+					Description: "Resource tier; defaults to the highest you may use up to standard",
+					Choices:     stringChoices(executor.TierNames()),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionNetwork,
+					Description: "Network access; defaults to the isolated network",
+					Choices:     stringChoices(executor.NetworkModeNames()),
+				},
+			},
+		},
+		{
+			Name:        cancelCommandName,
+			Description: "Cancel a running execution",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionExecutionID,
+					Description: "Execution ID shown on the progress message",
+					Required:    true,
+				},
+			},
+		},
+		sessionCommand(),
+		{
+			Name:        quotaCommandName,
+			Description: "Show how many runs and how much CPU time you have left",
+		},
+	}
+	commands = append(commands, historyCommands()...)
+	commands = append(commands, snippetCommand(), scheduleCommand())
+	return append(commands, macroCommands()...)
+}
+
+// stringChoices returns option choices for a list of names
+func stringChoices(names []string) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(names))
+	for _, name := range names {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	return choices
+}
+
+// onReady registers slash commands once the gateway session is established
+func (b *Bot) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	if _, err := s.ApplicationCommandBulkOverwrite(r.User.ID, b.cfg.GuildID, slashCommands()); err != nil {
+		logrus.WithError(err).Error("Failed to register slash commands")
+		return
+	}
+	logrus.WithField("guild_id", b.cfg.GuildID).Info("Registered slash commands")
+}
+
+// onInteractionCreate handles slash commands and message components
+func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		switch i.ApplicationCommandData().Name {
+		case runCommandName:
+			b.onRunCommand(s, i)
+		case cancelCommandName:
+			b.onCancelCommand(s, i)
+		case sessionCommandName:
+			b.onSessionCommand(s, i)
+		case quotaCommandName:
+			b.onQuotaCommand(s, i)
+		case historyCommandName:
+			b.onHistoryCommand(s, i)
+		case showCommandName:
+			b.onShowCommand(s, i)
+		case forgetCommandName:
+			b.onForgetCommand(s, i)
+		case snippetCommandName:
+			b.onSnippetCommand(s, i)
+		case scheduleCommandName:
+			b.onScheduleCommand(s, i)
+		case aliasCommandName, macroCommandName:
+			b.onMacroCommand(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		customID := i.MessageComponentData().CustomID
+		switch {
+		case strings.HasPrefix(customID, cancelButtonPrefix):
+			b.onCancelButton(s, i, customID)
+		case strings.HasPrefix(customID, resultButtonPrefix):
+			b.onResultButton(s, i, customID)
+		case strings.HasPrefix(customID, historyButtonPrefix):
+			b.onHistoryButton(s, i, customID)
+		}
+	case discordgo.InteractionModalSubmit:
+		if strings.HasPrefix(i.ModalSubmitData().CustomID, editModalPrefix) {
+			b.onEditSubmit(s, i)
+		}
+	}
+}
+
+// onRunCommand handles /run
+func (b *Bot) onRunCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd := &runCommand{}
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case optionLanguage:
+			cmd.Language = opt.StringValue()
+		case optionCode:
+			cmd.Code = opt.StringValue()
+		case optionInput:
+			cmd.Stdin = terminateInput(opt.StringValue())
+		case optionTier:
+			cmd.Tier = executor.Tier(opt.StringValue())
+		case optionNetwork:
+			cmd.Network = executor.NetworkMode(opt.StringValue())
+		}
+	}
+	if msg := b.checkRun(interactionMember(i), i.ChannelID, cmd); msg != nil {
+		b.respond(s, i.Interaction, msg)
+		return
+	}
+
+	b.runInteraction(s, i, cmd, nil, "")
+}
+
+// respond answers an interaction with the rendered message
+func (b *Bot) respond(s *discordgo.Session, i *discordgo.Interaction, msg *discordgo.MessageSend) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         msg.Content,
+			Files:           msg.Files,
+			Embeds:          msg.Embeds,
+			Components:      msg.Components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to respond to interaction")
+	}
+}
+
+// interactionUser returns the invoking user for guild and DM interactions
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
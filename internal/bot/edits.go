@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// trackedSource links a prefix command message to the reply showing its
+// result, so edits rerun the command and deletions remove the reply
+type trackedSource struct {
+	// Source message, fixed at creation
+	ID        string
+	ChannelID string
+	created   time.Time
+
+	// Held for the duration of each run so reruns happen in edit order
+	running chan struct{}
+
+	mu       sync.Mutex
+	content  string
+	resultID string
+	revision int
+	execID   string
+}
+
+// lock waits for any run of the source to finish and claims the next one
+func (t *trackedSource) lock() {
+	t.running <- struct{}{}
+}
+
+// unlock ends the current run of the source
+func (t *trackedSource) unlock() {
+	<-t.running
+}
+
+// nextExecution records a new run and returns its execution ID; reruns get
+// a revision suffix so they never collide with the run they replace
+func (t *trackedSource) nextExecution() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.revision++
+	t.execID = t.ID
+	if t.revision > 1 {
+		t.execID = fmt.Sprintf("%s-%d", t.ID, t.revision)
+	}
+	return t.execID
+}
+
+// execution returns the ID of the latest run
+func (t *trackedSource) execution() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.execID
+}
+
+// result returns the ID of the reply showing the result, if posted yet
+func (t *trackedSource) result() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resultID
+}
+
+// setResult records the reply showing the result
+func (t *trackedSource) setResult(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resultID = id
+}
+
+// updateContent stores new source content, reporting whether it changed
+func (t *trackedSource) updateContent(content string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if content == t.content {
+		return false
+	}
+	t.content = content
+	return true
+}
+
+// isLatest reports whether content is the most recent source content
+func (t *trackedSource) isLatest(content string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return content == t.content
+}
+
+// sourceIndex tracks recent prefix commands within the edit window
+type sourceIndex struct {
+	mu      sync.Mutex
+	window  time.Duration
+	sources map[string]*trackedSource
+	now     func() time.Time
+}
+
+// newSourceIndex creates an index keeping sources for window; a zero
+// window disables tracking
+func newSourceIndex(window time.Duration) *sourceIndex {
+	return &sourceIndex{window: window, sources: make(map[string]*trackedSource), now: time.Now}
+}
+
+// track starts tracking a source message, dropping expired ones. It returns
+// an untracked source when the edit window is disabled.
+func (x *sourceIndex) track(m *discordgo.Message) *trackedSource {
+	source := &trackedSource{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		created:   x.now(),
+		running:   make(chan struct{}, 1),
+		content:   m.Content,
+	}
+	if x.window <= 0 {
+		return source
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for id, s := range x.sources {
+		if x.expired(s) {
+			delete(x.sources, id)
+		}
+	}
+	x.sources[m.ID] = source
+	return source
+}
+
+// get returns a tracked source still within the edit window
+func (x *sourceIndex) get(id string) (*trackedSource, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	source, ok := x.sources[id]
+	if !ok {
+		return nil, false
+	}
+	if x.expired(source) {
+		delete(x.sources, id)
+		return nil, false
+	}
+	return source, true
+}
+
+// forget stops tracking a source
+func (x *sourceIndex) forget(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.sources, id)
+}
+
+// expired reports whether a source is past the edit window; the caller holds mu
+func (x *sourceIndex) expired(source *trackedSource) bool {
+	return x.now().Sub(source.created) > x.window
+}
+
+// onMessageUpdate reruns an edited prefix command, replacing its result.
+// A run still in progress for the old content is cancelled first.
+func (b *Bot) onMessageUpdate(s *discordgo.Session, u *discordgo.MessageUpdate) {
+	if u.Author == nil || u.Author.Bot {
+		return
+	}
+
+	source, ok := b.sources.get(u.ID)
+	if !ok {
+		return
+	}
+
+	// Link previews also trigger updates; only content changes rerun
+	if !source.updateContent(u.Content) {
+		return
+	}
+
+	if exec, ok := b.executions.get(source.execution()); ok {
+		exec.Cancel(u.Author.ID)
+	}
+
+	logrus.WithFields(logrus.Fields{"message_id": u.ID, "user_id": u.Author.ID}).Info("Source edited, rerunning")
+	go func() {
+		source.lock()
+
+		// Rapid edits queue up; only the latest content is worth running
+		if !source.isLatest(u.Content) {
+			source.unlock()
+			return
+		}
+		b.handleCommand(s, u.Message, source)
+	}()
+}
+
+// onMessageDelete removes the result of a deleted prefix command,
+// cancelling it if it is still running
+func (b *Bot) onMessageDelete(s *discordgo.Session, d *discordgo.MessageDelete) {
+	source, ok := b.sources.get(d.ID)
+	if !ok {
+		return
+	}
+	b.sources.forget(d.ID)
+
+	if exec, ok := b.executions.get(source.execution()); ok {
+		exec.Cancel(exec.UserID)
+	}
+
+	resultID := source.result()
+	if resultID == "" {
+		return
+	}
+	if err := s.ChannelMessageDelete(source.ChannelID, resultID); err != nil {
+		logrus.WithError(err).WithField("message_id", resultID).Warn("Failed to delete result of deleted message")
+	}
+}
+
+// resetProgress turns a previous result back into a progress message for a
+// rerun, clearing its embeds and attachments
+func (b *Bot) resetProgress(s *discordgo.Session, channelID, messageID string, exec *execution,
+	language string) *discordgo.Message {
+	edit := discordgo.NewMessageEdit(channelID, messageID).SetContent(progressContent(language, exec.ID, 0, ""))
+	components := stopButton(exec.ID)
+	edit.Components = &components
+	edit.Embeds = &[]*discordgo.MessageEmbed{}
+	edit.Attachments = &[]*discordgo.MessageAttachment{}
+	edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+
+	progress, err := s.ChannelMessageEditComplex(edit)
+	if err != nil {
+		logrus.WithError(err).WithField("message_id", messageID).Warn("Failed to reset previous result")
+		return nil
+	}
+	exec.setProgressMessage(progress.ID)
+	return progress
+}
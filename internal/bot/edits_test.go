@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSourceIndexWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	x := newSourceIndex(time.Minute)
+	x.now = func() time.Time { return now }
+
+	x.track(&discordgo.Message{ID: "1", ChannelID: "c", Content: "!run py"})
+	if _, ok := x.get("1"); !ok {
+		t.Fatal("Expected tracked source within the window")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := x.get("1"); ok {
+		t.Error("Expected source past the window to be dropped")
+	}
+
+	x.track(&discordgo.Message{ID: "2", ChannelID: "c"})
+	x.forget("2")
+	if _, ok := x.get("2"); ok {
+		t.Error("Expected forgotten source to be gone")
+	}
+}
+
+func TestSourceIndexDisabled(t *testing.T) {
+	x := newSourceIndex(0)
+	source := x.track(&discordgo.Message{ID: "1", ChannelID: "c"})
+	if source == nil {
+		t.Fatal("Expected an untracked source for running the command")
+	}
+	if _, ok := x.get("1"); ok {
+		t.Error("Expected nothing to be tracked with a zero window")
+	}
+}
+
+func TestTrackedSourceRevisions(t *testing.T) {
+	x := newSourceIndex(time.Minute)
+	source := x.track(&discordgo.Message{ID: "42", ChannelID: "c", Content: "a"})
+
+	if id := source.nextExecution(); id != "42" {
+		t.Errorf("Expected first run to use the message ID, got %s", id)
+	}
+	if id := source.nextExecution(); id != "42-2" {
+		t.Errorf("Expected rerun to get a revision suffix, got %s", id)
+	}
+	if source.execution() != "42-2" {
+		t.Errorf("Expected latest execution 42-2, got %s", source.execution())
+	}
+
+	if source.updateContent("a") {
+		t.Error("Expected unchanged content not to count as an edit")
+	}
+	if !source.updateContent("b") || !source.isLatest("b") {
+		t.Error("Expected changed content to be stored as the latest")
+	}
+}
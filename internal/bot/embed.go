@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Embed limits and colors
+const (
+	// Characters of output shown in the embed description, leaving room for
+	// the code fence within Discord's 4096 character limit
+	embedOutputLength = 3800
+
+	// Characters of compiler diagnostics shown in a field, within the
+	// 1024 character field limit
+	embedCompilerLength = 900
+
+	// Status colors
+	colorSuccess  = 0x2ecc71
+	colorFailure  = 0xe74c3c
+	colorKilled   = 0xe67e22
+	colorCanceled = 0x95a5a6
+)
+
+// render builds the reply for a finished execution, using an embed unless
+// the bot cannot post embeds in the channel
+func (b *Bot) render(channelID string, res *executor.Result) *discordgo.MessageSend {
+	if b.canEmbed(channelID) {
+		return renderResultEmbed(res)
+	}
+	return renderResult(res)
+}
+
+// canEmbed reports whether the bot may post embeds in the channel. Direct
+// messages have no permission overwrites, so lookup failures allow embeds.
+func (b *Bot) canEmbed(channelID string) bool {
+	if b.session.State == nil || b.session.State.User == nil {
+		return true
+	}
+	perms, err := b.session.UserChannelPermissions(b.session.State.User.ID, channelID)
+	if err != nil {
+		return true
+	}
+	return perms&discordgo.PermissionEmbedLinks != 0
+}
+
+// renderResultEmbed builds the embed reply for a finished execution: output
+// in the description, status, timings and resource usage as fields, and a
+// color for the outcome. Attachments follow the plain-text renderer.
+func renderResultEmbed(res *executor.Result) *discordgo.MessageSend {
+	embed := &discordgo.MessageEmbed{Title: res.Language}
+	var files []*discordgo.File
+	var b strings.Builder
+
+	if res.CompileFailed() {
+		embed.Title += " · compilation failed"
+		embed.Color = resultColor(res.Compile)
+
+		diagnostics := res.Compile.Output()
+		if writeOutputBlock(&b, diagnostics, embedOutputLength) || res.Compile.Truncated {
+			files = append(files, textFile(compileAttachmentName, diagnostics))
+		}
+		embed.Description = b.String()
+		embed.Fields = []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: describeStatus(res.Compile), Inline: true},
+			{Name: "Compile time", Value: res.Compile.Duration.Round(time.Millisecond).String(), Inline: true},
+		}
+		return finishEmbed(embed, files)
+	}
+
+	embed.Color = resultColor(&res.PhaseResult)
+	output := res.Output()
+	if writeOutputBlock(&b, output, embedOutputLength) || res.Truncated {
+		files = append(files, textFile(outputAttachmentName, output))
+	}
+	embed.Description = b.String()
+
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{Name: "Status", Value: describeStatus(&res.PhaseResult), Inline: true},
+		&discordgo.MessageEmbedField{Name: "Wall time", Value: describeWallTime(res), Inline: true},
+	)
+	if res.CPUTime > 0 || res.PeakMemory > 0 {
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{
+				Name: "CPU time", Value: res.CPUTime.Round(time.Millisecond).String(), Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Peak memory",
+				Value:  fmt.Sprintf("%s / %s", formatBytes(res.PeakMemory), formatBytes(res.MemoryLimit)),
+				Inline: true,
+			},
+		)
+	}
+
+	if res.Compile != nil && res.Compile.Output() != "" {
+		var diagnostics strings.Builder
+		if writeOutputBlock(&diagnostics, res.Compile.Output(), embedCompilerLength) {
+			files = append(files, textFile(compileAttachmentName, res.Compile.Output()))
+		}
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "Compiler output", Value: diagnostics.String()})
+	}
+
+	var notes []string
+	if res.Truncated {
+		notes = append(notes, "✂️ Output exceeded the capture limit")
+	}
+	msg := finishEmbed(embed, files, notes...)
+	attachArtifacts(msg, &res.PhaseResult)
+	return msg
+}
+
+// finishEmbed adds footer notes, including one for attached output, and
+// wraps the embed
+func finishEmbed(embed *discordgo.MessageEmbed, files []*discordgo.File, notes ...string) *discordgo.MessageSend {
+	if len(files) > 0 {
+		notes = append(notes, "📎 Full captured output attached")
+	}
+	if len(notes) > 0 {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: strings.Join(notes, " · ")}
+	}
+	return &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}, Files: files}
+}
+
+// describeStatus summarizes how a phase ended in a single line
+func describeStatus(p *executor.PhaseResult) string {
+	switch p.Reason {
+	case executor.TerminationSuccess:
+		return "✅ Exit code 0"
+	case executor.TerminationNonZeroExit:
+		return fmt.Sprintf("❌ Exit code %d", p.ExitCode)
+	default:
+		return describeTermination(p)
+	}
+}
+
+// describeWallTime shows the run duration, preceded by the compile
+// duration for compiled languages
+func describeWallTime(res *executor.Result) string {
+	run := res.Duration.Round(time.Millisecond)
+	if res.Compile == nil {
+		return run.String()
+	}
+	return fmt.Sprintf("compile %s · run %s", res.Compile.Duration.Round(time.Millisecond), run)
+}
+
+// resultColor picks the embed color for how a phase ended
+func resultColor(p *executor.PhaseResult) int {
+	switch p.Reason {
+	case executor.TerminationSuccess:
+		return colorSuccess
+	case executor.TerminationNonZeroExit:
+		return colorFailure
+	case executor.TerminationCanceled:
+		return colorCanceled
+	default:
+		return colorKilled
+	}
+}
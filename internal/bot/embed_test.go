@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+func TestRenderResultEmbedSuccess(t *testing.T) {
+	msg := renderResultEmbed(&executor.Result{
+		Language: "python",
+		PhaseResult: executor.PhaseResult{
+			Stdout:      "hello\n",
+			Reason:      executor.TerminationSuccess,
+			Duration:    120 * time.Millisecond,
+			CPUTime:     80 * time.Millisecond,
+			PeakMemory:  12 << 20,
+			MemoryLimit: 128 << 20,
+		},
+	})
+
+	if len(msg.Embeds) != 1 {
+		t.Fatalf("Expected one embed, got %d", len(msg.Embeds))
+	}
+	embed := msg.Embeds[0]
+	if embed.Color != colorSuccess {
+		t.Errorf("Expected success color, got %#x", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "hello") {
+		t.Errorf("Expected output in description, got '%s'", embed.Description)
+	}
+	if embed.Footer != nil {
+		t.Errorf("Expected no footer, got '%s'", embed.Footer.Text)
+	}
+
+	fields := map[string]string{}
+	for _, f := range embed.Fields {
+		fields[f.Name] = f.Value
+	}
+	if fields["Peak memory"] != "12 MB / 128 MB" {
+		t.Errorf("Expected peak memory against the limit, got '%s'", fields["Peak memory"])
+	}
+	if fields["Status"] != "✅ Exit code 0" {
+		t.Errorf("Expected success status, got '%s'", fields["Status"])
+	}
+}
+
+func TestRenderResultEmbedTruncated(t *testing.T) {
+	msg := renderResultEmbed(&executor.Result{
+		Language: "python",
+		PhaseResult: executor.PhaseResult{
+			Stdout:    "spam\n",
+			Reason:    executor.TerminationOutputLimit,
+			Truncated: true,
+		},
+	})
+
+	embed := msg.Embeds[0]
+	if embed.Color != colorKilled {
+		t.Errorf("Expected killed color, got %#x", embed.Color)
+	}
+	if embed.Footer == nil || !strings.Contains(embed.Footer.Text, "capture limit") {
+		t.Error("Expected a truncation marker in the footer")
+	}
+	if len(msg.Files) != 1 {
+		t.Errorf("Expected full output attached, got %d files", len(msg.Files))
+	}
+}
+
+func TestRenderResultEmbedCompileFailed(t *testing.T) {
+	msg := renderResultEmbed(&executor.Result{
+		Language: "go",
+		Compile: &executor.PhaseResult{
+			Stderr:   "./main.go:1: syntax error\n",
+			ExitCode: 1,
+			Reason:   executor.TerminationNonZeroExit,
+		},
+	})
+
+	embed := msg.Embeds[0]
+	if !strings.Contains(embed.Title, "compilation failed") {
+		t.Errorf("Expected compilation failure title, got '%s'", embed.Title)
+	}
+	if embed.Color != colorFailure {
+		t.Errorf("Expected failure color, got %#x", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "syntax error") {
+		t.Errorf("Expected diagnostics in description, got '%s'", embed.Description)
+	}
+}
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// execution tracks a running execution so it can be cancelled
+type execution struct {
+	// Execution ID, shown to users for /cancel
+	ID string
+
+	// User who requested the execution
+	UserID string
+
+	// Channel the execution was requested in
+	ChannelID string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+
+	// Message showing progress, carrying the stop button and reaction
+	progressMessageID string
+
+	// Live output shown on the progress message, nil when not streamed
+	output *liveOutput
+
+	// User who cancelled the execution, empty while it is still running
+	canceledBy string
+
+	// Whether the execution got a slot, and whether shutdown stopped it
+	started     bool
+	interrupted bool
+
+	// Result of the finished execution, nil until then or if it failed
+	result *executor.Result
+}
+
+// Cancel stops the execution on behalf of userID. It reports false if the
+// execution was already cancelled.
+func (e *execution) Cancel(userID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.canceledBy != "" {
+		return false
+	}
+	e.canceledBy = userID
+	e.cancel()
+	return true
+}
+
+// CanceledBy returns the user who cancelled the execution, if any
+func (e *execution) CanceledBy() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.canceledBy
+}
+
+// begin marks a queued execution as running. It reports false if shutdown
+// interrupted it while it waited.
+func (e *execution) begin() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.interrupted {
+		return false
+	}
+	e.started = true
+	return true
+}
+
+// Interrupt stops the execution because the bot is shutting down: one still
+// queued always, a running one only with force. It reports whether the
+// execution was stopped.
+func (e *execution) Interrupt(force bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.interrupted || (e.started && !force) {
+		return false
+	}
+	e.interrupted = true
+	e.cancel()
+	return true
+}
+
+// Interrupted reports whether shutdown stopped the execution
+func (e *execution) Interrupted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.interrupted
+}
+
+// setProgressMessage records the message that shows the execution's progress
+func (e *execution) setProgressMessage(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.progressMessageID = id
+}
+
+// executionRegistry indexes running executions by ID
+type executionRegistry struct {
+	mu      sync.Mutex
+	running map[string]*execution
+}
+
+// newExecutionRegistry creates an empty registry
+func newExecutionRegistry() *executionRegistry {
+	return &executionRegistry{running: make(map[string]*execution)}
+}
+
+// start registers a new cancellable execution
+func (r *executionRegistry) start(id, userID, channelID string) *execution {
+	ctx, cancel := context.WithCancel(context.Background())
+	exec := &execution{ID: id, UserID: userID, ChannelID: channelID, ctx: ctx, cancel: cancel}
+
+	r.mu.Lock()
+	r.running[id] = exec
+	r.mu.Unlock()
+
+	return exec
+}
+
+// finish removes an execution from the registry and releases its context
+func (r *executionRegistry) finish(exec *execution) {
+	r.mu.Lock()
+	delete(r.running, exec.ID)
+	r.mu.Unlock()
+
+	exec.cancel()
+}
+
+// get returns the running execution with the given ID
+func (r *executionRegistry) get(id string) (*execution, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exec, ok := r.running[id]
+	return exec, ok
+}
+
+// interrupt stops executions for shutdown, running ones only with force,
+// returning how many were stopped
+func (r *executionRegistry) interrupt(force bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+	for _, exec := range r.running {
+		if exec.Interrupt(force) {
+			n++
+		}
+	}
+	return n
+}
+
+// count returns how many executions are registered
+func (r *executionRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.running)
+}
+
+// byProgressMessage returns the running execution whose progress is shown in messageID
+func (r *executionRegistry) byProgressMessage(messageID string) (*execution, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, exec := range r.running {
+		exec.mu.Lock()
+		match := exec.progressMessageID == messageID
+		exec.mu.Unlock()
+		if match {
+			return exec, true
+		}
+	}
+	return nil, false
+}
+
+// canCancel reports whether userID may cancel exec: the requester always
+// may, and so may moderators
+func canCancel(s *discordgo.Session, exec *execution, userID string) bool {
+	return userID == exec.UserID || isModerator(s, userID, exec.ChannelID)
+}
+
+// isModerator reports whether userID has Manage Messages in the channel
+func isModerator(s *discordgo.Session, userID, channelID string) bool {
+	perms, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return false
+	}
+	return perms&discordgo.PermissionManageMessages != 0
+}
@@ -0,0 +1,43 @@
+package bot
+
+import "testing"
+
+func TestExecutionRegistryCancel(t *testing.T) {
+	registry := newExecutionRegistry()
+	exec := registry.start("exec-1", "user-1", "channel-1")
+	exec.setProgressMessage("progress-1")
+
+	if got, ok := registry.get("exec-1"); !ok || got != exec {
+		t.Fatal("Expected execution to be registered")
+	}
+	if got, ok := registry.byProgressMessage("progress-1"); !ok || got != exec {
+		t.Fatal("Expected execution to be found by its progress message")
+	}
+
+	if !exec.Cancel("user-2") {
+		t.Fatal("Expected first cancellation to succeed")
+	}
+	if exec.Cancel("user-3") {
+		t.Error("Expected second cancellation to be rejected")
+	}
+	if exec.CanceledBy() != "user-2" {
+		t.Errorf("Expected execution cancelled by user-2, got '%s'", exec.CanceledBy())
+	}
+	if exec.ctx.Err() == nil {
+		t.Error("Expected execution context to be cancelled")
+	}
+
+	registry.finish(exec)
+	if _, ok := registry.get("exec-1"); ok {
+		t.Error("Expected finished execution to be removed")
+	}
+}
+
+func TestCanCancelRequester(t *testing.T) {
+	exec := &execution{ID: "exec-1", UserID: "user-1", ChannelID: "channel-1"}
+
+	// The requester is allowed without consulting channel permissions
+	if !canCancel(nil, exec, "user-1") {
+		t.Error("Expected requester to be allowed to cancel")
+	}
+}
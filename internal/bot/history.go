@@ -0,0 +1,405 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// History commands and controls
+const (
+	historyCommandName = "history"
+	showCommandName    = "show"
+	forgetCommandName  = "forget-me"
+
+	// Slash command options
+	optionPage    = "page"
+	optionConfirm = "confirm"
+
+	// Custom ID prefix of history page buttons: history:<user>:<page>
+	historyButtonPrefix = "history:"
+
+	// Runs listed per history page
+	historyPageSize = 10
+
+	// Characters of code shown per run in the history list
+	historySummaryLength = 60
+
+	// How often expired history is deleted
+	historyPruneInterval = time.Hour
+)
+
+// errHistoryDisabled is shown when the bot runs without a store
+var errHistoryDisabled = errors.New("execution history is not enabled on this bot")
+
+// historyCommands returns the application commands for browsing history
+func historyCommands() []*discordgo.ApplicationCommand {
+	minPage := 1.0
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:        historyCommandName,
+			Description: "List your past runs",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        optionPage,
+					Description: "Page to show, newest runs first",
+					MinValue:    &minPage,
+				},
+			},
+		},
+		{
+			Name:        showCommandName,
+			Description: "Show the code and output of a past run",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        optionExecutionID,
+					Description: "Execution ID listed by /history",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        forgetCommandName,
+			Description: "Delete everything the bot has stored about you",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        optionConfirm,
+					Description: "Confirm the deletion; it cannot be undone",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// saveHistory adds a finished run to the user's history. Failures are
+// logged rather than affecting the reply.
+func (b *Bot) saveHistory(exec *execution, who member, cmd *runCommand, res *executor.Result) {
+	if b.store == nil {
+		return
+	}
+	record := historyRecord(exec, who, cmd, res)
+	record.Limit(b.history.MaxOutputBytes)
+	if err := b.store.SaveExecution(record); err != nil {
+		logrus.WithError(err).WithField("execution_id", exec.ID).Error("Failed to save execution history")
+	}
+}
+
+// historyRecord describes a finished run for the history store
+func historyRecord(exec *execution, who member, cmd *runCommand, res *executor.Result) *store.Execution {
+	e := &store.Execution{
+		ID:        exec.ID,
+		UserID:    who.UserID,
+		GuildID:   who.GuildID,
+		ChannelID: exec.ChannelID,
+		CreatedAt: time.Now().UTC(),
+
+		Language: res.Language,
+		Code:     cmd.Code,
+		Stdin:    cmd.Stdin,
+		Args:     cmd.Args,
+		Tier:     string(cmd.Tier),
+		Network:  string(cmd.Network),
+
+		Output:      res.Output(),
+		Truncated:   res.Truncated,
+		Reason:      string(res.Reason),
+		ExitCode:    res.ExitCode,
+		Duration:    res.Duration,
+		CPUTime:     res.CPUTime,
+		PeakMemory:  res.PeakMemory,
+		MemoryLimit: res.MemoryLimit,
+		Timeout:     res.Timeout,
+	}
+	for _, f := range cmd.Files {
+		e.Files = append(e.Files, f.Path)
+	}
+
+	if res.CompileFailed() {
+		c := res.Compile
+		e.CompileFailed = true
+		e.Output, e.Truncated = c.Output(), c.Truncated
+		e.Reason, e.ExitCode = string(c.Reason), c.ExitCode
+		e.Duration, e.CPUTime = c.Duration, c.CPUTime
+		e.PeakMemory, e.MemoryLimit, e.Timeout = c.PeakMemory, c.MemoryLimit, c.Timeout
+	}
+	return e
+}
+
+// historyResult rebuilds the result of a stored run for rendering
+func historyResult(e *store.Execution) *executor.Result {
+	phase := executor.PhaseResult{
+		Stdout:      e.Output,
+		ExitCode:    e.ExitCode,
+		Reason:      executor.TerminationReason(e.Reason),
+		Duration:    e.Duration,
+		CPUTime:     e.CPUTime,
+		PeakMemory:  e.PeakMemory,
+		MemoryLimit: e.MemoryLimit,
+		Timeout:     e.Timeout,
+		Truncated:   e.Truncated,
+	}
+	if e.CompileFailed {
+		return &executor.Result{Language: e.Language, Compile: &phase}
+	}
+	return &executor.Result{Language: e.Language, PhaseResult: phase}
+}
+
+// onHistoryCommand handles /history
+func (b *Bot) onHistoryCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	page := 1
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == optionPage {
+			page = int(opt.IntValue())
+		}
+	}
+
+	msg, err := b.historyPage(interactionUser(i).ID, page)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     msg.Embeds,
+			Components: msg.Components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to respond to interaction")
+	}
+}
+
+// onHistoryButton turns the page of a history listing
+func (b *Bot) onHistoryButton(s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	userID, pageText, _ := strings.Cut(strings.TrimPrefix(customID, historyButtonPrefix), ":")
+	page, err := strconv.Atoi(pageText)
+	if err != nil || userID != interactionUser(i).ID {
+		b.respondEphemeral(s, i.Interaction, "❌ This history listing is not yours.")
+		return
+	}
+
+	msg, err := b.historyPage(userID, page)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Embeds: msg.Embeds, Components: msg.Components},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to update history listing")
+	}
+}
+
+// historyPage renders one page of a user's history, with buttons to the
+// neighbouring pages
+func (b *Bot) historyPage(userID string, page int) (*discordgo.MessageSend, error) {
+	if b.store == nil {
+		return nil, errHistoryDisabled
+	}
+	page = max(page, 1)
+	records, total, err := b.store.Executions(userID, (page-1)*historyPageSize, historyPageSize)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to read execution history")
+		return nil, errors.New("failed to read your history")
+	}
+	return renderHistoryPage(userID, page, records, total), nil
+}
+
+// renderHistoryPage lists runs, one per line, as an embed
+func renderHistoryPage(userID string, page int, records []*store.Execution, total int) *discordgo.MessageSend {
+	pages := max((total+historyPageSize-1)/historyPageSize, 1)
+	embed := &discordgo.MessageEmbed{
+		Title:  "📜 Your runs",
+		Color:  colorCanceled,
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d of %d · %d runs", page, pages, total)},
+	}
+
+	var lines []string
+	for _, e := range records {
+		res := historyResult(e)
+		phase := &res.PhaseResult
+		if res.Compile != nil {
+			phase = res.Compile
+		}
+		summary, _ := headOfOutput(e.Summary(), historySummaryLength)
+		lines = append(lines, fmt.Sprintf("`%s` · <t:%d:R> · **%s** · %s\n`%s`",
+			e.ID, e.CreatedAt.Unix(), e.Language, describeStatus(phase), strings.ReplaceAll(summary, "`", "'")))
+	}
+	switch {
+	case total == 0:
+		lines = append(lines, "You have no saved runs yet.")
+	case len(lines) == 0:
+		lines = append(lines, "No runs on this page.")
+	default:
+		lines = append(lines, "\nUse `/show <id>` to see a run again.")
+	}
+	embed.Description = strings.Join(lines, "\n")
+
+	button := func(label string, target int, disabled bool) discordgo.Button {
+		return discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%s:%d", historyButtonPrefix, userID, target),
+			Disabled: disabled,
+		}
+	}
+	return &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				button("◀ Newer", page-1, page <= 1),
+				button("Older ▶", page+1, page >= pages),
+			}},
+		},
+	}
+}
+
+// onShowCommand handles /show, posting a past run of the invoker's again
+// with its source attached so the result buttons work on it
+func (b *Bot) onShowCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if b.store == nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errHistoryDisabled.Error()+".")
+		return
+	}
+	var id string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == optionExecutionID {
+			id = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	userID := interactionUser(i).ID
+	e, err := b.store.Execution(id)
+	// Other users' runs are reported as missing so IDs reveal nothing
+	if errors.Is(err, store.ErrNotFound) || (err == nil && e.UserID != userID) {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("❌ No run `%s` in your history.", id))
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("execution_id", id).Error("Failed to read execution history")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to read your history.")
+		return
+	}
+
+	msg := b.render(i.ChannelID, historyResult(e))
+	note := fmt.Sprintf("📜 Run `%s` from <t:%d:f>.", e.ID, e.CreatedAt.Unix())
+	if len(e.Files) > 0 {
+		note += " Uploaded files are not kept: " + strings.Join(e.Files, ", ")
+	}
+	appendNote(msg, note)
+	msg.Files = append(msg.Files, sourceFiles(&runCommand{Language: e.Language, Code: e.Code, Stdin: e.Stdin})...)
+	if e.Code != "" {
+		msg.Components = resultButtons(userID, e.Language, "")
+	}
+	b.respond(s, i.Interaction, msg)
+}
+
+// onForgetCommand handles /forget-me. Audit entries are kept, as removing
+// them would break the log's hash chain.
+func (b *Bot) onForgetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if b.store == nil {
+		b.respondEphemeral(s, i.Interaction, "Nothing is stored about you: "+errHistoryDisabled.Error()+".")
+		return
+	}
+	confirmed := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == optionConfirm {
+			confirmed = opt.BoolValue()
+		}
+	}
+	if !confirmed {
+		b.respondEphemeral(s, i.Interaction, "Nothing was deleted. Run `/forget-me confirm:True` to delete your data.")
+		return
+	}
+
+	userID := interactionUser(i).ID
+	forgotten, err := b.store.ForgetUser(userID)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to delete user data")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to delete your data; please try again later.")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"user_id": userID, "forgotten": forgotten}).Info("Deleted user data")
+	b.respondEphemeral(s, i.Interaction, describeForgotten(forgotten))
+}
+
+// describeForgotten tells a user what /forget-me removed
+func describeForgotten(f store.Forgotten) string {
+	var deleted []string
+	for _, part := range []struct {
+		count int64
+		what  string
+	}{
+		{f.Executions, "run history records"},
+		{f.Snippets, "personal snippet versions"},
+		{f.Schedules, "schedules"},
+	} {
+		if part.count > 0 {
+			deleted = append(deleted, fmt.Sprintf("%d %s", part.count, part.what))
+		}
+	}
+
+	var sb strings.Builder
+	if len(deleted) == 0 {
+		sb.WriteString("🗑️ Nothing personal was stored about you.")
+	} else {
+		sb.WriteString("🗑️ Deleted " + strings.Join(deleted, ", ") + ".")
+	}
+	if f.Unattributed > 0 {
+		fmt.Fprintf(&sb, " %d shared snippets, aliases and templates stay with their server without your name.",
+			f.Unattributed)
+	}
+	sb.WriteString(" The security audit log keeps only code hashes and run metadata, and is not affected.")
+	return sb.String()
+}
+
+// pruneHistory deletes runs past the retention policy
+func (b *Bot) pruneHistory() {
+	var before time.Time
+	if b.history.RetentionDays > 0 {
+		before = time.Now().AddDate(0, 0, -b.history.RetentionDays)
+	}
+	deleted, err := b.store.PruneExecutions(before, b.history.MaxPerUser)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to prune execution history")
+		return
+	}
+	if deleted > 0 {
+		logrus.WithField("deleted", deleted).Info("Pruned execution history")
+	}
+}
+
+// keepHistoryPruned applies the retention policy now and then periodically
+// until done is closed
+func (b *Bot) keepHistoryPruned(done <-chan struct{}) {
+	if b.store == nil {
+		return
+	}
+	ticker := time.NewTicker(historyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		b.pruneHistory()
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+func TestSaveHistory(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	b := &Bot{store: db, history: config.HistoryConfig{MaxOutputBytes: 8}}
+
+	registry := newExecutionRegistry()
+	exec := registry.start("e1", "u1", "c1")
+	defer registry.finish(exec)
+	who := member{GuildID: "g1", UserID: "u1"}
+	cmd := &runCommand{Language: "py", Code: "print('hello world')", Files: []executor.File{{Path: "data.csv"}}}
+
+	res := &executor.Result{Language: "python"}
+	res.Stdout = "hello world\n"
+	res.Reason = executor.TerminationSuccess
+	b.saveHistory(exec, who, cmd, res)
+
+	e, err := db.Execution("e1")
+	if err != nil {
+		t.Fatalf("Expected the run to be saved, got %v", err)
+	}
+	if e.UserID != "u1" || e.GuildID != "g1" || e.Language != "python" || e.Code != cmd.Code {
+		t.Errorf("Expected who ran what to be saved, got %+v", e)
+	}
+	if e.Output != "hello wo" || !e.Truncated {
+		t.Errorf("Expected the output cut to the history limit, got %q", e.Output)
+	}
+	if len(e.Files) != 1 || e.Files[0] != "data.csv" {
+		t.Errorf("Expected uploaded file names to be listed, got %v", e.Files)
+	}
+}
+
+func TestHistoryRecordRoundTrip(t *testing.T) {
+	registry := newExecutionRegistry()
+	exec := registry.start("e1", "u1", "c1")
+	defer registry.finish(exec)
+
+	res := &executor.Result{Language: "c", Compile: &executor.PhaseResult{
+		Stdout:   "main.c:1: error",
+		ExitCode: 1,
+		Reason:   executor.TerminationNonZeroExit,
+	}}
+	e := historyRecord(exec, member{UserID: "u1"}, &runCommand{Language: "c", Code: "int main("}, res)
+	if !e.CompileFailed || e.Output != "main.c:1: error" || e.ExitCode != 1 {
+		t.Fatalf("Expected the compile phase to be kept, got %+v", e)
+	}
+
+	shown := historyResult(e)
+	if !shown.CompileFailed() || shown.Compile.Output() != "main.c:1: error" {
+		t.Errorf("Expected a failed compilation back, got %+v", shown)
+	}
+}
+
+func TestRenderHistoryPage(t *testing.T) {
+	records := []*store.Execution{
+		{ID: "e2", Language: "python", Code: "\n  print(2)\nprint(3)", Reason: string(executor.TerminationSuccess)},
+		{ID: "e1", Language: "bash", Code: "exit 3", Reason: string(executor.TerminationNonZeroExit), ExitCode: 3},
+	}
+	msg := renderHistoryPage("u1", 2, records, 12)
+
+	embed := msg.Embeds[0]
+	if !strings.Contains(embed.Description, "`e2`") || !strings.Contains(embed.Description, "`print(2)`") {
+		t.Errorf("Expected each run's ID and first line of code, got %q", embed.Description)
+	}
+	if !strings.Contains(embed.Description, "Exit code 3") {
+		t.Errorf("Expected each run's status, got %q", embed.Description)
+	}
+	if embed.Footer.Text != "Page 2 of 2 · 12 runs" {
+		t.Errorf("Expected the page count in the footer, got %q", embed.Footer.Text)
+	}
+
+	row := msg.Components[0].(discordgo.ActionsRow)
+	newer, older := row.Components[0].(discordgo.Button), row.Components[1].(discordgo.Button)
+	if newer.CustomID != "history:u1:1" || newer.Disabled {
+		t.Errorf("Expected an enabled button to page 1, got %+v", newer)
+	}
+	if !older.Disabled {
+		t.Error("Expected no older page after the last")
+	}
+}
+
+func TestDescribeForgotten(t *testing.T) {
+	msg := describeForgotten(store.Forgotten{Executions: 3, Schedules: 1, Unattributed: 2})
+	for _, want := range []string{"3 run history records", "1 schedules", "2 shared snippets, aliases and templates"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+	if strings.Contains(msg, "personal snippet") {
+		t.Errorf("Expected kinds with nothing deleted to be left out, got %q", msg)
+	}
+
+	if msg := describeForgotten(store.Forgotten{}); !strings.Contains(msg, "Nothing personal") {
+		t.Errorf("Expected an empty result to say so, got %q", msg)
+	}
+}
@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Interaction token validity
+const (
+	// How long Discord accepts follow-ups and edits for an interaction
+	interactionTokenLifetime = 15 * time.Minute
+
+	// Safety margin so an edit is not attempted just as the token expires
+	interactionTokenMargin = 30 * time.Second
+)
+
+// undeliveredContent replaces a result that Discord refused to accept
+const undeliveredContent = "❌ The result could not be delivered."
+
+// errInteractionExpired is returned when editing a reply whose token has expired
+var errInteractionExpired = errors.New("interaction token expired")
+
+// deferredReply is an acknowledged interaction whose response is filled in
+// later, while its token is still valid
+type deferredReply struct {
+	interaction *discordgo.Interaction
+
+	// User the result is addressed to when falling back to a channel message
+	userID string
+
+	// When the interaction token stops being accepted
+	expires time.Time
+
+	// Serializes edits so a late progress update cannot follow the result
+	mu sync.Mutex
+}
+
+// deferReply acknowledges an interaction so Discord shows a thinking state
+// instead of failing after three seconds
+func (b *Bot) deferReply(s *discordgo.Session, i *discordgo.InteractionCreate) (*deferredReply, error) {
+	return deferResponse(s, i, 0)
+}
+
+// deferEphemeralReply is deferReply for a response only the invoker sees
+func (b *Bot) deferEphemeralReply(s *discordgo.Session, i *discordgo.InteractionCreate) (*deferredReply, error) {
+	return deferResponse(s, i, discordgo.MessageFlagsEphemeral)
+}
+
+// deferResponse acknowledges an interaction with the given message flags
+func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate,
+	flags discordgo.MessageFlags) (*deferredReply, error) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: flags},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to defer interaction response: %w", err)
+	}
+
+	return &deferredReply{
+		interaction: i.Interaction,
+		userID:      interactionUser(i).ID,
+		expires:     time.Now().Add(interactionTokenLifetime - interactionTokenMargin),
+	}, nil
+}
+
+// valid reports whether the interaction token can still be used
+func (d *deferredReply) valid() bool {
+	return time.Now().Before(d.expires)
+}
+
+// edit replaces the deferred response while the token is valid
+func (d *deferredReply) edit(s *discordgo.Session, edit *discordgo.WebhookEdit) (*discordgo.Message, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.valid() {
+		return nil, errInteractionExpired
+	}
+	edit.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	return s.InteractionResponseEdit(d.interaction, edit)
+}
+
+// reply fills in the deferred response with a text message
+func (d *deferredReply) reply(s *discordgo.Session, content string) {
+	if _, err := d.edit(s, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		logrus.WithError(err).WithField("interaction_id", d.interaction.ID).Error("Failed to send reply")
+	}
+}
+
+// replyPrivately withdraws the deferred response and answers with a message
+// only the invoker sees, for refusals that should not be posted publicly
+func (d *deferredReply) replyPrivately(s *discordgo.Session, content string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	log := logrus.WithField("interaction_id", d.interaction.ID)
+	if err := s.InteractionResponseDelete(d.interaction); err != nil {
+		log.WithError(err).Warn("Failed to withdraw deferred response")
+	}
+	_, err := s.FollowupMessageCreate(d.interaction, false, &discordgo.WebhookParams{
+		Content:         content,
+		Flags:           discordgo.MessageFlagsEphemeral,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to send reply")
+	}
+}
+
+// deliver fills in the deferred response with the final message, removing
+// any controls. If the token has expired, the message is posted to the
+// channel instead, mentioning the requester.
+func (b *Bot) deliver(s *discordgo.Session, d *deferredReply, msg *discordgo.MessageSend) {
+	// Sending drains the file readers, so keep the contents for a fallback
+	files := bufferFiles(msg.Files)
+
+	components := []discordgo.MessageComponent{}
+	if msg.Components != nil {
+		components = msg.Components
+	}
+	edit := &discordgo.WebhookEdit{Content: &msg.Content, Components: &components, Files: files()}
+	if len(msg.Embeds) > 0 {
+		edit.Embeds = &msg.Embeds
+	}
+	_, err := d.edit(s, edit)
+	if err == nil {
+		return
+	}
+	log := logrus.WithError(err).WithField("interaction_id", d.interaction.ID)
+	if !tokenExpired(err) {
+		// The response stays where it is rather than being posted twice;
+		// a plain notice replaces the progress and its stop button
+		log.Error("Failed to deliver result")
+		if _, err := d.edit(s, undeliveredEdit()); err != nil {
+			logrus.WithError(err).WithField("interaction_id", d.interaction.ID).Error("Failed to report undelivered result")
+		}
+		return
+	}
+	log.Warn("Falling back to a channel message")
+
+	msg.Content = fmt.Sprintf("<@%s> %s", d.userID, msg.Content)
+	msg.AllowedMentions = &discordgo.MessageAllowedMentions{Users: []string{d.userID}}
+	msg.Files = files()
+	if _, err := s.ChannelMessageSendComplex(d.interaction.ChannelID, msg); err != nil {
+		logrus.WithError(err).WithField("channel_id", d.interaction.ChannelID).Error("Failed to send result")
+	}
+}
+
+// undeliveredEdit replaces a deferred response with the plain undelivered
+// notice, clearing any files, embeds and controls
+func undeliveredEdit() *discordgo.WebhookEdit {
+	content := undeliveredContent
+	return &discordgo.WebhookEdit{
+		Content:     &content,
+		Components:  &[]discordgo.MessageComponent{},
+		Embeds:      &[]*discordgo.MessageEmbed{},
+		Attachments: &[]*discordgo.MessageAttachment{},
+	}
+}
+
+// tokenExpired reports whether err means the interaction can no longer be
+// edited, so the response has to go to the channel
+func tokenExpired(err error) bool {
+	if errors.Is(err, errInteractionExpired) {
+		return true
+	}
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return false
+	}
+	switch restErr.Message.Code {
+	case discordgo.ErrCodeUnknownWebhook, discordgo.ErrCodeInvalidWebhookTokenProvided,
+		discordgo.ErrCodeUnknownInteraction:
+		return true
+	}
+	return false
+}
+
+// bufferFiles reads the files into memory and returns a function that
+// yields fresh copies each time they are sent
+func bufferFiles(files []*discordgo.File) func() []*discordgo.File {
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		data, err := io.ReadAll(f.Reader)
+		if err != nil {
+			logrus.WithError(err).WithField("file", f.Name).Warn("Failed to buffer attachment")
+		}
+		contents[i] = data
+	}
+
+	return func() []*discordgo.File {
+		fresh := make([]*discordgo.File, len(files))
+		for i, f := range files {
+			fresh[i] = &discordgo.File{Name: f.Name, ContentType: f.ContentType, Reader: bytes.NewReader(contents[i])}
+		}
+		return fresh
+	}
+}
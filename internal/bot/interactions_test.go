@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDeferredReplyExpired(t *testing.T) {
+	d := &deferredReply{
+		interaction: &discordgo.Interaction{ID: "1"},
+		expires:     time.Now().Add(-time.Second),
+	}
+
+	if d.valid() {
+		t.Error("Expected expired reply to be invalid")
+	}
+
+	// An expired token must not reach the API, so no session is needed
+	content := "result"
+	if _, err := d.edit(nil, &discordgo.WebhookEdit{Content: &content}); !errors.Is(err, errInteractionExpired) {
+		t.Errorf("Expected errInteractionExpired, got %v", err)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	restErr := func(code int) error {
+		return &discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: code}}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "expired locally", err: errInteractionExpired, expected: true},
+		{name: "unknown webhook", err: restErr(discordgo.ErrCodeUnknownWebhook), expected: true},
+		{name: "invalid token", err: restErr(discordgo.ErrCodeInvalidWebhookTokenProvided), expected: true},
+		{name: "missing permissions", err: restErr(discordgo.ErrCodeMissingPermissions), expected: false},
+		{name: "network error", err: errors.New("connection reset"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenExpired(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBufferFiles(t *testing.T) {
+	files := bufferFiles([]*discordgo.File{{Name: "out.txt", Reader: strings.NewReader("data")}})
+
+	for range 2 {
+		f := files()[0]
+		data, err := io.ReadAll(f.Reader)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if f.Name != "out.txt" || string(data) != "data" {
+			t.Errorf("Expected every copy to hold the contents, got %s %q", f.Name, data)
+		}
+	}
+}
+
+func TestUndeliveredEdits(t *testing.T) {
+	edit := undeliveredEdit()
+	if *edit.Content != undeliveredContent || edit.Files != nil {
+		t.Errorf("Expected a plain notice without files, got %+v", edit)
+	}
+	if edit.Components == nil || len(*edit.Components) != 0 || edit.Embeds == nil || len(*edit.Embeds) != 0 ||
+		edit.Attachments == nil || len(*edit.Attachments) != 0 {
+		t.Error("Expected controls, embeds and attachments to be cleared")
+	}
+
+	msgEdit := undeliveredMessageEdit(&discordgo.Message{ID: "2", ChannelID: "1"})
+	if msgEdit.ID != "2" || msgEdit.Channel != "1" || *msgEdit.Content != undeliveredContent || msgEdit.Files != nil {
+		t.Errorf("Expected a plain notice for the progress message, got %+v", msgEdit)
+	}
+	if msgEdit.Components == nil || len(*msgEdit.Components) != 0 || msgEdit.Embeds == nil ||
+		len(*msgEdit.Embeds) != 0 || msgEdit.Attachments == nil || len(*msgEdit.Attachments) != 0 {
+		t.Error("Expected controls, embeds and attachments to be cleared")
+	}
+}
@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// Slash command showing a user's remaining runs and CPU time
+const quotaCommandName = "quota"
+
+// Throttle scopes, also the keys of the throttled request metric
+const (
+	scopeUser    = "user"
+	scopeChannel = "channel"
+	scopeGuild   = "guild"
+	scopeQuota   = "quota"
+)
+
+// How often buckets that have refilled completely are dropped
+const bucketSweepInterval = 10 * time.Minute
+
+// throttledRequests counts runs refused by rate limits and quotas, by scope
+var throttledRequests = expvar.NewMap("throttled_requests")
+
+// rate is a token bucket's capacity and refill speed; a zero burst is unlimited
+type rate struct {
+	burst     float64
+	perSecond float64
+}
+
+// newRate converts a configured rate limit
+func newRate(cfg config.RateLimit) rate {
+	return rate{burst: float64(cfg.Burst), perSecond: cfg.PerMinute / 60}
+}
+
+// unlimited reports whether the rate never throttles
+func (r rate) unlimited() bool {
+	return r.burst <= 0
+}
+
+// tokenBucket holds the tokens left for one user, channel or guild
+type tokenBucket struct {
+	rate    rate
+	tokens  float64
+	updated time.Time
+}
+
+// fill adds the tokens regained since the last update
+func (b *tokenBucket) fill(now time.Time) {
+	b.tokens = min(b.rate.burst, b.tokens+now.Sub(b.updated).Seconds()*b.rate.perSecond)
+	b.updated = now
+}
+
+// bucketKey identifies a token bucket
+type bucketKey struct {
+	scope string
+	id    string
+}
+
+// bucketRequest asks for a token from a bucket with the given rate
+type bucketRequest struct {
+	key  bucketKey
+	rate rate
+}
+
+// limiter holds the token buckets of every user, channel and guild
+type limiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+// newLimiter creates a limiter with no buckets
+func newLimiter(now func() time.Time) *limiter {
+	return &limiter{now: now, buckets: make(map[bucketKey]*tokenBucket), lastSweep: now()}
+}
+
+// take removes a token from every requested bucket, or from none if any is
+// empty. It returns the scope of the first empty bucket and how long until
+// it regains a token.
+func (l *limiter) take(requests ...bucketRequest) (throttled string, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	buckets := make([]*tokenBucket, 0, len(requests))
+	for _, req := range requests {
+		if req.rate.unlimited() || req.key.id == "" {
+			continue
+		}
+		b := l.bucket(req, now)
+		if b.tokens < 1 {
+			wait := (1 - b.tokens) / b.rate.perSecond
+			return req.key.scope, time.Duration(wait * float64(time.Second))
+		}
+		buckets = append(buckets, b)
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return "", 0
+}
+
+// available returns the whole tokens left in a bucket
+func (l *limiter) available(req bucketRequest) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.bucket(req, l.now()).tokens)
+}
+
+// bucket returns the bucket for req, creating it full, and brings it up to
+// date. The rate follows req, as role changes can change a user's limit.
+// The caller holds mu.
+func (l *limiter) bucket(req bucketRequest, now time.Time) *tokenBucket {
+	b, ok := l.buckets[req.key]
+	if !ok {
+		b = &tokenBucket{rate: req.rate, tokens: req.rate.burst, updated: now}
+		l.buckets[req.key] = b
+	}
+	b.rate = req.rate
+	b.fill(now)
+	return b
+}
+
+// sweep drops buckets that have refilled completely, as a new bucket would
+// be identical; the caller holds mu
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.fill(now)
+		if b.tokens >= b.rate.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// quotaTracker accounts the CPU time each user used during the current UTC day
+type quotaTracker struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	day  time.Time
+	used map[string]time.Duration
+}
+
+// newQuotaTracker creates a tracker with no usage
+func newQuotaTracker(now func() time.Time) *quotaTracker {
+	return &quotaTracker{now: now, used: make(map[string]time.Duration)}
+}
+
+// usage returns the CPU time userID used today
+func (q *quotaTracker) usage(userID string) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	return q.used[userID]
+}
+
+// charge adds CPU time to userID's usage today
+func (q *quotaTracker) charge(userID string, cpu time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	q.used[userID] += cpu
+}
+
+// resetsIn returns the time until usage resets at the next UTC midnight
+func (q *quotaTracker) resetsIn() time.Duration {
+	now := q.now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return midnight.Sub(now)
+}
+
+// rollover clears usage when the day changes; the caller holds mu
+func (q *quotaTracker) rollover() {
+	now := q.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !day.Equal(q.day) {
+		q.day = day
+		q.used = make(map[string]time.Duration)
+	}
+}
+
+// roleLimits overrides the user limits for members of some roles
+type roleLimits struct {
+	roles    map[string]bool
+	user     *rate
+	dailyCPU *time.Duration
+}
+
+// limits applies the configured rate limits and quotas to runs
+type limits struct {
+	user    rate
+	channel rate
+	guild   rate
+
+	// CPU time per user per day; 0 is unlimited
+	dailyCPU time.Duration
+
+	roles []roleLimits
+
+	buckets *limiter
+	quotas  *quotaTracker
+}
+
+// newLimits creates limits from the configuration, reading time from now
+func newLimits(cfg config.LimitsConfig, now func() time.Time) *limits {
+	l := &limits{
+		user:     newRate(cfg.User),
+		channel:  newRate(cfg.Channel),
+		guild:    newRate(cfg.Guild),
+		dailyCPU: time.Duration(cfg.DailyCPUSeconds) * time.Second,
+		buckets:  newLimiter(now),
+		quotas:   newQuotaTracker(now),
+	}
+
+	for _, override := range cfg.Roles {
+		r := roleLimits{roles: make(map[string]bool)}
+		for _, role := range override.Roles {
+			r.roles[role] = true
+		}
+		if override.User != nil {
+			user := newRate(*override.User)
+			r.user = &user
+		}
+		if override.DailyCPUSeconds != nil {
+			cpu := time.Duration(*override.DailyCPUSeconds) * time.Second
+			r.dailyCPU = &cpu
+		}
+		l.roles = append(l.roles, r)
+	}
+
+	return l
+}
+
+// forMember returns the user rate and daily CPU quota for m. Role overrides
+// replace the defaults, and the most generous matching override wins.
+func (l *limits) forMember(m member) (rate, time.Duration) {
+	var user *rate
+	var cpu *time.Duration
+
+	for _, override := range l.roles {
+		if !override.matches(m) {
+			continue
+		}
+		if override.user != nil {
+			user = generousRate(user, *override.user)
+		}
+		if override.dailyCPU != nil {
+			cpu = generousQuota(cpu, *override.dailyCPU)
+		}
+	}
+
+	userRate, dailyCPU := l.user, l.dailyCPU
+	if user != nil {
+		userRate = *user
+	}
+	if cpu != nil {
+		dailyCPU = *cpu
+	}
+	return userRate, dailyCPU
+}
+
+// matches reports whether m has one of the override's roles
+func (r *roleLimits) matches(m member) bool {
+	for _, role := range m.Roles {
+		if r.roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// generousRate returns the more generous of current, if any, and candidate
+func generousRate(current *rate, candidate rate) *rate {
+	if current == nil {
+		return &candidate
+	}
+	if current.unlimited() || candidate.unlimited() {
+		return &rate{}
+	}
+	return &rate{burst: max(current.burst, candidate.burst), perSecond: max(current.perSecond, candidate.perSecond)}
+}
+
+// generousQuota returns the larger of current, if any, and candidate,
+// where zero is unlimited
+func generousQuota(current *time.Duration, candidate time.Duration) *time.Duration {
+	if current == nil || candidate == 0 {
+		return &candidate
+	}
+	if *current == 0 {
+		return current
+	}
+	larger := max(*current, candidate)
+	return &larger
+}
+
+// admit takes a run token from m and the channel and guild, and checks m's
+// CPU quota. It returns why the run must wait, if it must.
+func (l *limits) admit(m member, channelID string) error {
+	userRate, dailyCPU := l.forMember(m)
+
+	if dailyCPU > 0 && l.quotas.usage(m.UserID) >= dailyCPU {
+		throttledRequests.Add(scopeQuota, 1)
+		return fmt.Errorf("you have used your daily CPU time of %s; it resets in %s",
+			dailyCPU, formatWait(l.quotas.resetsIn()))
+	}
+
+	scope, retryAfter := l.buckets.take(
+		bucketRequest{key: bucketKey{scope: scopeUser, id: m.UserID}, rate: userRate},
+		bucketRequest{key: bucketKey{scope: scopeChannel, id: channelID}, rate: l.channel},
+		bucketRequest{key: bucketKey{scope: scopeGuild, id: m.GuildID}, rate: l.guild},
+	)
+	if scope == "" {
+		return nil
+	}
+	throttledRequests.Add(scope, 1)
+
+	subject := map[string]string{
+		scopeUser:    "you are",
+		scopeChannel: "this channel is",
+		scopeGuild:   "this server is",
+	}[scope]
+	return fmt.Errorf("%s running code too often; try again in %s", subject, formatWait(retryAfter))
+}
+
+// charge records CPU time used by userID against their quota
+func (l *limits) charge(userID string, cpu time.Duration) {
+	l.quotas.charge(userID, cpu)
+}
+
+// describe summarizes m's remaining runs and CPU time for /quota
+func (l *limits) describe(m member) string {
+	userRate, dailyCPU := l.forMember(m)
+
+	runs := "unlimited"
+	if !userRate.unlimited() {
+		available := l.buckets.available(bucketRequest{key: bucketKey{scope: scopeUser, id: m.UserID}, rate: userRate})
+		runs = fmt.Sprintf("%d of %d available, %g regained per minute",
+			available, int(userRate.burst), userRate.perSecond*60)
+	}
+
+	used := l.quotas.usage(m.UserID).Round(100 * time.Millisecond)
+	cpu := fmt.Sprintf("%s used, no daily limit", used)
+	if dailyCPU > 0 {
+		cpu = fmt.Sprintf("%s of %s used, %s left; resets in %s",
+			used, dailyCPU, max(dailyCPU-used, 0), formatWait(l.quotas.resetsIn()))
+	}
+
+	return fmt.Sprintf("**Your limits**\nRuns: %s\nCPU time today: %s", runs, cpu)
+}
+
+// onQuotaCommand handles /quota
+func (b *Bot) onQuotaCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	b.respondEphemeral(s, i.Interaction, b.limits.describe(interactionMember(i)))
+}
+
+// formatWait rounds a wait up to whole seconds for display
+func formatWait(d time.Duration) string {
+	return (time.Duration(math.Ceil(d.Seconds())) * time.Second).String()
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// fakeClock is a settable time source
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// throttledCount reads the throttled request metric for a scope
+func throttledCount(scope string) int64 {
+	if v, ok := throttledRequests.Get(scope).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestLimiterTake(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newLimiter(clock.now)
+	user := bucketRequest{key: bucketKey{scope: scopeUser, id: "u1"}, rate: rate{burst: 2, perSecond: 0.5}}
+
+	for i := 0; i < 2; i++ {
+		if scope, _ := l.take(user); scope != "" {
+			t.Fatalf("Expected run %d within burst to pass, throttled by %s", i+1, scope)
+		}
+	}
+	scope, retry := l.take(user)
+	if scope != scopeUser {
+		t.Fatalf("Expected user throttle after burst, got %q", scope)
+	}
+	if retry != 2*time.Second {
+		t.Errorf("Expected retry after 2s, got %s", retry)
+	}
+
+	clock.advance(2 * time.Second)
+	if scope, _ := l.take(user); scope != "" {
+		t.Errorf("Expected refilled token to pass, throttled by %s", scope)
+	}
+}
+
+func TestLimiterTakeIsAllOrNothing(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newLimiter(clock.now)
+	user := bucketRequest{key: bucketKey{scope: scopeUser, id: "u1"}, rate: rate{burst: 5, perSecond: 1}}
+	channel := bucketRequest{key: bucketKey{scope: scopeChannel, id: "c1"}, rate: rate{burst: 1, perSecond: 1}}
+
+	if scope, _ := l.take(user, channel); scope != "" {
+		t.Fatalf("Expected first run to pass, throttled by %s", scope)
+	}
+	if scope, _ := l.take(user, channel); scope != scopeChannel {
+		t.Fatalf("Expected channel throttle, got %q", scope)
+	}
+	if available := l.available(user); available != 4 {
+		t.Errorf("Expected throttled run not to use a user token, %d left", available)
+	}
+}
+
+func TestLimiterSkipsUnlimitedAndMissingScopes(t *testing.T) {
+	l := newLimiter(time.Now)
+	unlimited := bucketRequest{key: bucketKey{scope: scopeUser, id: "u1"}}
+	direct := bucketRequest{key: bucketKey{scope: scopeGuild}, rate: rate{burst: 1, perSecond: 1}}
+
+	for i := 0; i < 10; i++ {
+		if scope, _ := l.take(unlimited, direct); scope != "" {
+			t.Fatalf("Expected unlimited and guildless runs to pass, throttled by %s", scope)
+		}
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newLimiter(clock.now)
+	l.take(bucketRequest{key: bucketKey{scope: scopeUser, id: "u1"}, rate: rate{burst: 1, perSecond: 1}})
+
+	clock.advance(bucketSweepInterval)
+	l.take()
+	if len(l.buckets) != 0 {
+		t.Errorf("Expected refilled buckets to be swept, %d left", len(l.buckets))
+	}
+}
+
+func TestQuotaTrackerResetsDaily(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)}
+	q := newQuotaTracker(clock.now)
+
+	q.charge("u1", 90*time.Second)
+	q.charge("u1", 30*time.Second)
+	if used := q.usage("u1"); used != 2*time.Minute {
+		t.Errorf("Expected 2m used, got %s", used)
+	}
+	if resets := q.resetsIn(); resets != time.Hour {
+		t.Errorf("Expected reset in 1h, got %s", resets)
+	}
+
+	clock.advance(time.Hour)
+	if used := q.usage("u1"); used != 0 {
+		t.Errorf("Expected usage to reset at midnight, got %s", used)
+	}
+}
+
+func TestLimitsRoleOverrides(t *testing.T) {
+	unlimited := 0
+	hour := 3600
+	l := newLimits(config.LimitsConfig{
+		User:            config.RateLimit{Burst: 2, PerMinute: 6},
+		DailyCPUSeconds: 60,
+		Roles: []config.RoleLimits{
+			{Roles: []string{"regular"}, User: &config.RateLimit{Burst: 5, PerMinute: 6}, DailyCPUSeconds: &hour},
+			{Roles: []string{"fast"}, User: &config.RateLimit{Burst: 3, PerMinute: 60}},
+			{Roles: []string{"staff"}, DailyCPUSeconds: &unlimited},
+		},
+	}, time.Now)
+
+	tests := []struct {
+		name  string
+		roles []string
+		rate  rate
+		cpu   time.Duration
+	}{
+		{name: "no roles", rate: rate{burst: 2, perSecond: 0.1}, cpu: time.Minute},
+		{name: "one override", roles: []string{"regular"}, rate: rate{burst: 5, perSecond: 0.1}, cpu: time.Hour},
+		{
+			name:  "most generous of several",
+			roles: []string{"regular", "fast"},
+			rate:  rate{burst: 5, perSecond: 1},
+			cpu:   time.Hour,
+		},
+		{name: "unlimited quota wins", roles: []string{"regular", "staff"}, rate: rate{burst: 5, perSecond: 0.1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, cpu := l.forMember(member{UserID: "u1", Roles: tt.roles})
+			if r != tt.rate {
+				t.Errorf("Expected rate %+v, got %+v", tt.rate, r)
+			}
+			if cpu != tt.cpu {
+				t.Errorf("Expected quota %s, got %s", tt.cpu, cpu)
+			}
+		})
+	}
+}
+
+func TestLimitsAdmit(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newLimits(config.LimitsConfig{
+		User:            config.RateLimit{Burst: 1, PerMinute: 1},
+		Guild:           config.RateLimit{Burst: 10, PerMinute: 10},
+		DailyCPUSeconds: 10,
+	}, clock.now)
+	m := member{GuildID: "g1", UserID: "u1"}
+
+	if err := l.admit(m, "c1"); err != nil {
+		t.Fatalf("Expected first run to be admitted, got %v", err)
+	}
+	before := throttledCount(scopeUser)
+	err := l.admit(m, "c1")
+	if err == nil || !strings.Contains(err.Error(), "you are running code too often; try again in 1m0s") {
+		t.Errorf("Expected user throttle, got %v", err)
+	}
+	if throttledCount(scopeUser) != before+1 {
+		t.Error("Expected the throttled request metric to increase")
+	}
+
+	clock.advance(time.Minute)
+	l.charge("u1", 10*time.Second)
+	err = l.admit(m, "c1")
+	if err == nil || !strings.Contains(err.Error(), "daily CPU time of 10s; it resets in 11h59m0s") {
+		t.Errorf("Expected quota denial, got %v", err)
+	}
+
+	if summary := l.describe(m); !strings.Contains(summary, "10s of 10s used, 0s left") {
+		t.Errorf("Expected exhausted quota in summary, got %q", summary)
+	}
+}
@@ -0,0 +1,635 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// Alias and macro commands and options
+const (
+	aliasCommandName = "alias"
+	macroCommandName = "macro"
+
+	// Subcommands
+	macroSetCommand    = "set"
+	macroRemoveCommand = "remove"
+	macroListCommand   = "list"
+
+	// Options
+	optionCommand  = "command"
+	optionTemplate = "template"
+
+	// Where submitted code goes in a template
+	macroCodePlaceholder = "{{code}}"
+
+	// Most aliases one command may pass through before reaching run
+	maxAliasDepth = 8
+)
+
+// Macro errors
+var (
+	errMacrosDisabled = errors.New("adding aliases and macros needs storage, which is not enabled on this bot")
+	errMacrosInDM     = errors.New("aliases and macros are managed in servers")
+	errInvalidMacro   = errors.New("alias and macro names are 1-32 lowercase letters, digits, - and _")
+	errMacroNoCode    = errors.New("templates must contain " + macroCodePlaceholder + " where the code goes")
+
+	// errAliasLoop means aliases stand for each other in a cycle
+	errAliasLoop = errors.New("aliases stand for each other in a loop")
+)
+
+// macroTemplate wraps submitted code in a harness
+type macroTemplate struct {
+	// Canonical language name
+	Language string
+	Template string
+}
+
+// macroSet holds aliases and templates by name
+type macroSet struct {
+	aliases   map[string]string
+	templates map[string]macroTemplate
+}
+
+// newMacroSet returns an empty set
+func newMacroSet() macroSet {
+	return macroSet{aliases: make(map[string]string), templates: make(map[string]macroTemplate)}
+}
+
+// macros looks up aliases and templates, preferring those admins added to
+// a guild, then the guild's configured ones, then global ones
+type macros struct {
+	mu sync.RWMutex
+
+	global macroSet
+
+	// Configured per-guild sets
+	guilds map[string]macroSet
+
+	// Sets added with /alias and /macro, by guild
+	added map[string]macroSet
+}
+
+// newMacros compiles configured aliases and templates, checking template
+// languages and that every alias leads to the run command
+func newMacros(cfg config.MacrosConfig) (*macros, error) {
+	m := &macros{guilds: make(map[string]macroSet), added: make(map[string]macroSet)}
+
+	var err error
+	if m.global, err = compileMacroSet(cfg.Aliases, cfg.Templates); err != nil {
+		return nil, err
+	}
+	for guildID, guild := range cfg.Guilds {
+		if m.guilds[guildID], err = compileMacroSet(guild.Aliases, guild.Templates); err != nil {
+			return nil, fmt.Errorf("guild %s: %w", guildID, err)
+		}
+	}
+
+	for name := range cfg.Aliases {
+		if err := m.checkAlias("", name, nil); err != nil {
+			return nil, err
+		}
+	}
+	for guildID, guild := range cfg.Guilds {
+		for name := range guild.Aliases {
+			if err := m.checkAlias(guildID, name, nil); err != nil {
+				return nil, fmt.Errorf("guild %s: %w", guildID, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// compileMacroSet builds a set from configuration
+func compileMacroSet(aliases map[string]string, templates map[string]config.MacroTemplate) (macroSet, error) {
+	set := newMacroSet()
+	for name, command := range aliases {
+		if isReservedAlias(name) {
+			return set, fmt.Errorf("alias %q would hide the %s command", name, name)
+		}
+		set.aliases[name] = strings.TrimSpace(command)
+	}
+	for name, tmpl := range templates {
+		lang, ok := executor.LookupLanguage(tmpl.Language)
+		if !ok {
+			return set, fmt.Errorf("template %q: unsupported language %q", name, tmpl.Language)
+		}
+		set.templates[name] = macroTemplate{Language: lang.Name, Template: tmpl.Template}
+	}
+	return set, nil
+}
+
+// isReservedAlias reports whether an alias would hide a built-in prefix command
+func isReservedAlias(name string) bool {
+	return name == runCommandName || name == snippetCommandName
+}
+
+// load adds the aliases and templates saved in db
+func (m *macros) load(db *store.Store) error {
+	saved, err := db.Macros()
+	if err != nil {
+		return err
+	}
+	for _, sm := range saved {
+		m.add(sm)
+	}
+	return nil
+}
+
+// add adds or replaces an alias or template of a guild
+func (m *macros) add(sm *store.Macro) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, ok := m.added[sm.GuildID]
+	if !ok {
+		set = newMacroSet()
+		m.added[sm.GuildID] = set
+	}
+	switch sm.Kind {
+	case store.MacroAlias:
+		set.aliases[sm.Name] = sm.Body
+	case store.MacroTemplate:
+		set.templates[sm.Name] = macroTemplate{Language: sm.Language, Template: sm.Body}
+	}
+}
+
+// remove removes an alias or template added to a guild
+func (m *macros) remove(guildID, kind, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, ok := m.added[guildID]
+	if !ok {
+		return
+	}
+	switch kind {
+	case store.MacroAlias:
+		delete(set.aliases, name)
+	case store.MacroTemplate:
+		delete(set.templates, name)
+	}
+}
+
+// isAdded reports whether a guild has added an alias or template
+func (m *macros) isAdded(guildID, kind, name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := m.added[guildID]
+	if kind == store.MacroAlias {
+		_, ok := set.aliases[name]
+		return ok
+	}
+	_, ok := set.templates[name]
+	return ok
+}
+
+// sets returns the sets that apply in a guild, most specific first
+func (m *macros) sets(guildID string) []macroSet {
+	if guildID == "" {
+		return []macroSet{m.global}
+	}
+	return []macroSet{m.added[guildID], m.guilds[guildID], m.global}
+}
+
+// alias returns the command an alias stands for in a guild
+func (m *macros) alias(guildID, name string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, set := range m.sets(guildID) {
+		if command, ok := set.aliases[name]; ok {
+			return command, true
+		}
+	}
+	return "", false
+}
+
+// template returns a template by name in a guild
+func (m *macros) template(guildID, name string) (macroTemplate, bool) {
+	if m == nil {
+		return macroTemplate{}, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, set := range m.sets(guildID) {
+		if tmpl, ok := set.templates[name]; ok {
+			return tmpl, true
+		}
+	}
+	return macroTemplate{}, false
+}
+
+// list returns the aliases and templates that apply in a guild
+func (m *macros) list(guildID string) (map[string]string, map[string]macroTemplate) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	aliases := make(map[string]string)
+	templates := make(map[string]macroTemplate)
+	sets := m.sets(guildID)
+	for _, set := range slices.Backward(sets) {
+		maps.Copy(aliases, set.aliases)
+		maps.Copy(templates, set.templates)
+	}
+	return aliases, templates
+}
+
+// expand replaces the alias a command line starts with, without the
+// prefix, by the command it stands for until it no longer starts with one
+func (m *macros) expand(guildID, line string) (string, error) {
+	return expandAliases(line, func(name string) (string, bool) { return m.alias(guildID, name) })
+}
+
+// checkAlias reports whether an alias in a guild leads to the run command
+// without looping, using override for aliases it redefines
+func (m *macros) checkAlias(guildID, name string, override map[string]string) error {
+	expanded, err := expandAliases(name, func(name string) (string, bool) {
+		if command, ok := override[name]; ok {
+			return command, true
+		}
+		return m.alias(guildID, name)
+	})
+	if err != nil {
+		return err
+	}
+	if command, _ := cutCommandName(expanded); command != runCommandName {
+		return fmt.Errorf("alias `%s` must stand for `%s` or another alias, not `%s`", name, runCommandName, command)
+	}
+	return nil
+}
+
+// expandAliases expands the aliases a command line starts with
+func expandAliases(line string, alias func(name string) (string, bool)) (string, error) {
+	var chain []string
+	for {
+		name, rest := cutCommandName(line)
+		command, ok := alias(name)
+		if !ok {
+			return line, nil
+		}
+		if slices.Contains(chain, name) {
+			return "", fmt.Errorf("%w: %s → %s", errAliasLoop, strings.Join(chain, " → "), name)
+		}
+		chain = append(chain, name)
+		if len(chain) > maxAliasDepth {
+			return "", fmt.Errorf("aliases may stand for at most %d others in turn", maxAliasDepth)
+		}
+		line = command + rest
+	}
+}
+
+// cutCommandName splits a command line into the command name and the rest,
+// which starts at the first space or code fence
+func cutCommandName(line string) (name, rest string) {
+	end := strings.IndexFunc(line, func(r rune) bool { return unicode.IsSpace(r) || r == '`' })
+	if end < 0 {
+		return line, ""
+	}
+	return line[:end], line[end:]
+}
+
+// apply wraps cmd's code in the template it names with --macro, taking the
+// template's language when cmd names none
+func (m *macros) apply(guildID string, cmd *runCommand) error {
+	if cmd.Macro == "" {
+		return nil
+	}
+	tmpl, ok := m.template(guildID, cmd.Macro)
+	if !ok {
+		return fmt.Errorf("unknown macro `%s`; see `/macro list`", cmd.Macro)
+	}
+	if cmd.Language == "" {
+		cmd.Language = tmpl.Language
+	}
+	if lang, ok := executor.LookupLanguage(cmd.Language); ok && lang.Name != tmpl.Language {
+		return fmt.Errorf("macro `%s` wraps %s code, not %s", cmd.Macro, tmpl.Language, lang.Name)
+	}
+	if cmd.Code == "" {
+		return fmt.Errorf("macro `%s` wraps code in ``` fences, not attachments", cmd.Macro)
+	}
+	cmd.Code = strings.Replace(tmpl.Template, macroCodePlaceholder, cmd.Code, 1)
+	return nil
+}
+
+// parseMessage parses a prefix run command in a guild, expanding aliases
+// first and wrapping the code in the macro template it names
+func (b *Bot) parseMessage(guildID, content string) (*runCommand, error) {
+	content = strings.TrimSpace(content)
+	if line, ok := strings.CutPrefix(content, b.cfg.Prefix); ok {
+		expanded, err := b.macros.expand(guildID, line)
+		if err != nil {
+			return nil, err
+		}
+		content = b.cfg.Prefix + expanded
+	}
+
+	cmd, err := parseRunCommand(b.cfg.Prefix, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.macros.apply(guildID, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// macroCommands returns the /alias and /macro application commands
+func macroCommands() []*discordgo.ApplicationCommand {
+	name := func(description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        optionName,
+			Description: description,
+			Required:    true,
+		}
+	}
+	list := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        macroListCommand,
+		Description: "List what this server can use",
+	}
+
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:        aliasCommandName,
+			Description: "Short prefix commands standing for longer ones, like !py for !run python",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        macroSetCommand,
+					Description: "Add or replace an alias in this server",
+					Options: []*discordgo.ApplicationCommandOption{
+						name("Alias typed after the prefix, like py"),
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        optionCommand,
+							Description: "Command it stands for, like run python --macro=aoc",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        macroRemoveCommand,
+					Description: "Remove an alias added to this server",
+					Options:     []*discordgo.ApplicationCommandOption{name("Alias to remove")},
+				},
+				list,
+			},
+		},
+		{
+			Name:        macroCommandName,
+			Description: "Templates wrapping code run with --macro=<name>",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        macroSetCommand,
+					Description: "Add or replace a template in this server",
+					Options: []*discordgo.ApplicationCommandOption{
+						name("Template name used with --macro"),
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        optionLanguage,
+							Description: "Language of the template",
+							Required:    true,
+							Choices:     stringChoices(executor.LanguageNames()),
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        optionTemplate,
+							Description: "Single-line template with " + macroCodePlaceholder + " where the code goes",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionAttachment,
+							Name:        optionFile,
+							Description: "File holding the template, instead of the template option",
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        macroRemoveCommand,
+					Description: "Remove a template added to this server",
+					Options:     []*discordgo.ApplicationCommandOption{name("Template to remove")},
+				},
+				list,
+			},
+		},
+	}
+}
+
+// onMacroCommand handles the /alias and /macro subcommands
+func (b *Bot) onMacroCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	kind := store.MacroAlias
+	if data.Name == macroCommandName {
+		kind = store.MacroTemplate
+	}
+	who := interactionMember(i)
+	if who.GuildID == "" {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errMacrosInDM.Error()+".")
+		return
+	}
+
+	sub := data.Options[0]
+	if sub.Name == macroListCommand {
+		b.onMacroList(s, i, who, kind)
+		return
+	}
+	if b.store == nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errMacrosDisabled.Error()+".")
+		return
+	}
+
+	// Checking roles and downloading a template can exceed Discord's three
+	// second limit, so the response is deferred
+	deferred, err := b.deferEphemeralReply(s, i)
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to acknowledge interaction")
+		return
+	}
+	if !b.isAdmin(s, who, i.ChannelID, adminMacros) {
+		deferred.reply(s, "🚫 Only admins may change this server's aliases and macros.")
+		return
+	}
+
+	sm := &store.Macro{GuildID: who.GuildID, Kind: kind, AuthorID: who.UserID, CreatedAt: time.Now().UTC()}
+	var file string
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case optionName:
+			sm.Name = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		case optionCommand, optionTemplate:
+			sm.Body = opt.StringValue()
+		case optionLanguage:
+			sm.Language = opt.StringValue()
+		case optionFile:
+			file, _ = opt.Value.(string)
+		}
+	}
+
+	var reply string
+	switch sub.Name {
+	case macroSetCommand:
+		if file != "" {
+			sm.Body, err = b.downloadTemplate(i, file)
+		}
+		if err == nil {
+			reply, err = b.setMacro(sm)
+		}
+	case macroRemoveCommand:
+		reply, err = b.removeMacro(sm)
+	}
+	if err != nil {
+		deferred.reply(s, "❌ "+err.Error())
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"guild_id": sm.GuildID,
+		"user_id":  who.UserID,
+		"kind":     sm.Kind,
+		"name":     sm.Name,
+		"action":   sub.Name,
+	}).Info("Changed guild macros")
+	deferred.reply(s, reply)
+}
+
+// downloadTemplate reads a template uploaded with /macro set
+func (b *Bot) downloadTemplate(i *discordgo.InteractionCreate, file string) (string, error) {
+	var att *discordgo.MessageAttachment
+	if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+		att = resolved.Attachments[file]
+	}
+	if att == nil {
+		return "", errors.New("the template file is missing")
+	}
+	data, err := download(context.Background(), att.URL, b.cfg.MaxUploadBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the template: %w", err)
+	}
+	return string(data), nil
+}
+
+// setMacro checks and saves an alias or template added to a guild
+func (b *Bot) setMacro(sm *store.Macro) (string, error) {
+	if !snippetNamePattern.MatchString(sm.Name) {
+		return "", errInvalidMacro
+	}
+	if limit := b.cfg.Macros.MaxPerGuild; limit > 0 && !b.macros.isAdded(sm.GuildID, sm.Kind, sm.Name) {
+		count, err := b.store.CountMacros(sm.GuildID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to count macros")
+			return "", errors.New("failed to read this server's macros")
+		}
+		if count >= limit {
+			return "", fmt.Errorf("this server has reached its limit of %d aliases and macros", limit)
+		}
+	}
+
+	var reply string
+	switch sm.Kind {
+	case store.MacroAlias:
+		sm.Body = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(sm.Body), b.cfg.Prefix))
+		if isReservedAlias(sm.Name) {
+			return "", fmt.Errorf("`%s` would hide the %s command", sm.Name, sm.Name)
+		}
+		if err := b.macros.checkAlias(sm.GuildID, sm.Name, map[string]string{sm.Name: sm.Body}); err != nil {
+			return "", err
+		}
+		reply = fmt.Sprintf("🔤 `%s%s` now stands for `%s%s`.", b.cfg.Prefix, sm.Name, b.cfg.Prefix, sm.Body)
+	case store.MacroTemplate:
+		lang, ok := executor.LookupLanguage(sm.Language)
+		if !ok {
+			return "", fmt.Errorf("unsupported language `%s`", sm.Language)
+		}
+		sm.Language = lang.Name
+		if !strings.Contains(sm.Body, macroCodePlaceholder) {
+			return "", errMacroNoCode
+		}
+		reply = fmt.Sprintf("🧩 Saved macro `%s` for %s; use it with `%s%s %s --%s=%s`.",
+			sm.Name, lang.Name, b.cfg.Prefix, runCommandName, lang.Name, optionMacro, sm.Name)
+	}
+
+	if err := b.store.SaveMacro(sm); err != nil {
+		logrus.WithError(err).Error("Failed to save macro")
+		return "", errors.New("failed to save")
+	}
+	b.macros.add(sm)
+	return reply, nil
+}
+
+// removeMacro deletes an alias or template added to a guild
+func (b *Bot) removeMacro(sm *store.Macro) (string, error) {
+	err := b.store.DeleteMacro(sm.GuildID, sm.Kind, sm.Name)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", fmt.Errorf("no %s `%s` was added to this server", sm.Kind, sm.Name)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete macro")
+		return "", errors.New("failed to remove")
+	}
+	b.macros.remove(sm.GuildID, sm.Kind, sm.Name)
+
+	reply := fmt.Sprintf("🗑️ Removed %s `%s`.", sm.Kind, sm.Name)
+	if sm.Kind == store.MacroAlias {
+		if _, ok := b.macros.alias(sm.GuildID, sm.Name); ok {
+			reply += " The configured alias of the same name applies again."
+		}
+	} else if _, ok := b.macros.template(sm.GuildID, sm.Name); ok {
+		reply += " The configured template of the same name applies again."
+	}
+	return reply, nil
+}
+
+// onMacroList lists the aliases or templates that apply in the guild
+func (b *Bot) onMacroList(s *discordgo.Session, i *discordgo.InteractionCreate, who member, kind string) {
+	aliases, templates := b.macros.list(who.GuildID)
+
+	var lines []string
+	title, command := "🔤 Aliases", aliasCommandName
+	if kind == store.MacroAlias {
+		for _, name := range slices.Sorted(maps.Keys(aliases)) {
+			lines = append(lines, fmt.Sprintf("`%s%s` → `%s%s`", b.cfg.Prefix, name, b.cfg.Prefix, aliases[name]))
+		}
+	} else {
+		title, command = "🧩 Macros", macroCommandName
+		for _, name := range slices.Sorted(maps.Keys(templates)) {
+			tmpl := templates[name]
+			first, _, _ := strings.Cut(strings.TrimSpace(tmpl.Template), "\n")
+			lines = append(lines, fmt.Sprintf("**%s** (%s) `%s`", name, tmpl.Language, first))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf("None yet; admins can add some with `/%s %s`.", command, macroSetCommand))
+	}
+
+	desc, _ := headOfOutput(strings.Join(lines, "\n"), embedOutputLength)
+	b.respondEphemeralEmbed(s, i.Interaction, &discordgo.MessageEmbed{
+		Title:       title,
+		Description: desc,
+		Color:       colorCanceled,
+	}, nil)
+}
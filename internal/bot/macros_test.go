@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// newMacroBot returns a bot with the given macros and no store
+func newMacroBot(t *testing.T, cfg config.MacrosConfig) *Bot {
+	t.Helper()
+	m, err := newMacros(cfg)
+	if err != nil {
+		t.Fatalf("Failed to compile macros: %v", err)
+	}
+	return &Bot{cfg: config.BotConfig{Prefix: "!"}, macros: m}
+}
+
+func TestParseMessageExpandsAliases(t *testing.T) {
+	b := newMacroBot(t, config.MacrosConfig{
+		Aliases: map[string]string{"py": "run python", "big": "py --tier=large", "harness": "run --macro=aoc"},
+		Templates: map[string]config.MacroTemplate{
+			"aoc": {Language: "py", Template: "import sys\n{{code}}\nprint('done')"},
+		},
+		Guilds: map[string]config.GuildMacros{"g1": {Aliases: map[string]string{"py": "run python3"}}},
+	})
+
+	cmd, err := b.parseMessage("g2", "!big ```print(1)```")
+	if err != nil || cmd.Language != "python" || cmd.Tier != "large" || cmd.Code != "print(1)" {
+		t.Errorf("Expected aliases expanded in turn, got %+v, %v", cmd, err)
+	}
+	if cmd, _ := b.parseMessage("g1", "!py```print(1)```"); cmd == nil || cmd.Language != "python3" {
+		t.Errorf("Expected the guild's alias to win, got %+v", cmd)
+	}
+	if cmd, _ := b.parseMessage("", "!py ```print(1)```"); cmd == nil || cmd.Language != "python" {
+		t.Errorf("Expected global aliases in DMs, got %+v", cmd)
+	}
+
+	cmd, err = b.parseMessage("g2", "!harness\n```\nprint(1)\n```")
+	if err != nil || cmd.Language != "python" || cmd.Code != "import sys\nprint(1)\nprint('done')" {
+		t.Errorf("Expected the code wrapped in the template, got %+v, %v", cmd, err)
+	}
+	if _, err := b.parseMessage("g2", "!run js --macro=aoc ```1```"); err == nil {
+		t.Error("Expected a template of another language to be refused")
+	}
+	if _, err := b.parseMessage("g2", "!run --macro=nope ```1```"); err == nil {
+		t.Error("Expected an unknown macro to be refused")
+	}
+	if _, err := b.parseMessage("g2", "!python ```1```"); !errors.Is(err, errNotRunCommand) {
+		t.Errorf("Expected other commands to be left alone, got %v", err)
+	}
+}
+
+func TestAliasLoops(t *testing.T) {
+	_, err := newMacros(config.MacrosConfig{Aliases: map[string]string{"a": "b --tier=small", "b": "a"}})
+	if !errors.Is(err, errAliasLoop) {
+		t.Errorf("Expected a configured loop to be refused, got %v", err)
+	}
+	if _, err := newMacros(config.MacrosConfig{Aliases: map[string]string{"run": "run python"}}); err == nil {
+		t.Error("Expected an alias hiding run to be refused")
+	}
+	if _, err := newMacros(config.MacrosConfig{Aliases: map[string]string{"h": "help"}}); err == nil {
+		t.Error("Expected an alias for another command to be refused")
+	}
+
+	m, err := newMacros(config.MacrosConfig{Aliases: map[string]string{"py": "run python"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.add(&store.Macro{GuildID: "g1", Kind: store.MacroAlias, Name: "p", Body: "py"})
+	if err := m.checkAlias("g1", "py", map[string]string{"py": "p"}); !errors.Is(err, errAliasLoop) {
+		t.Errorf("Expected redefining py as p to loop, got %v", err)
+	}
+	if err := m.checkAlias("g2", "py", map[string]string{"py": "p"}); err == nil {
+		t.Error("Expected p to be unknown outside g1")
+	}
+
+	// A loop created behind the checks is still caught when expanding
+	m.add(&store.Macro{GuildID: "g1", Kind: store.MacroAlias, Name: "py", Body: "p"})
+	if _, err := m.expand("g1", "p ```1```"); !errors.Is(err, errAliasLoop) {
+		t.Errorf("Expected the loop to be detected, got %v", err)
+	}
+}
+
+func TestSetMacro(t *testing.T) {
+	b := newMacroBot(t, config.MacrosConfig{Aliases: map[string]string{"py": "run python"}})
+	b.store = newSnippetBot(t, config.SnippetsConfig{}).store
+	b.cfg.Macros.MaxPerGuild = 2
+
+	alias := func(name, command string) *store.Macro {
+		return &store.Macro{GuildID: "g1", Kind: store.MacroAlias, Name: name, Body: command}
+	}
+	if _, err := b.setMacro(alias("p", "!py --network=none")); err != nil {
+		t.Fatalf("Expected the alias to be saved, got %v", err)
+	}
+	if command, _ := b.macros.alias("g1", "p"); command != "py --network=none" {
+		t.Errorf("Expected the prefix dropped from the command, got %q", command)
+	}
+	if _, err := b.setMacro(alias("p", "run python")); err != nil {
+		t.Errorf("Expected replacing an alias to ignore the limit, got %v", err)
+	}
+
+	tmpl := &store.Macro{GuildID: "g1", Kind: store.MacroTemplate, Name: "t", Language: "py", Body: "{{code}}"}
+	if _, err := b.setMacro(tmpl); err != nil || tmpl.Language != "python" {
+		t.Fatalf("Expected the template saved with its canonical language, got %v", err)
+	}
+	if _, err := b.setMacro(alias("q", "run python")); err == nil || !strings.Contains(err.Error(), "limit of 2") {
+		t.Errorf("Expected the guild limit to be enforced, got %v", err)
+	}
+
+	b.cfg.Macros.MaxPerGuild = 0
+	for _, sm := range []*store.Macro{
+		alias("Bad Name", "run python"),
+		alias("snippet", "run python"),
+		alias("loop", "loop"),
+		{GuildID: "g1", Kind: store.MacroTemplate, Name: "t2", Language: "py", Body: "print(1)"},
+		{GuildID: "g1", Kind: store.MacroTemplate, Name: "t2", Language: "cobol", Body: "{{code}}"},
+	} {
+		if _, err := b.setMacro(sm); err == nil {
+			t.Errorf("Expected %+v to be refused", sm)
+		}
+	}
+
+	if _, err := b.removeMacro(alias("p", "")); err != nil {
+		t.Errorf("Expected the alias to be removed, got %v", err)
+	}
+	if _, err := b.removeMacro(alias("py", "")); err == nil {
+		t.Error("Expected configured aliases to be out of reach")
+	}
+}
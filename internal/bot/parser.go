@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+)
+
+// runCommandName is the prefix command that triggers an execution
+const runCommandName = "run"
+
+// Parser errors
+var (
+	// errNotRunCommand means the message is not addressed to the run command
+	errNotRunCommand = errors.New("not a run command")
+
+	// errMissingCode means the message contains neither a code block nor attachments
+	errMissingCode = errors.New("no code found; wrap your code in ``` fences or attach source files")
+
+	// errMissingLanguage means neither the command nor the code fence names a language
+	errMissingLanguage = errors.New("no language given; use `!run <language>` or a fenced block like ```python")
+)
+
+// Run command options, given as --name=value on the command line
+const (
+	optionTier    = "tier"
+	optionNetwork = "network"
+	optionMacro   = "macro"
+)
+
+// runCommand is a parsed run request
+type runCommand struct {
+	Language string
+	Code     string
+	Stdin    string
+
+	// Command-line arguments passed to the program
+	Args []string
+
+	// Requested resources and network; empty picks the default the
+	// requester is allowed
+	Tier    executor.Tier
+	Network executor.NetworkMode
+
+	// Files collected from message attachments
+	Files []executor.File
+
+	// Template the code is wrapped in, named with --macro
+	Macro string
+
+	// Policy rule the code matched, if any
+	Policy *policy.Match
+}
+
+// screenedSource returns what the policy screens: the code and any arguments
+func (c *runCommand) screenedSource() string {
+	if len(c.Args) == 0 {
+		return c.Code
+	}
+	return c.Code + "\n" + strings.Join(c.Args, " ")
+}
+
+// parseRunCommand parses messages of the form
+//
+//	!run [language] [--tier=<tier>] [--network=<mode>] [--macro=<name>]
+//	```[language]
+//	code
+//	```
+//	```
+//	optional stdin
+//	```
+//
+// The language on the command line takes precedence over the fence tag.
+// A second code block, if present, is fed to the program as standard input.
+// The code block may be omitted when the source comes from attachments, in
+// which case the command line must name only the language and Code is empty.
+// The language may also be left to the macro, which is applied separately.
+func parseRunCommand(prefix, content string) (*runCommand, error) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, prefix+runCommandName) {
+		return nil, errNotRunCommand
+	}
+	rest := content[len(prefix+runCommandName):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\n' && rest[0] != '\t' {
+		// Something like "!running" is a different command
+		return nil, errNotRunCommand
+	}
+
+	header, body, hasBlock := strings.Cut(rest, "```")
+	cmd := &runCommand{}
+	language, err := parseHeader(header, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasBlock {
+		if language == "" {
+			return nil, errMissingCode
+		}
+		cmd.Language = language
+		return cmd, nil
+	}
+
+	tag, code, remainder, ok := parseCodeBlock("```" + body)
+	if !ok {
+		return nil, errMissingCode
+	}
+	if language == "" {
+		language = tag
+	}
+	if language == "" && cmd.Macro == "" {
+		return nil, errMissingLanguage
+	}
+
+	cmd.Language = language
+	cmd.Code = code
+	if _, stdin, _, ok := parseCodeBlock(remainder); ok {
+		cmd.Stdin = terminateInput(stdin)
+	}
+
+	return cmd, nil
+}
+
+// parseHeader reads the command line after the command name: options are
+// stored in cmd and the language, if named, is returned
+func parseHeader(header string, cmd *runCommand) (string, error) {
+	var language string
+	for _, field := range strings.Fields(header) {
+		if !strings.HasPrefix(field, "--") {
+			if language != "" {
+				return "", errMissingCode
+			}
+			language = field
+			continue
+		}
+
+		name, value, _ := strings.Cut(strings.TrimPrefix(field, "--"), "=")
+		var err error
+		switch name {
+		case optionTier:
+			cmd.Tier, err = executor.ParseTier(value)
+		case optionNetwork:
+			cmd.Network, err = executor.ParseNetworkMode(value)
+		case optionMacro:
+			cmd.Macro = strings.ToLower(value)
+		default:
+			err = fmt.Errorf("unknown option `--%s`; use --%s=<tier>, --%s=<mode> or --%s=<name>",
+				name, optionTier, optionNetwork, optionMacro)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return language, nil
+}
+
+// parseCodeBlock extracts the language tag and body of the first fenced code
+// block in s, along with the text following it. Inline single-line blocks
+// such as ```print(1)``` have no tag.
+func parseCodeBlock(s string) (tag, code, rest string, ok bool) {
+	start := strings.Index(s, "```")
+	if start < 0 {
+		return "", "", "", false
+	}
+	s = s[start+3:]
+
+	end := strings.Index(s, "```")
+	if end < 0 {
+		return "", "", "", false
+	}
+	block := s[:end]
+	rest = s[end+3:]
+
+	firstLine, remainder, multiline := strings.Cut(block, "\n")
+	if multiline && isFenceTag(strings.TrimSpace(firstLine)) {
+		tag = strings.TrimSpace(firstLine)
+		block = remainder
+	}
+
+	return tag, strings.Trim(block, "\n"), rest, true
+}
+
+// terminateInput ensures non-empty input ends with a newline, as
+// line-oriented readers expect the final line to be terminated
+func terminateInput(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// isFenceTag reports whether s looks like a code fence language tag such as
+// "python", "c++" or "c#" rather than the first line of the block itself
+func isFenceTag(s string) bool {
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("+#-_.", r) {
+			return false
+		}
+	}
+	return true
+}
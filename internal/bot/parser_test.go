@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+func TestParseRunCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		language string
+		code     string
+		stdin    string
+		err      error
+	}{
+		{
+			name:     "language on command line",
+			content:  "!run python\n```\nprint(1)\n```",
+			language: "python",
+			code:     "print(1)",
+		},
+		{
+			name:     "language from fence",
+			content:  "!run\n```js\nconsole.log(1)\n```",
+			language: "js",
+			code:     "console.log(1)",
+		},
+		{
+			name:     "command line overrides fence",
+			content:  "!run python3 ```py\nprint(1)\nprint(2)\n```",
+			language: "python3",
+			code:     "print(1)\nprint(2)",
+		},
+		{
+			name:     "inline block",
+			content:  "!run python ```print(1)```",
+			language: "python",
+			code:     "print(1)",
+		},
+		{
+			name:     "second block is stdin",
+			content:  "!run python\n```py\nprint(input())\n```\n```\n5\n1 2 3\n```",
+			language: "python",
+			code:     "print(input())",
+			stdin:    "5\n1 2 3\n",
+		},
+		{
+			name:     "stdin without tag line",
+			content:  "!run python ```py\nprint(input())\n``` ```42\n```",
+			language: "python",
+			code:     "print(input())",
+			stdin:    "42\n",
+		},
+		{
+			name:    "other command",
+			content: "!help",
+			err:     errNotRunCommand,
+		},
+		{
+			name:    "command with shared prefix",
+			content: "!running ```py\nx\n```",
+			err:     errNotRunCommand,
+		},
+		{
+			name:     "language only for attachments",
+			content:  "!run python",
+			language: "python",
+		},
+		{
+			name:    "missing code block",
+			content: "!run python print(1)",
+			err:     errMissingCode,
+		},
+		{
+			name:    "missing language",
+			content: "!run ```\nprint(1)\n```",
+			err:     errMissingLanguage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseRunCommand("!", tt.content)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cmd.Language != tt.language {
+				t.Errorf("Expected language '%s', got '%s'", tt.language, cmd.Language)
+			}
+			if cmd.Code != tt.code {
+				t.Errorf("Expected code '%s', got '%s'", tt.code, cmd.Code)
+			}
+			if cmd.Stdin != tt.stdin {
+				t.Errorf("Expected stdin '%s', got '%s'", tt.stdin, cmd.Stdin)
+			}
+		})
+	}
+}
+
+func TestParseRunCommandOptions(t *testing.T) {
+	cmd, err := parseRunCommand("!", "!run python --tier=large --network=internet\n```\nprint(1)\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Language != "python" || cmd.Code != "print(1)" {
+		t.Errorf("Expected python code 'print(1)', got %s code '%s'", cmd.Language, cmd.Code)
+	}
+	if cmd.Tier != executor.TierLarge {
+		t.Errorf("Expected large tier, got '%s'", cmd.Tier)
+	}
+	if cmd.Network != executor.NetworkInternet {
+		t.Errorf("Expected internet network, got '%s'", cmd.Network)
+	}
+
+	cmd, err = parseRunCommand("!", "!run --tier=small ```js\nconsole.log(1)\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Language != "js" || cmd.Tier != executor.TierSmall || cmd.Network != "" {
+		t.Errorf("Expected js at small tier without network, got %+v", cmd)
+	}
+
+	for _, content := range []string{
+		"!run python --tier=huge ```print(1)```",
+		"!run python --network=host ```print(1)```",
+		"!run python --memory=1g ```print(1)```",
+	} {
+		if _, err := parseRunCommand("!", content); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
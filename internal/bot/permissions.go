@@ -0,0 +1,377 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Admin commands that may be granted for other users' runs and snippets
+const (
+	adminCancel    = "cancel"
+	adminDelete    = "delete"
+	adminSnippets  = "snippets"
+	adminSchedules = "schedules"
+	adminMacros    = "macros"
+
+	// Grants every admin command, and every language in language lists
+	grantAll = "*"
+)
+
+// errNoRunPermission means a user may not run anything at all
+var errNoRunPermission = errors.New("you are not allowed to run code here")
+
+// member identifies the subject of a permission check
+type member struct {
+	GuildID string
+	UserID  string
+	Roles   []string
+}
+
+// grant is the combined permission of a member
+type grant struct {
+	allLanguages bool
+	languages    map[string]bool
+
+	// Rank of the highest tier allowed, -1 for none
+	maxTier int
+
+	networks map[executor.NetworkMode]bool
+
+	allAdmin bool
+	admin    map[string]bool
+}
+
+// newGrant converts a configured grant, resolving language aliases
+func newGrant(cfg config.PermissionGrant) (grant, error) {
+	g := grant{
+		languages: make(map[string]bool),
+		maxTier:   -1,
+		networks:  make(map[executor.NetworkMode]bool),
+		admin:     make(map[string]bool),
+	}
+
+	for _, name := range cfg.Languages {
+		if name == grantAll {
+			g.allLanguages = true
+			continue
+		}
+		lang, ok := executor.LookupLanguage(name)
+		if !ok {
+			return grant{}, fmt.Errorf("unsupported language %q", name)
+		}
+		g.languages[lang.Name] = true
+	}
+
+	if cfg.MaxTier != "" {
+		tier, err := executor.ParseTier(cfg.MaxTier)
+		if err != nil {
+			return grant{}, err
+		}
+		g.maxTier = tier.Rank()
+	}
+
+	for _, name := range cfg.Network {
+		mode, err := executor.ParseNetworkMode(name)
+		if err != nil {
+			return grant{}, err
+		}
+		g.networks[mode] = true
+	}
+
+	for _, command := range cfg.Admin {
+		command = strings.ToLower(command)
+		if command == grantAll {
+			g.allAdmin = true
+			continue
+		}
+		g.admin[command] = true
+	}
+
+	return g, nil
+}
+
+// merge adds the permissions of o to g
+func (g *grant) merge(o grant) {
+	g.allLanguages = g.allLanguages || o.allLanguages
+	for name := range o.languages {
+		g.languages[name] = true
+	}
+	g.maxTier = max(g.maxTier, o.maxTier)
+	for mode := range o.networks {
+		g.networks[mode] = true
+	}
+	g.allAdmin = g.allAdmin || o.allAdmin
+	for command := range o.admin {
+		g.admin[command] = true
+	}
+}
+
+// authorize checks that cmd may run under the grant, returning the reason
+// if not. A missing tier or network mode defaults to the most the grant
+// allows up to standard and isolated.
+func (g *grant) authorize(cmd *runCommand) error {
+	if (!g.allLanguages && len(g.languages) == 0) || g.maxTier < 0 || len(g.networks) == 0 {
+		return errNoRunPermission
+	}
+
+	lang, ok := executor.LookupLanguage(cmd.Language)
+	if !ok {
+		return fmt.Errorf("unsupported language `%s`", cmd.Language)
+	}
+	if !g.allLanguages && !g.languages[lang.Name] {
+		return fmt.Errorf("you are not allowed to run %s here; allowed: %s", lang.Name, g.languageNames())
+	}
+
+	if cmd.Tier == "" {
+		cmd.Tier = executor.Tier(executor.TierNames()[min(g.maxTier, executor.TierStandard.Rank())])
+	}
+	if cmd.Tier.Rank() > g.maxTier {
+		return fmt.Errorf("the %s tier exceeds your limit of %s", cmd.Tier, executor.TierNames()[g.maxTier])
+	}
+
+	if cmd.Network == "" {
+		cmd.Network = executor.NetworkIsolated
+		if !g.networks[executor.NetworkIsolated] && g.networks[executor.NetworkNone] {
+			cmd.Network = executor.NetworkNone
+		}
+	}
+	if !g.networks[cmd.Network] {
+		return fmt.Errorf("network mode %s is not allowed for you; allowed: %s", cmd.Network, g.networkNames())
+	}
+
+	return nil
+}
+
+// isAdmin reports whether the grant includes an admin command
+func (g *grant) isAdmin(command string) bool {
+	return g.allAdmin || g.admin[command]
+}
+
+// languageNames lists the allowed languages for deny reasons
+func (g *grant) languageNames() string {
+	names := make([]string, 0, len(g.languages))
+	for name := range g.languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// networkNames lists the allowed network modes for deny reasons
+func (g *grant) networkNames() string {
+	var names []string
+	for _, name := range executor.NetworkModeNames() {
+		if g.networks[executor.NetworkMode(name)] {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// permissionRule is a grant for a set of roles and users
+type permissionRule struct {
+	roles map[string]bool
+	users map[string]bool
+	grant grant
+}
+
+// matches reports whether the rule applies to m
+func (r *permissionRule) matches(m member) bool {
+	if r.users[m.UserID] {
+		return true
+	}
+	for _, role := range m.Roles {
+		if r.roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionSet is the default grant and rules in effect in one scope
+type permissionSet struct {
+	defaults grant
+	rules    []permissionRule
+}
+
+// permissions evaluates the configured permissions for each request
+type permissions struct {
+	global permissionSet
+
+	// Guild overrides, with the global rules already included
+	guilds map[string]permissionSet
+}
+
+// newPermissions compiles the permissions configuration
+func newPermissions(cfg config.PermissionsConfig) (*permissions, error) {
+	defaults, err := newGrant(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	rules, err := newPermissionRules("rules", cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &permissions{
+		global: permissionSet{defaults: defaults, rules: rules},
+		guilds: make(map[string]permissionSet),
+	}
+	for guildID, guild := range cfg.Guilds {
+		set := permissionSet{defaults: defaults}
+		if guild.Lockdown {
+			set.defaults, _ = newGrant(config.PermissionGrant{})
+		}
+		if guild.Default != nil {
+			if set.defaults, err = newGrant(*guild.Default); err != nil {
+				return nil, fmt.Errorf("guild %s default: %w", guildID, err)
+			}
+		}
+		guildRules, err := newPermissionRules("guild "+guildID+" rules", guild.Rules)
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(append(set.rules, rules...), guildRules...)
+		p.guilds[guildID] = set
+	}
+
+	return p, nil
+}
+
+// newPermissionRules compiles a list of configured rules
+func newPermissionRules(name string, rules []config.PermissionRule) ([]permissionRule, error) {
+	compiled := make([]permissionRule, 0, len(rules))
+	for i, rule := range rules {
+		g, err := newGrant(rule.PermissionGrant)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", name, i, err)
+		}
+		r := permissionRule{roles: make(map[string]bool), users: make(map[string]bool), grant: g}
+		for _, role := range rule.Roles {
+			r.roles[role] = true
+		}
+		for _, user := range rule.Users {
+			r.users[user] = true
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// resolve combines the grants applying to m
+func (p *permissions) resolve(m member) grant {
+	set, ok := p.guilds[m.GuildID]
+	if !ok {
+		set = p.global
+	}
+
+	g, _ := newGrant(config.PermissionGrant{})
+	g.merge(set.defaults)
+	for i := range set.rules {
+		if set.rules[i].matches(m) {
+			g.merge(set.rules[i].grant)
+		}
+	}
+	return g
+}
+
+// checkRun returns an error reply when cmd cannot run for m in channelID:
+// the bot is shutting down, the language is unsupported, m lacks permission,
+// a policy rule blocks the code, or a rate limit or quota is exhausted. It
+// fills in the tier and network mode when cmd leaves them out.
+func (b *Bot) checkRun(m member, channelID string, cmd *runCommand) *discordgo.MessageSend {
+	if b.closing.Load() {
+		return &discordgo.MessageSend{Content: "🛑 " + errShuttingDown.Error()}
+	}
+	if msg := checkLanguage(cmd.Language); msg != nil {
+		return msg
+	}
+
+	g := b.permissions.resolve(m)
+	if err := g.authorize(cmd); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"guild_id": m.GuildID,
+			"user_id":  m.UserID,
+			"language": cmd.Language,
+			"tier":     cmd.Tier,
+			"network":  cmd.Network,
+		}).WithError(err).Info("Run denied")
+		return &discordgo.MessageSend{Content: "🚫 Permission denied: " + err.Error()}
+	}
+
+	match, msg := b.screen(m, channelID, cmd.Language, cmd.screenedSource())
+	cmd.Policy = match
+	if msg != nil {
+		return msg
+	}
+
+	if err := b.limits.admit(m, channelID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"guild_id":   m.GuildID,
+			"channel_id": channelID,
+			"user_id":    m.UserID,
+		}).WithError(err).Info("Run throttled")
+		return &discordgo.MessageSend{Content: "⏳ Slow down: " + err.Error()}
+	}
+	return nil
+}
+
+// isAdmin reports whether m may use an admin command on other users' runs
+// in the channel: moderators always may, others only when granted
+func (b *Bot) isAdmin(s *discordgo.Session, m member, channelID, command string) bool {
+	if isModerator(s, m.UserID, channelID) {
+		return true
+	}
+	g := b.permissions.resolve(m)
+	return g.isAdmin(command)
+}
+
+// messageMember returns the author of a message as a permission subject,
+// looking up their roles when the message does not carry them
+func messageMember(s *discordgo.Session, m *discordgo.Message) member {
+	var roles []string
+	if m.Member != nil {
+		roles = m.Member.Roles
+	}
+	return lookupMember(s, m.GuildID, m.Author.ID, roles)
+}
+
+// interactionMember returns the invoker of an interaction as a permission subject
+func interactionMember(i *discordgo.InteractionCreate) member {
+	m := member{GuildID: i.GuildID, UserID: interactionUser(i).ID}
+	if i.Member != nil {
+		m.Roles = i.Member.Roles
+	}
+	return m
+}
+
+// lookupMember builds a permission subject, fetching the user's roles from
+// the state cache or the API when they are not known
+func lookupMember(s *discordgo.Session, guildID, userID string, roles []string) member {
+	m := member{GuildID: guildID, UserID: userID, Roles: roles}
+	if roles != nil || guildID == "" || s == nil {
+		return m
+	}
+
+	if s.State != nil {
+		if cached, err := s.State.Member(guildID, userID); err == nil {
+			m.Roles = cached.Roles
+			return m
+		}
+	}
+	fetched, err := s.GuildMember(guildID, userID)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Warn("Failed to look up member roles")
+		return m
+	}
+	m.Roles = fetched.Roles
+	return m
+}
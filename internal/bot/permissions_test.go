@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+func testPermissions(t *testing.T) *permissions {
+	t.Helper()
+	p, err := newPermissions(config.PermissionsConfig{
+		Default: config.PermissionGrant{
+			Languages: []string{"py", "javascript"},
+			MaxTier:   "standard",
+			Network:   []string{"none", "isolated"},
+		},
+		Rules: []config.PermissionRule{
+			{
+				Roles:           []string{"trusted"},
+				PermissionGrant: config.PermissionGrant{Languages: []string{"*"}, MaxTier: "large"},
+			},
+			{
+				Users:           []string{"owner"},
+				PermissionGrant: config.PermissionGrant{Network: []string{"internet"}, Admin: []string{"*"}},
+			},
+		},
+		Guilds: map[string]config.GuildPermissions{
+			"public": {
+				Lockdown: true,
+				Rules: []config.PermissionRule{{
+					Roles: []string{"helper"},
+					PermissionGrant: config.PermissionGrant{
+						Languages: []string{"python"}, MaxTier: "small", Network: []string{"none"},
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to compile permissions: %v", err)
+	}
+	return p
+}
+
+func TestPermissionsAuthorize(t *testing.T) {
+	p := testPermissions(t)
+
+	tests := []struct {
+		name    string
+		member  member
+		cmd     runCommand
+		tier    executor.Tier
+		network executor.NetworkMode
+		denied  string
+	}{
+		{
+			name:    "default grant with defaults filled in",
+			member:  member{GuildID: "home", UserID: "user"},
+			cmd:     runCommand{Language: "python"},
+			tier:    executor.TierStandard,
+			network: executor.NetworkIsolated,
+		},
+		{
+			name:   "language outside default",
+			member: member{GuildID: "home", UserID: "user"},
+			cmd:    runCommand{Language: "go"},
+			denied: "you are not allowed to run go here; allowed: javascript, python",
+		},
+		{
+			name:   "tier above default",
+			member: member{GuildID: "home", UserID: "user"},
+			cmd:    runCommand{Language: "python", Tier: executor.TierLarge},
+			denied: "the large tier exceeds your limit of standard",
+		},
+		{
+			name:   "network outside default",
+			member: member{GuildID: "home", UserID: "user"},
+			cmd:    runCommand{Language: "python", Network: executor.NetworkInternet},
+			denied: "network mode internet is not allowed for you; allowed: none, isolated",
+		},
+		{
+			name:    "role grant",
+			member:  member{GuildID: "home", UserID: "user", Roles: []string{"trusted"}},
+			cmd:     runCommand{Language: "go", Tier: executor.TierLarge},
+			tier:    executor.TierLarge,
+			network: executor.NetworkIsolated,
+		},
+		{
+			name:    "user grant",
+			member:  member{GuildID: "home", UserID: "owner"},
+			cmd:     runCommand{Language: "python", Network: executor.NetworkInternet},
+			tier:    executor.TierStandard,
+			network: executor.NetworkInternet,
+		},
+		{
+			name:   "guild lockdown",
+			member: member{GuildID: "public", UserID: "user"},
+			cmd:    runCommand{Language: "python"},
+			denied: errNoRunPermission.Error(),
+		},
+		{
+			name:    "guild rule picks allowed defaults",
+			member:  member{GuildID: "public", UserID: "user", Roles: []string{"helper"}},
+			cmd:     runCommand{Language: "python"},
+			tier:    executor.TierSmall,
+			network: executor.NetworkNone,
+		},
+		{
+			name:   "global rules apply in guilds",
+			member: member{GuildID: "public", UserID: "owner"},
+			cmd:    runCommand{Language: "python"},
+			denied: errNoRunPermission.Error(),
+		},
+		{
+			name:    "direct messages use global permissions",
+			member:  member{UserID: "user", Roles: []string{"trusted"}},
+			cmd:     runCommand{Language: "rust"},
+			tier:    executor.TierStandard,
+			network: executor.NetworkIsolated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := p.resolve(tt.member)
+			cmd := tt.cmd
+			err := g.authorize(&cmd)
+			if tt.denied != "" {
+				if err == nil || err.Error() != tt.denied {
+					t.Fatalf("Expected denial %q, got %v", tt.denied, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected denial: %v", err)
+			}
+			if cmd.Tier != tt.tier {
+				t.Errorf("Expected tier %s, got %s", tt.tier, cmd.Tier)
+			}
+			if cmd.Network != tt.network {
+				t.Errorf("Expected network %s, got %s", tt.network, cmd.Network)
+			}
+		})
+	}
+}
+
+func TestPermissionsAdmin(t *testing.T) {
+	p := testPermissions(t)
+
+	owner := p.resolve(member{GuildID: "home", UserID: "owner"})
+	if !owner.isAdmin(adminCancel) || !owner.isAdmin(adminDelete) {
+		t.Error("Expected wildcard admin grant to allow cancel and delete")
+	}
+	user := p.resolve(member{GuildID: "home", UserID: "user", Roles: []string{"trusted"}})
+	if user.isAdmin(adminCancel) {
+		t.Error("Expected no admin commands without a grant")
+	}
+}
+
+func TestNewPermissionsRejectsUnknownLanguage(t *testing.T) {
+	_, err := newPermissions(config.PermissionsConfig{
+		Rules: []config.PermissionRule{{
+			Users:           []string{"1"},
+			PermissionGrant: config.PermissionGrant{Languages: []string{"cobol"}},
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "cobol") {
+		t.Errorf("Expected unsupported language error, got %v", err)
+	}
+}
+
+func TestEmptyPermissionsDenyEverything(t *testing.T) {
+	p, err := newPermissions(config.PermissionsConfig{})
+	if err != nil {
+		t.Fatalf("Failed to compile permissions: %v", err)
+	}
+	g := p.resolve(member{UserID: "user"})
+	if err := g.authorize(&runCommand{Language: "python"}); !errors.Is(err, errNoRunPermission) {
+		t.Errorf("Expected errNoRunPermission, got %v", err)
+	}
+}
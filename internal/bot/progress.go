@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Live progress settings
+const (
+	// How often the progress message is edited; Discord allows five edits
+	// per channel every five seconds, shared with everything else posted there
+	progressInterval = 2 * time.Second
+
+	// Characters of the output tail shown on the progress message
+	progressTailLength = 1000
+
+	// Bytes of recent output kept for the tail
+	liveOutputKeep = 4 * progressTailLength
+)
+
+// liveOutput keeps the most recent output of a running execution
+type liveOutput struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+// newLiveOutput creates an empty live output buffer
+func newLiveOutput() *liveOutput {
+	return &liveOutput{}
+}
+
+// Write appends p, discarding all but the most recent bytes
+func (o *liveOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf, p...)
+	if over := len(o.buf) - liveOutputKeep; over > 0 {
+		o.buf = append(o.buf[:0], o.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept output, skipping a rune cut by trimming
+func (o *liveOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	start := 0
+	for start < len(o.buf) && start < utf8.UTFMax && !utf8.RuneStart(o.buf[start]) {
+		start++
+	}
+	return string(o.buf[start:])
+}
+
+// progressContent renders the progress message for a running execution
+func progressContent(language, executionID string, elapsed time.Duration, output string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏳ Running **%s**… %s (execution `%s`)", language, elapsed.Truncate(time.Second), executionID)
+
+	if tail := tailOfOutput(output, progressTailLength); tail != "" {
+		fmt.Fprintf(&b, "\n```\n%s\n```", escapeCodeBlock(tail))
+	}
+	return b.String()
+}
+
+// updateProgress periodically calls edit with the progress content showing
+// the elapsed time and the tail of the live output. The returned function
+// stops the updates and waits for any edit in flight, so the final result
+// is never overwritten.
+func updateProgress(exec *execution, language string, edit func(content string) error) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			content := progressContent(language, exec.ID, time.Since(start), exec.output.String())
+			if err := edit(content); err != nil {
+				logrus.WithError(err).WithField("execution_id", exec.ID).Debug("Failed to update progress message")
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveOutputKeepsTail(t *testing.T) {
+	out := newLiveOutput()
+	_, _ = out.Write([]byte(strings.Repeat("a", liveOutputKeep)))
+	_, _ = out.Write([]byte("é" + strings.Repeat("b", liveOutputKeep-1)))
+
+	got := out.String()
+	if strings.Contains(got, "a") {
+		t.Error("Expected old output to be discarded")
+	}
+	if !strings.HasPrefix(got, "b") {
+		t.Errorf("Expected a cut rune to be skipped, got prefix %q", got[:4])
+	}
+}
+
+func TestProgressContent(t *testing.T) {
+	content := progressContent("python", "123", 3500*time.Millisecond, "")
+	if !strings.Contains(content, "3s") || strings.Contains(content, "```") {
+		t.Errorf("Expected elapsed time without output block, got '%s'", content)
+	}
+
+	output := strings.Repeat("line\n", 1000) + "last"
+	content = progressContent("python", "123", time.Second, output)
+	if len(content) > maxMessageLength {
+		t.Errorf("Expected content within %d characters, got %d", maxMessageLength, len(content))
+	}
+	if !strings.Contains(content, "last\n```") {
+		t.Errorf("Expected the tail of the output, got '%s'", content)
+	}
+}
@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Discord message limits
+const (
+	// Maximum characters in a message body
+	maxMessageLength = 2000
+
+	// Characters reserved for the header, fences and notes around inline output
+	messageOverhead = 300
+
+	// Characters of compiler diagnostics shown above successful program output
+	compilerNoteLength = 400
+
+	// Names of the attachments holding full captured output
+	outputAttachmentName  = "output.txt"
+	compileAttachmentName = "compile.txt"
+)
+
+// renderResult builds the reply for a finished execution. The head of the
+// output is shown inline; when it does not fit, or the capture limit was hit,
+// the full captured output is attached as a text file. Compiler diagnostics
+// are shown separately from the program's own output. Files the program
+// wrote to its output directory are attached, with images shown inline.
+func renderResult(res *executor.Result) *discordgo.MessageSend {
+	var b strings.Builder
+	var files []*discordgo.File
+
+	b.WriteString(resultHeader(res))
+	budget := maxMessageLength - messageOverhead
+
+	if res.CompileFailed() {
+		diagnostics := res.Compile.Output()
+		if writeOutputBlock(&b, diagnostics, budget) || res.Compile.Truncated {
+			files = append(files, textFile(compileAttachmentName, diagnostics))
+		}
+		if note := describeTermination(res.Compile); note != "" {
+			b.WriteString("\n" + note)
+		}
+		return finishMessage(&b, files)
+	}
+
+	if res.Compile != nil && res.Compile.Output() != "" {
+		diagnostics := res.Compile.Output()
+		b.WriteString("Compiler output:\n")
+		if writeOutputBlock(&b, diagnostics, compilerNoteLength) {
+			files = append(files, textFile(compileAttachmentName, diagnostics))
+		}
+		b.WriteString("\nProgram output:\n")
+		budget -= compilerNoteLength
+	}
+
+	output := res.Output()
+	if writeOutputBlock(&b, output, budget) || res.Truncated {
+		files = append(files, textFile(outputAttachmentName, output))
+	}
+
+	if note := describeTermination(&res.PhaseResult); note != "" {
+		b.WriteString("\n" + note)
+	}
+	if usage := describeUsage(&res.PhaseResult); usage != "" {
+		b.WriteString("\n" + usage)
+	}
+
+	msg := finishMessage(&b, files)
+	attachArtifacts(msg, &res.PhaseResult)
+	return msg
+}
+
+// attachArtifacts adds output directory files to the message, with an embed
+// rendering each image inline
+func attachArtifacts(msg *discordgo.MessageSend, p *executor.PhaseResult) {
+	for _, a := range p.Artifacts {
+		msg.Files = append(msg.Files, &discordgo.File{
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Reader:      bytes.NewReader(a.Content),
+		})
+		if a.IsImage() {
+			msg.Embeds = append(msg.Embeds, &discordgo.MessageEmbed{
+				Title: a.Name,
+				Image: &discordgo.MessageEmbedImage{URL: "attachment://" + a.Name},
+			})
+		}
+	}
+
+	if len(p.Artifacts) > 0 {
+		appendNote(msg, fmt.Sprintf("🗂️ %d output file(s) attached.", len(p.Artifacts)))
+	}
+	if p.ArtifactsSkipped {
+		appendNote(msg, "⚠️ Some output files exceeded the limits and were left out.")
+	}
+}
+
+// appendNote adds a line to the message content
+func appendNote(msg *discordgo.MessageSend, note string) {
+	if msg.Content != "" {
+		msg.Content += "\n"
+	}
+	msg.Content += note
+}
+
+// describeTermination explains abnormal terminations; normal exits, including
+// non-zero ones already shown in the header, produce no note
+func describeTermination(p *executor.PhaseResult) string {
+	switch p.Reason {
+	case executor.TerminationTimeout:
+		return fmt.Sprintf("⏱️ Timed out after %s and was stopped.", p.Timeout)
+	case executor.TerminationOOMKilled:
+		return fmt.Sprintf("💥 Killed: exceeded %s memory limit.", formatBytes(p.MemoryLimit))
+	case executor.TerminationSignal:
+		return fmt.Sprintf("⚠️ Killed by %s.", executor.SignalName(p.Signal))
+	case executor.TerminationOutputLimit:
+		return "✂️ Output exceeded the capture limit; execution was stopped."
+	case executor.TerminationCanceled:
+		return "🛑 Execution was cancelled."
+	case executor.TerminationWorkspaceLimit:
+		return "📦 Workspace too large: the build output exceeded the size limit."
+	default:
+		return ""
+	}
+}
+
+// describeUsage summarizes CPU time and peak memory when stats were sampled
+func describeUsage(p *executor.PhaseResult) string {
+	if p.CPUTime == 0 && p.PeakMemory == 0 {
+		return ""
+	}
+	return fmt.Sprintf("CPU %s · peak memory %s of %s",
+		p.CPUTime.Round(time.Millisecond), formatBytes(p.PeakMemory), formatBytes(p.MemoryLimit))
+}
+
+// formatBytes renders a byte count in the largest whole binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	switch {
+	case n >= unit*unit*unit:
+		return fmt.Sprintf("%.1f GB", float64(n)/(unit*unit*unit))
+	case n >= unit*unit:
+		return fmt.Sprintf("%d MB", n/(unit*unit))
+	case n >= unit:
+		return fmt.Sprintf("%d KB", n/unit)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// resultHeader summarizes the language, exit status and phase timings
+func resultHeader(res *executor.Result) string {
+	if res.CompileFailed() {
+		return fmt.Sprintf("**%s** · compilation failed (exit code %d) · compile %s\n",
+			res.Language, res.Compile.ExitCode, res.Compile.Duration.Round(time.Millisecond))
+	}
+	if res.Compile != nil {
+		return fmt.Sprintf("**%s** · exit code %d · compile %s · run %s\n",
+			res.Language, res.ExitCode,
+			res.Compile.Duration.Round(time.Millisecond), res.Duration.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("**%s** · exit code %d · %s\n", res.Language, res.ExitCode, res.Duration.Round(time.Millisecond))
+}
+
+// writeOutputBlock writes the head of output as a code block and reports
+// whether anything was cut to fit within limit
+func writeOutputBlock(b *strings.Builder, output string, limit int) bool {
+	head, cut := headOfOutput(output, limit)
+	if head == "" {
+		head = "(no output)"
+	}
+	fmt.Fprintf(b, "```\n%s\n```", escapeCodeBlock(head))
+	return cut
+}
+
+// finishMessage adds the attachment note and files to the message
+func finishMessage(b *strings.Builder, files []*discordgo.File) *discordgo.MessageSend {
+	if len(files) > 0 {
+		b.WriteString("\n📎 Full captured output attached.")
+	}
+	return &discordgo.MessageSend{Content: b.String(), Files: files}
+}
+
+// textFile wraps content as a plain-text attachment
+func textFile(name, content string) *discordgo.File {
+	return &discordgo.File{
+		Name:        name,
+		ContentType: "text/plain; charset=utf-8",
+		Reader:      strings.NewReader(content),
+	}
+}
+
+// headOfOutput returns at most limit characters of s, preferring to cut at a
+// line boundary. cut reports whether anything was dropped.
+func headOfOutput(s string, limit int) (head string, cut bool) {
+	if utf8.RuneCountInString(s) <= limit {
+		return strings.TrimRight(s, "\n"), false
+	}
+
+	end := 0
+	for i := 0; i < limit; i++ {
+		_, size := utf8.DecodeRuneInString(s[end:])
+		end += size
+	}
+	head = s[:end]
+
+	if nl := strings.LastIndexByte(head, '\n'); nl > len(head)/2 {
+		head = head[:nl]
+	}
+
+	return head, true
+}
+
+// tailOfOutput returns at most limit characters from the end of s,
+// preferring to start at a line boundary
+func tailOfOutput(s string, limit int) string {
+	s = strings.TrimRight(s, "\n")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	start := len(s)
+	for i := 0; i < limit; i++ {
+		_, size := utf8.DecodeLastRuneInString(s[:start])
+		start -= size
+	}
+	tail := s[start:]
+
+	if nl := strings.IndexByte(tail, '\n'); nl >= 0 && nl < len(tail)/2 {
+		tail = tail[nl+1:]
+	}
+
+	return tail
+}
+
+// escapeCodeBlock prevents output from closing the surrounding code fence
+func escapeCodeBlock(s string) string {
+	return strings.ReplaceAll(s, "```", "`\u200b``")
+}
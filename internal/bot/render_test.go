@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+func TestRenderResultInline(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language:    "python",
+		PhaseResult: executor.PhaseResult{Stdout: "hello\n", Reason: executor.TerminationSuccess},
+	})
+
+	if !strings.Contains(msg.Content, "hello") {
+		t.Errorf("Expected inline output, got '%s'", msg.Content)
+	}
+	if len(msg.Files) != 0 {
+		t.Errorf("Expected no attachments, got %d", len(msg.Files))
+	}
+}
+
+func TestRenderResultLongOutput(t *testing.T) {
+	output := strings.Repeat("line of output\n", 500)
+	msg := renderResult(&executor.Result{
+		Language:    "python",
+		PhaseResult: executor.PhaseResult{Stdout: output, Reason: executor.TerminationSuccess},
+	})
+
+	if len(msg.Content) > maxMessageLength {
+		t.Errorf("Expected content within %d characters, got %d", maxMessageLength, len(msg.Content))
+	}
+	if len(msg.Files) != 1 {
+		t.Fatalf("Expected one attachment, got %d", len(msg.Files))
+	}
+
+	data, err := io.ReadAll(msg.Files[0].Reader)
+	if err != nil {
+		t.Fatalf("Failed to read attachment: %v", err)
+	}
+	if string(data) != output {
+		t.Error("Expected attachment to contain the full output")
+	}
+}
+
+func TestRenderResultTruncated(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language: "python",
+		PhaseResult: executor.PhaseResult{
+			Stdout:    "x",
+			Reason:    executor.TerminationOutputLimit,
+			Truncated: true,
+		},
+	})
+
+	if !strings.Contains(msg.Content, "capture limit") {
+		t.Errorf("Expected truncation notice, got '%s'", msg.Content)
+	}
+	if len(msg.Files) != 1 {
+		t.Errorf("Expected truncated output to be attached, got %d attachments", len(msg.Files))
+	}
+}
+
+func TestHeadOfOutput(t *testing.T) {
+	head, cut := headOfOutput("ab\ncd\nef", 8)
+	if cut || head != "ab\ncd\nef" {
+		t.Errorf("Expected uncut output, got '%s' (cut=%t)", head, cut)
+	}
+
+	head, cut = headOfOutput("ab\ncd\nefgh", 7)
+	if !cut || head != "ab\ncd" {
+		t.Errorf("Expected cut at line boundary, got '%s' (cut=%t)", head, cut)
+	}
+
+	head, cut = headOfOutput("ééééé", 3)
+	if !cut || head != "ééé" {
+		t.Errorf("Expected cut on rune boundary, got '%s' (cut=%t)", head, cut)
+	}
+}
+
+func TestRenderResultCompileFailure(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language: "go",
+		Compile: &executor.PhaseResult{
+			Stderr:   "./main.go:3:1: syntax error",
+			ExitCode: 1,
+			Reason:   executor.TerminationNonZeroExit,
+		},
+	})
+
+	if !strings.Contains(msg.Content, "compilation failed") {
+		t.Errorf("Expected compilation failure header, got '%s'", msg.Content)
+	}
+	if !strings.Contains(msg.Content, "syntax error") {
+		t.Errorf("Expected compiler diagnostics, got '%s'", msg.Content)
+	}
+}
+
+func TestRenderResultWorkspaceLimit(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language: "c",
+		Compile:  &executor.PhaseResult{Reason: executor.TerminationWorkspaceLimit},
+	})
+
+	if !strings.Contains(msg.Content, "compilation failed") || !strings.Contains(msg.Content, "Workspace too large") {
+		t.Errorf("Expected a workspace size failure, got '%s'", msg.Content)
+	}
+}
+
+func TestRenderResultCompileTimings(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language: "go",
+		PhaseResult: executor.PhaseResult{
+			Stdout:   "ok",
+			Reason:   executor.TerminationSuccess,
+			Duration: 5 * time.Millisecond,
+		},
+		Compile: &executor.PhaseResult{
+			Stderr:   "warning: unused",
+			Reason:   executor.TerminationSuccess,
+			Duration: 1200 * time.Millisecond,
+		},
+	})
+
+	if !strings.Contains(msg.Content, "compile 1.2s · run 5ms") {
+		t.Errorf("Expected phase timings, got '%s'", msg.Content)
+	}
+	if !strings.Contains(msg.Content, "Compiler output") || !strings.Contains(msg.Content, "warning: unused") {
+		t.Errorf("Expected compiler diagnostics section, got '%s'", msg.Content)
+	}
+}
+
+func TestRenderResultTermination(t *testing.T) {
+	tests := []struct {
+		name   string
+		phase  executor.PhaseResult
+		expect string
+	}{
+		{
+			name:   "oom killed",
+			phase:  executor.PhaseResult{Reason: executor.TerminationOOMKilled, ExitCode: 137, MemoryLimit: 128 << 20},
+			expect: "Killed: exceeded 128 MB memory limit",
+		},
+		{
+			name:   "timeout",
+			phase:  executor.PhaseResult{Reason: executor.TerminationTimeout, ExitCode: 137, Timeout: 30 * time.Second},
+			expect: "Timed out after 30s",
+		},
+		{
+			name:   "signal",
+			phase:  executor.PhaseResult{Reason: executor.TerminationSignal, ExitCode: 139, Signal: 11},
+			expect: "Killed by SIGSEGV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := renderResult(&executor.Result{Language: "python", PhaseResult: tt.phase})
+			if !strings.Contains(msg.Content, tt.expect) {
+				t.Errorf("Expected '%s' in reply, got '%s'", tt.expect, msg.Content)
+			}
+		})
+	}
+}
+
+func TestRenderResultArtifacts(t *testing.T) {
+	msg := renderResult(&executor.Result{
+		Language: "python",
+		PhaseResult: executor.PhaseResult{
+			Reason: executor.TerminationSuccess,
+			Artifacts: []executor.Artifact{
+				{Name: "plot.png", ContentType: "image/png", Content: []byte("png")},
+				{Name: "data.csv", ContentType: "text/csv", Content: []byte("a,b\n")},
+			},
+			ArtifactsSkipped: true,
+		},
+	})
+
+	if len(msg.Files) != 2 {
+		t.Fatalf("Expected two attachments, got %d", len(msg.Files))
+	}
+	if len(msg.Embeds) != 1 {
+		t.Fatalf("Expected one image embed, got %d", len(msg.Embeds))
+	}
+	if msg.Embeds[0].Image == nil || msg.Embeds[0].Image.URL != "attachment://plot.png" {
+		t.Errorf("Expected embed to reference the attached image, got %+v", msg.Embeds[0].Image)
+	}
+	if !strings.Contains(msg.Content, "left out") {
+		t.Errorf("Expected note about skipped files, got '%s'", msg.Content)
+	}
+}
+
+func TestTailOfOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		expected string
+	}{
+		{name: "fits", input: "a\nb\n", limit: 10, expected: "a\nb"},
+		{name: "line boundary", input: "first\nsecond\nthird", limit: 14, expected: "second\nthird"},
+		{name: "no boundary", input: "abcdefgh", limit: 3, expected: "fgh"},
+		{name: "multibyte", input: "ééé", limit: 2, expected: "éé"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tailOfOutput(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
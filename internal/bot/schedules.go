@@ -0,0 +1,706 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// Schedule commands and options
+const (
+	scheduleCommandName = "schedule"
+
+	// Subcommands
+	scheduleCreate = "create"
+	scheduleList   = "list"
+	schedulePause  = "pause"
+	scheduleResume = "resume"
+	scheduleDelete = "delete"
+
+	// Options
+	optionWhen       = "when"
+	optionSnippet    = "snippet"
+	optionChannel    = "channel"
+	optionScheduleID = "id"
+
+	// How often due schedules are looked for
+	scheduleTick = 30 * time.Second
+
+	// Upcoming runs checked against the minimum interval
+	scheduleGapChecks = 32
+)
+
+// Schedule errors
+var (
+	errSchedulesInDM     = errors.New("schedules are only available in servers")
+	errNoScheduleCode    = errors.New("give a snippet, or code and its language")
+	errSchedulesDisabled = errors.New("schedules are not enabled on this bot")
+	errScheduleChannel   = errors.New("you can only schedule runs into channels where you can send messages")
+	errCreatorLeft       = errors.New("its creator is no longer a member of this server")
+	errCreatorCannotPost = errors.New("its creator can no longer view and send messages in this channel")
+)
+
+// channelPostPermissions are needed to schedule runs into a channel
+const channelPostPermissions = int64(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+
+// scheduleParser reads standard five-field cron expressions and
+// descriptors such as @daily and @every 6h
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseScheduleSpec parses a cron expression or an interval written as
+// "every 6h", refusing schedules that would run more often than minInterval
+func parseScheduleSpec(spec string, minInterval time.Duration) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, ok := strings.CutPrefix(strings.ToLower(spec), "every "); ok {
+		spec = "@every " + strings.TrimSpace(interval)
+	}
+	schedule, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule `%s`: use a cron expression like `0 9 * * *` "+
+			"or an interval like `every 6h`", spec)
+	}
+
+	prev := schedule.Next(time.Now().UTC())
+	for n := 0; n < scheduleGapChecks; n++ {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if next.Sub(prev) < minInterval {
+			return nil, fmt.Errorf("schedules may run at most once every %s", minInterval)
+		}
+		prev = next
+	}
+	return schedule, nil
+}
+
+// nextScheduledRun returns when a schedule runs next after now
+func nextScheduledRun(spec string, now time.Time) (time.Time, error) {
+	// The minimum interval was checked when the schedule was created
+	schedule, err := parseScheduleSpec(spec, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(now.UTC())
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule `%s` never runs again", spec)
+	}
+	return next, nil
+}
+
+// scheduleRunner tracks schedules whose runs are in progress, so a slow
+// run is not overlapped by the next one
+type scheduleRunner struct {
+	mu      sync.Mutex
+	running map[int64]bool
+}
+
+// newScheduleRunner creates a runner with nothing in progress
+func newScheduleRunner() *scheduleRunner {
+	return &scheduleRunner{running: make(map[int64]bool)}
+}
+
+// claim marks a schedule as running, reporting false if it already is
+func (r *scheduleRunner) claim(id int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running[id] {
+		return false
+	}
+	r.running[id] = true
+	return true
+}
+
+// release marks a schedule's run as finished
+func (r *scheduleRunner) release(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, id)
+}
+
+// scheduleCommand returns the /schedule application command
+func scheduleCommand() *discordgo.ApplicationCommand {
+	id := func(description string) []*discordgo.ApplicationCommandOption {
+		return []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        optionScheduleID,
+			Description: description,
+			Required:    true,
+		}}
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        scheduleCommandName,
+		Description: "Recurring runs posted to a channel",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        scheduleCreate,
+				Description: "Run a snippet or code on a schedule",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionWhen,
+						Description: "Cron expression in UTC, like 0 9 * * 1-5, or an interval, like every 6h",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionSnippet,
+						Description: "Saved snippet to run; yours is used, then this server's shared one",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionCode,
+						Description: "Code to run instead of a snippet",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionLanguage,
+						Description: "Language of the code",
+						Choices:     stringChoices(executor.LanguageNames()),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionArgs,
+						Description: "Command-line arguments, separated by spaces",
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         optionChannel,
+						Description:  "Channel results are posted to; defaults to this one",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        scheduleList,
+				Description: "List this server's schedules",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        schedulePause,
+				Description: "Stop a schedule from running until it is resumed",
+				Options:     id("Schedule number shown by /schedule list"),
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        scheduleResume,
+				Description: "Resume a paused schedule",
+				Options:     id("Schedule number shown by /schedule list"),
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        scheduleDelete,
+				Description: "Delete a schedule",
+				Options:     id("Schedule number shown by /schedule list"),
+			},
+		},
+	}
+}
+
+// onScheduleCommand handles the /schedule subcommands
+func (b *Bot) onScheduleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	if b.store == nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errSchedulesDisabled.Error()+".")
+		return
+	}
+	who := interactionMember(i)
+	if who.GuildID == "" {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errSchedulesInDM.Error()+".")
+		return
+	}
+
+	switch sub := options[0]; sub.Name {
+	case scheduleCreate:
+		b.onScheduleCreate(s, i, who, sub)
+	case scheduleList:
+		b.onScheduleList(s, i, who)
+	case schedulePause, scheduleResume, scheduleDelete:
+		var id int64
+		for _, opt := range sub.Options {
+			if opt.Name == optionScheduleID {
+				id = opt.IntValue()
+			}
+		}
+		b.onScheduleChange(s, i, who, sub.Name, id)
+	}
+}
+
+// onScheduleCreate adds a schedule after checking the guild's cap, the
+// interval and that its creator may run the code
+func (b *Bot) onScheduleCreate(s *discordgo.Session, i *discordgo.InteractionCreate, who member,
+	sub *discordgo.ApplicationCommandInteractionDataOption) {
+	sc := &store.Schedule{GuildID: who.GuildID, ChannelID: i.ChannelID, UserID: who.UserID}
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case optionWhen:
+			sc.Spec = strings.TrimSpace(opt.StringValue())
+		case optionSnippet:
+			sc.Snippet = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		case optionCode:
+			sc.Code = opt.StringValue()
+		case optionLanguage:
+			sc.Language = opt.StringValue()
+		case optionArgs:
+			sc.Args = strings.Fields(opt.StringValue())
+		case optionChannel:
+			sc.ChannelID, _ = opt.Value.(string)
+		}
+	}
+
+	if !canPostIn(s, i, sc.ChannelID) {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errScheduleChannel.Error()+".")
+		return
+	}
+	if err := b.prepareSchedule(who, sc, time.Now()); err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	if err := b.store.AddSchedule(sc); err != nil {
+		logrus.WithError(err).Error("Failed to save schedule")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to save the schedule.")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"schedule_id": sc.ID,
+		"guild_id":    sc.GuildID,
+		"channel_id":  sc.ChannelID,
+		"user_id":     sc.UserID,
+		"spec":        sc.Spec,
+	}).Info("Created schedule")
+	b.respondEphemeral(s, i.Interaction, fmt.Sprintf("⏰ Created schedule #%d: %s at `%s` (UTC) in <#%s>. "+
+		"Next run <t:%d:R>.", sc.ID, describeScheduleTarget(sc), sc.Spec, sc.ChannelID, sc.NextRun.Unix()))
+}
+
+// canPostIn reports whether the invoker may view and send messages in
+// channelID. Discord resolves their permissions in the invoking channel;
+// others are computed from the guild's roles and overwrites.
+func canPostIn(s *discordgo.Session, i *discordgo.InteractionCreate, channelID string) bool {
+	var perms int64
+	switch {
+	case channelID == i.ChannelID && i.Member != nil:
+		perms = i.Member.Permissions
+	case s != nil:
+		var err error
+		perms, err = s.UserChannelPermissions(interactionUser(i).ID, channelID)
+		if err != nil {
+			logrus.WithError(err).WithField("channel_id", channelID).Warn("Failed to read channel permissions")
+			return false
+		}
+	}
+	return perms&channelPostPermissions == channelPostPermissions
+}
+
+// prepareSchedule validates a new schedule and fills in its first run. The
+// code is screened against the target channel now, and again before each run.
+func (b *Bot) prepareSchedule(who member, sc *store.Schedule, now time.Time) error {
+	if limit := b.cfg.Schedules.MaxPerGuild; limit > 0 {
+		count, err := b.store.CountSchedules(who.GuildID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to count schedules")
+			return errors.New("failed to read schedules")
+		}
+		if count >= limit {
+			return fmt.Errorf("this server has reached its limit of %d schedules", limit)
+		}
+	}
+
+	minInterval := time.Duration(b.cfg.Schedules.MinIntervalSeconds) * time.Second
+	schedule, err := parseScheduleSpec(sc.Spec, minInterval)
+	if err != nil {
+		return err
+	}
+	sc.NextRun = schedule.Next(now.UTC())
+	sc.CreatedAt = now.UTC()
+
+	if len(sc.Args) > maxSnippetArgs {
+		return fmt.Errorf("at most %d arguments may be passed", maxSnippetArgs)
+	}
+	cmd := &runCommand{Language: sc.Language, Code: sc.Code, Args: sc.Args}
+	switch {
+	case sc.Snippet != "" && sc.Code != "":
+		return errors.New("give either a snippet or code, not both")
+	case sc.Snippet != "":
+		sn, err := b.findSnippet(who, snippetOptions{Name: sc.Snippet})
+		if err != nil {
+			return err
+		}
+		sc.SnippetShared = sn.Namespace.Scope == store.ScopeGuild
+		sc.Language = sn.Language
+		cmd.Language, cmd.Code = sn.Language, sn.Code
+	case sc.Code == "" || sc.Language == "":
+		return errNoScheduleCode
+	}
+
+	if msg := checkLanguage(cmd.Language); msg != nil {
+		return errors.New(strings.TrimPrefix(msg.Content, "❌ "))
+	}
+	g := b.permissions.resolve(who)
+	if err := g.authorize(cmd); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	if _, msg := b.screen(who, sc.ChannelID, cmd.Language, cmd.screenedSource()); msg != nil {
+		return errors.New(strings.TrimPrefix(msg.Content, "🚫 "))
+	}
+	if sc.Snippet != "" {
+		// Snippets are read when each run starts, so later versions are used
+		sc.Language = ""
+	}
+	return nil
+}
+
+// describeScheduleTarget names what a schedule runs
+func describeScheduleTarget(sc *store.Schedule) string {
+	target := fmt.Sprintf("%s code", sc.Language)
+	if sc.Snippet != "" {
+		target = fmt.Sprintf("snippet `%s`", sc.Snippet)
+		if sc.SnippetShared {
+			target = fmt.Sprintf("shared snippet `%s`", sc.Snippet)
+		}
+	}
+	if len(sc.Args) > 0 {
+		target += fmt.Sprintf(" with `%s`", strings.Join(sc.Args, " "))
+	}
+	return target
+}
+
+// onScheduleList lists the guild's schedules
+func (b *Bot) onScheduleList(s *discordgo.Session, i *discordgo.InteractionCreate, who member) {
+	schedules, err := b.store.Schedules(who.GuildID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list schedules")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to read schedules.")
+		return
+	}
+
+	var lines []string
+	for _, sc := range schedules {
+		line := fmt.Sprintf("**#%d** · `%s` · %s · <#%s> · by <@%s>",
+			sc.ID, sc.Spec, describeScheduleTarget(sc), sc.ChannelID, sc.UserID)
+		if sc.Paused {
+			line += " · ⏸️ paused"
+		} else {
+			line += fmt.Sprintf(" · next <t:%d:R>", sc.NextRun.Unix())
+		}
+		if sc.LastStatus != "" {
+			line += "\n  Last run: " + sc.LastStatus
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No schedules yet; create one with `/schedule create`.")
+	}
+
+	desc, _ := headOfOutput(strings.Join(lines, "\n"), embedOutputLength)
+	footer := fmt.Sprintf("%d schedules", len(schedules))
+	if limit := b.cfg.Schedules.MaxPerGuild; limit > 0 {
+		footer = fmt.Sprintf("%d of %d schedules", len(schedules), limit)
+	}
+	b.respondEphemeralEmbed(s, i.Interaction, &discordgo.MessageEmbed{
+		Title:       "⏰ Schedules",
+		Description: desc,
+		Color:       colorCanceled,
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+	}, nil)
+}
+
+// onScheduleChange pauses, resumes or deletes a schedule on behalf of its
+// creator or an admin
+func (b *Bot) onScheduleChange(s *discordgo.Session, i *discordgo.InteractionCreate, who member, action string,
+	id int64) {
+	sc, err := b.store.Schedule(id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && sc.GuildID != who.GuildID) {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("❌ No schedule #%d in this server.", id))
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read schedule")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to read the schedule.")
+		return
+	}
+	if sc.UserID != who.UserID && !b.isAdmin(s, who, i.ChannelID, adminSchedules) {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("🚫 Schedule #%d belongs to someone else.", id))
+		return
+	}
+
+	var reply string
+	switch action {
+	case schedulePause:
+		sc.Paused = true
+		err = b.store.UpdateSchedule(sc)
+		reply = fmt.Sprintf("⏸️ Paused schedule #%d.", id)
+	case scheduleResume:
+		sc.Paused, sc.Failures = false, 0
+		if sc.NextRun, err = nextScheduledRun(sc.Spec, time.Now()); err == nil {
+			err = b.store.UpdateSchedule(sc)
+		}
+		reply = fmt.Sprintf("▶️ Resumed schedule #%d; next run <t:%d:R>.", id, sc.NextRun.Unix())
+	case scheduleDelete:
+		err = b.store.DeleteSchedule(id)
+		reply = fmt.Sprintf("🗑️ Deleted schedule #%d.", id)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("schedule_id", id).Error("Failed to change schedule")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to change the schedule.")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"schedule_id": id,
+		"user_id":     who.UserID,
+		"action":      action,
+	}).Info("Changed schedule")
+	b.respondEphemeral(s, i.Interaction, reply)
+}
+
+// runSchedules starts due schedules now and then periodically until done
+// is closed
+func (b *Bot) runSchedules(done <-chan struct{}) {
+	if b.store == nil {
+		return
+	}
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	for {
+		b.startDueSchedules(time.Now())
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDueSchedules moves each due schedule to its next run, then runs it
+// in the background unless its previous run is still going. Runs missed
+// while the bot was down are made up once.
+func (b *Bot) startDueSchedules(now time.Time) {
+	due, err := b.store.DueSchedules(now)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read due schedules")
+		return
+	}
+
+	for _, sc := range due {
+		log := logrus.WithField("schedule_id", sc.ID)
+		next, err := nextScheduledRun(sc.Spec, now)
+		if err != nil {
+			log.WithError(err).Warn("Pausing schedule that cannot run again")
+			sc.Paused = true
+		}
+		sc.NextRun = next
+		if err := b.store.UpdateSchedule(sc); err != nil {
+			log.WithError(err).Error("Failed to update schedule")
+			continue
+		}
+		if sc.Paused {
+			continue
+		}
+
+		if !b.scheduled.claim(sc.ID) {
+			log.Warn("Skipping scheduled run; the previous one is still running")
+			continue
+		}
+		go func() {
+			defer b.scheduled.release(sc.ID)
+			b.runScheduled(sc)
+		}()
+	}
+}
+
+// runScheduled runs a schedule as its creator, through the same checks,
+// limits and queue as their own runs, and posts the result. Schedules whose
+// creator can no longer post in their channel are paused instead.
+func (b *Bot) runScheduled(sc *store.Schedule) {
+	who, err := scheduleCreator(b.session, sc)
+	if errors.Is(err, errCreatorLeft) || errors.Is(err, errCreatorCannotPost) {
+		b.pauseScheduled(sc, err.Error())
+		return
+	}
+
+	var msg *discordgo.MessageSend
+	var failure string
+	var cmd *runCommand
+	if err == nil {
+		cmd, err = b.scheduledCommand(who, sc)
+	}
+	switch {
+	case err != nil:
+		msg = &discordgo.MessageSend{Content: "❌ " + err.Error()}
+		failure = msg.Content
+	default:
+		if msg = b.checkRun(who, sc.ChannelID, cmd); msg != nil {
+			failure = msg.Content
+			break
+		}
+		id := fmt.Sprintf("schedule-%d-%d", sc.ID, time.Now().Unix())
+		exec := b.executions.start(id, sc.UserID, sc.ChannelID)
+		defer b.executions.finish(exec)
+		msg = b.run(exec, who, cmd)
+		if exec.Interrupted() {
+			// A restart is not the schedule's failure
+			b.postScheduled(sc, msg, "")
+			return
+		}
+		failure = describeRunFailure(exec.result)
+	}
+
+	b.finishScheduled(sc, msg, failure)
+}
+
+// scheduleCreator looks up the creator of a schedule, confirming they are
+// still a member who can view and send messages in its channel
+func scheduleCreator(s *discordgo.Session, sc *store.Schedule) (member, error) {
+	m, err := s.State.Member(sc.GuildID, sc.UserID)
+	if err != nil {
+		m, err = s.GuildMember(sc.GuildID, sc.UserID)
+	}
+	if err != nil {
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember {
+			return member{}, errCreatorLeft
+		}
+		logrus.WithError(err).WithField("schedule_id", sc.ID).Warn("Failed to look up schedule creator")
+		return member{}, errors.New("failed to look up the schedule's creator")
+	}
+
+	perms, err := s.UserChannelPermissions(sc.UserID, sc.ChannelID)
+	if err != nil {
+		logrus.WithError(err).WithField("schedule_id", sc.ID).Warn("Failed to read channel permissions")
+		return member{}, errors.New("failed to read the creator's channel permissions")
+	}
+	if perms&channelPostPermissions != channelPostPermissions {
+		return member{}, errCreatorCannotPost
+	}
+
+	return member{GuildID: sc.GuildID, UserID: sc.UserID, Roles: m.Roles}, nil
+}
+
+// scheduledCommand builds the command a schedule runs, reading its snippet
+// as it is now
+func (b *Bot) scheduledCommand(who member, sc *store.Schedule) (*runCommand, error) {
+	if sc.Snippet == "" {
+		return &runCommand{Language: sc.Language, Code: sc.Code, Args: sc.Args}, nil
+	}
+	sn, err := b.findSnippet(who, snippetOptions{Name: sc.Snippet, SharedSet: true, Shared: sc.SnippetShared})
+	if err != nil {
+		return nil, err
+	}
+	return &runCommand{Language: sn.Language, Code: sn.Code, Args: sc.Args}, nil
+}
+
+// describeRunFailure explains why a run failed, or returns "" if it succeeded
+func describeRunFailure(res *executor.Result) string {
+	switch {
+	case res == nil:
+		return "❌ The run did not complete"
+	case res.CompileFailed():
+		return "❌ Compilation failed"
+	case res.Failed():
+		return describeStatus(&res.PhaseResult)
+	}
+	return ""
+}
+
+// finishScheduled records the outcome of a run, pausing the schedule after
+// too many failures in a row, and posts the result. Failures mention the
+// schedule's creator.
+func (b *Bot) finishScheduled(sc *store.Schedule, msg *discordgo.MessageSend, failure string) {
+	log := logrus.WithField("schedule_id", sc.ID)
+
+	// The schedule may have been changed or deleted while it ran
+	current, err := b.store.Schedule(sc.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.WithError(err).Error("Failed to read schedule")
+	}
+	if current != nil {
+		current.LastRun = time.Now().UTC()
+		current.LastStatus = "✅ Succeeded"
+		current.Failures = 0
+		if failure != "" {
+			current.LastStatus = failure
+			current.Failures++
+		}
+		maxFailures := b.cfg.Schedules.MaxFailures
+		if maxFailures > 0 && current.Failures >= maxFailures {
+			current.Paused = true
+		}
+		if err := b.store.UpdateSchedule(current); err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.WithError(err).Error("Failed to update schedule")
+		}
+		sc = current
+	}
+
+	var warning string
+	if failure != "" {
+		log.WithFields(logrus.Fields{"failure": failure, "failures": sc.Failures}).Warn("Scheduled run failed")
+		warning = fmt.Sprintf("⚠️ <@%s>, this scheduled run failed: %s", sc.UserID, failure)
+		if sc.Paused {
+			warning += fmt.Sprintf("\nPaused after %d failures in a row; use `/schedule resume %d` once it is fixed.",
+				sc.Failures, sc.ID)
+		}
+	} else {
+		log.Info("Scheduled run finished")
+	}
+	b.postScheduled(sc, msg, warning)
+}
+
+// pauseScheduled pauses a schedule that can no longer run as its creator
+// and posts the reason in place of a result
+func (b *Bot) pauseScheduled(sc *store.Schedule, reason string) {
+	log := logrus.WithFields(logrus.Fields{"schedule_id": sc.ID, "reason": reason})
+
+	// The schedule may have been changed or deleted since it was due
+	current, err := b.store.Schedule(sc.ID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.WithError(err).Error("Failed to read schedule")
+		}
+		return
+	}
+	current.Paused = true
+	current.LastRun = time.Now().UTC()
+	current.LastStatus = "⏸️ Paused: " + reason
+	if err := b.store.UpdateSchedule(current); err != nil {
+		log.WithError(err).Error("Failed to pause schedule")
+		return
+	}
+
+	log.Warn("Paused schedule")
+	b.postScheduled(current, &discordgo.MessageSend{}, fmt.Sprintf(
+		"⏸️ <@%s>, this schedule was paused because %s; use `/schedule resume %d` once that is fixed.",
+		current.UserID, reason, current.ID))
+}
+
+// postScheduled posts the result of a scheduled run, mentioning the
+// schedule's creator only when there is a warning for them
+func (b *Bot) postScheduled(sc *store.Schedule, msg *discordgo.MessageSend, warning string) {
+	note := fmt.Sprintf("⏰ Schedule #%d · %s · `%s`", sc.ID, describeScheduleTarget(sc), sc.Spec)
+	msg.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	if warning != "" {
+		note += "\n" + warning
+		msg.AllowedMentions.Users = []string{sc.UserID}
+	}
+	appendNote(msg, note)
+
+	if _, err := b.session.ChannelMessageSendComplex(sc.ChannelID, msg); err != nil {
+		logrus.WithError(err).WithField("schedule_id", sc.ID).Error("Failed to post scheduled run")
+	}
+}
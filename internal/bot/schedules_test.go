@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// newScheduleBot returns a bot with an empty store and the given limits
+func newScheduleBot(t *testing.T, cfg config.SchedulesConfig) *Bot {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	perms, err := newPermissions(config.PermissionsConfig{
+		Default: config.PermissionGrant{Languages: []string{"*"}, MaxTier: "standard", Network: []string{"none"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := policy.New(config.PolicyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Bot{store: db, permissions: perms, policy: engine, cfg: config.BotConfig{Schedules: cfg}}
+}
+
+func TestParseScheduleSpec(t *testing.T) {
+	tests := []struct {
+		spec      string
+		shouldErr bool
+	}{
+		{"0 9 * * 1-5", false},
+		{"@daily", false},
+		{"every 6h", false},
+		{"Every 1h30m", false},
+		{"*/10 * * * *", false},
+		{"every 1m", true},
+		{"* * * * *", true},
+		{"0,1 9 * * *", true},
+		{"every", true},
+		{"not a schedule", true},
+		{"0 0 9 * * *", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseScheduleSpec(tt.spec, 5*time.Minute)
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("parseScheduleSpec(%q) error = %v, shouldErr %v", tt.spec, err, tt.shouldErr)
+			}
+		})
+	}
+}
+
+func TestNextScheduledRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	next, err := nextScheduledRun("0 9 * * *", now)
+	if err != nil || !next.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("Expected 09:00 UTC, got %v, %v", next, err)
+	}
+	next, err = nextScheduledRun("every 2h", now)
+	if err != nil || !next.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected two hours later, got %v, %v", next, err)
+	}
+}
+
+func TestPrepareSchedule(t *testing.T) {
+	b := newScheduleBot(t, config.SchedulesConfig{MaxPerGuild: 1, MinIntervalSeconds: 300})
+	who := member{GuildID: "g1", UserID: "u1"}
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+
+	sc := &store.Schedule{GuildID: "g1", ChannelID: "c1", UserID: "u1", Spec: "0 9 * * *", Language: "py", Code: "1"}
+	if err := b.prepareSchedule(who, sc, now); err != nil {
+		t.Fatalf("Expected the schedule to be accepted, got %v", err)
+	}
+	if !sc.NextRun.Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("Expected the first run at 09:00, got %v", sc.NextRun)
+	}
+	if err := b.store.AddSchedule(sc); err != nil {
+		t.Fatal(err)
+	}
+	err := b.prepareSchedule(who, &store.Schedule{Spec: "@daily", Language: "py", Code: "1"}, now)
+	if err == nil || !strings.Contains(err.Error(), "limit of 1") {
+		t.Errorf("Expected the guild cap to be enforced, got %v", err)
+	}
+
+	b.cfg.Schedules.MaxPerGuild = 0
+	tests := []struct {
+		name     string
+		schedule store.Schedule
+	}{
+		{"too frequent", store.Schedule{Spec: "every 1m", Language: "py", Code: "1"}},
+		{"no code", store.Schedule{Spec: "@daily"}},
+		{"code and snippet", store.Schedule{Spec: "@daily", Snippet: "diag", Language: "py", Code: "1"}},
+		{"missing snippet", store.Schedule{Spec: "@daily", Snippet: "diag"}},
+		{"unknown language", store.Schedule{Spec: "@daily", Language: "cobol", Code: "1"}},
+		{"blocked code", store.Schedule{Spec: "@daily", Language: "bash", Code: ":(){ :|:& };:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := b.prepareSchedule(who, &tt.schedule, now); err == nil {
+				t.Error("Expected the schedule to be refused")
+			}
+		})
+	}
+}
+
+func TestPrepareScheduleSnippet(t *testing.T) {
+	b := newScheduleBot(t, config.SchedulesConfig{})
+	who := member{GuildID: "g1", UserID: "u1"}
+	sn := &store.Snippet{Namespace: store.GuildNamespace("g1"), Name: "diag", Language: "python", Code: "1"}
+	if err := b.store.SaveSnippet(sn, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := &store.Schedule{Spec: "@hourly", Snippet: "diag"}
+	if err := b.prepareSchedule(who, sc, time.Now()); err != nil {
+		t.Fatalf("Expected the shared snippet to be found, got %v", err)
+	}
+	if !sc.SnippetShared || sc.Language != "" {
+		t.Errorf("Expected a shared snippet read at run time, got %+v", sc)
+	}
+	cmd, err := b.scheduledCommand(who, sc)
+	if err != nil || cmd.Language != "python" || cmd.Code != "1" {
+		t.Errorf("Expected the snippet's code, got %+v, %v", cmd, err)
+	}
+}
+
+func TestCanPostIn(t *testing.T) {
+	interaction := func(perms int64) *discordgo.InteractionCreate {
+		return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			ChannelID: "c1",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "u1"}, Permissions: perms},
+		}}
+	}
+	post := int64(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+
+	if !canPostIn(nil, interaction(post), "c1") {
+		t.Error("Expected a member who can send messages to schedule here")
+	}
+	if canPostIn(nil, interaction(discordgo.PermissionViewChannel), "c1") {
+		t.Error("Expected a member who cannot send messages to be refused")
+	}
+	if canPostIn(nil, interaction(post), "c2") {
+		t.Error("Expected unknown permissions in another channel to be refused")
+	}
+}
+
+func TestScheduleCreator(t *testing.T) {
+	post := int64(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+	state := discordgo.NewState()
+	err := state.GuildAdd(&discordgo.Guild{
+		ID:      "g1",
+		OwnerID: "owner",
+		Roles:   []*discordgo.Role{{ID: "g1", Permissions: post}, {ID: "muted"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*discordgo.Channel{
+		{ID: "open", GuildID: "g1"},
+		{ID: "quiet", GuildID: "g1", PermissionOverwrites: []*discordgo.PermissionOverwrite{
+			{ID: "muted", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionSendMessages},
+		}},
+	} {
+		if err := state.ChannelAdd(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []*discordgo.Member{
+		{GuildID: "g1", User: &discordgo.User{ID: "u1"}},
+		{GuildID: "g1", User: &discordgo.User{ID: "u2"}, Roles: []string{"muted"}},
+	} {
+		if err := state.MemberAdd(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &discordgo.Session{State: state}
+
+	tests := []struct {
+		name     string
+		userID   string
+		channel  string
+		expected error
+	}{
+		{name: "can post", userID: "u1", channel: "quiet", expected: nil},
+		{name: "muted elsewhere", userID: "u2", channel: "open", expected: nil},
+		{name: "muted here", userID: "u2", channel: "quiet", expected: errCreatorCannotPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &store.Schedule{ID: 1, GuildID: "g1", UserID: tt.userID, ChannelID: tt.channel}
+			who, err := scheduleCreator(s, sc)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, err)
+			}
+			if err == nil && (who.UserID != tt.userID || who.GuildID != "g1") {
+				t.Errorf("Expected the creator as a member, got %+v", who)
+			}
+		})
+	}
+}
+
+func TestDescribeRunFailure(t *testing.T) {
+	ok := executor.PhaseResult{Reason: executor.TerminationSuccess}
+	if got := describeRunFailure(&executor.Result{PhaseResult: ok}); got != "" {
+		t.Errorf("Expected a clean exit to succeed, got %q", got)
+	}
+	if got := describeRunFailure(nil); got == "" {
+		t.Error("Expected a missing result to fail")
+	}
+	res := &executor.Result{PhaseResult: executor.PhaseResult{Reason: executor.TerminationNonZeroExit, ExitCode: 1}}
+	if got := describeRunFailure(res); !strings.Contains(got, "Exit code 1") {
+		t.Errorf("Expected a non-zero exit to fail, got %q", got)
+	}
+	res = &executor.Result{PhaseResult: ok, Compile: &executor.PhaseResult{Reason: executor.TerminationNonZeroExit}}
+	if got := describeRunFailure(res); got == "" {
+		t.Error("Expected a failed compilation to fail")
+	}
+}
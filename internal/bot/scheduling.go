@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/scheduler"
+)
+
+// newScheduler creates the scheduler sharing the execution slots
+func newScheduler(cfg config.BotConfig, now func() time.Time) *scheduler.Scheduler {
+	return scheduler.New(scheduler.Config{
+		Slots:         cfg.MaxConcurrentCommands,
+		MaxPerUser:    cfg.Scheduler.MaxPerUser,
+		MaxPerGuild:   cfg.Scheduler.MaxPerGuild,
+		StarvationAge: time.Duration(cfg.Scheduler.StarvationSeconds) * time.Second,
+	}, now)
+}
+
+// priorities weights members' shares of their guild's slots by role
+type priorities map[string]float64
+
+// newPriorities indexes the configured weights by role, keeping the largest
+// when a role is listed more than once
+func newPriorities(cfg []config.PriorityRole) priorities {
+	p := make(priorities)
+	for _, priority := range cfg {
+		for _, role := range priority.Roles {
+			p[role] = max(p[role], priority.Weight)
+		}
+	}
+	return p
+}
+
+// weight returns the largest weight among m's roles, or 1 without any
+func (p priorities) weight(m member) float64 {
+	weight := 1.0
+	for _, role := range m.Roles {
+		weight = max(weight, p[role])
+	}
+	return weight
+}
+
+// slotRequest asks the scheduler for a slot on behalf of m
+func (b *Bot) slotRequest(m member) scheduler.Request {
+	return scheduler.Request{UserID: m.UserID, GuildID: m.GuildID, Weight: b.priorities.weight(m)}
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+func TestPrioritiesWeight(t *testing.T) {
+	p := newPriorities([]config.PriorityRole{
+		{Roles: []string{"supporter"}, Weight: 2},
+		{Roles: []string{"staff", "supporter"}, Weight: 4},
+	})
+
+	tests := []struct {
+		name   string
+		roles  []string
+		weight float64
+	}{
+		{name: "no roles", weight: 1},
+		{name: "unweighted role", roles: []string{"member"}, weight: 1},
+		{name: "largest listing of a role", roles: []string{"supporter"}, weight: 4},
+		{name: "largest of several roles", roles: []string{"member", "staff"}, weight: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if weight := p.weight(member{UserID: "u1", Roles: tt.roles}); weight != tt.weight {
+				t.Errorf("Expected weight %g, got %g", tt.weight, weight)
+			}
+		})
+	}
+}
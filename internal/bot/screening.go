@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"expvar"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+)
+
+// policyMatches counts code blocked or flagged by policy rules, by rule
+var policyMatches = expvar.NewMap("policy_matches")
+
+// screen checks code m submitted in channelID against the policy. It
+// returns the match, if any, and an error reply when the code is blocked.
+// Every match is logged with its rule, and blocks are audited.
+func (b *Bot) screen(m member, channelID, language, source string) (*policy.Match, *discordgo.MessageSend) {
+	match := b.policy.Check(language, source)
+	if match == nil {
+		return nil, nil
+	}
+	policyMatches.Add(match.Rule, 1)
+
+	log := logrus.WithFields(logrus.Fields{
+		"guild_id":    m.GuildID,
+		"channel_id":  channelID,
+		"user_id":     m.UserID,
+		"language":    language,
+		"policy_rule": match.Rule,
+		"action":      match.Action,
+		"excerpt":     match.Excerpt,
+	})
+	if match.Action != policy.ActionBlock {
+		log.Warn("Code flagged by policy")
+		return match, nil
+	}
+	log.Warn("Code blocked by policy")
+	b.recordBlocked(m, channelID, language, source, match)
+	return match, &discordgo.MessageSend{Content: fmt.Sprintf("🚫 Blocked by policy rule `%s`.", match.Rule)}
+}
+
+// screenFiles checks uploaded files like inline code, stopping at the
+// first block. The first match is kept on cmd for the record.
+func (b *Bot) screenFiles(m member, channelID string, cmd *runCommand) *discordgo.MessageSend {
+	for _, f := range cmd.Files {
+		match, msg := b.screen(m, channelID, cmd.Language, string(f.Content))
+		if match != nil && (cmd.Policy == nil || msg != nil) {
+			cmd.Policy = match
+		}
+		if msg != nil {
+			return msg
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/policy"
+)
+
+// policyMatchCount reads the policy match metric for a rule
+func policyMatchCount(rule string) int64 {
+	if v, ok := policyMatches.Get(rule).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func newScreeningBot(t *testing.T) *Bot {
+	t.Helper()
+	engine, err := policy.New(config.PolicyConfig{Rules: []config.PolicyRule{
+		{Name: "sockets", Pattern: `import socket`, Action: "flag"},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	return &Bot{policy: engine}
+}
+
+func TestScreen(t *testing.T) {
+	b := newScreeningBot(t)
+	m := member{GuildID: "g1", UserID: "u1"}
+
+	before := policyMatchCount("fork-bomb")
+	match, msg := b.screen(m, "c1", "bash", ":(){ :|:& };:")
+	if msg == nil || !strings.Contains(msg.Content, "Blocked by policy rule `fork-bomb`") {
+		t.Fatalf("Expected a block reply, got %+v", msg)
+	}
+	if match == nil || match.Rule != "fork-bomb" {
+		t.Errorf("Expected the fork bomb rule, got %+v", match)
+	}
+	if policyMatchCount("fork-bomb") != before+1 {
+		t.Error("Expected the policy match metric to increase")
+	}
+
+	match, msg = b.screen(m, "c1", "python", "import socket")
+	if msg != nil || match == nil || match.Action != policy.ActionFlag {
+		t.Errorf("Expected a flag without a reply, got %+v and %+v", match, msg)
+	}
+
+	if match, msg := b.screen(m, "c1", "python", "print('hi')"); match != nil || msg != nil {
+		t.Errorf("Expected harmless code to pass, got %+v and %+v", match, msg)
+	}
+}
+
+func TestScreenFiles(t *testing.T) {
+	b := newScreeningBot(t)
+	m := member{GuildID: "g1", UserID: "u1"}
+
+	cmd := &runCommand{Language: "python", Files: []executor.File{
+		{Path: "main.py", Content: []byte("import socket")},
+		{Path: "util.py", Content: []byte("print('ok')")},
+	}}
+	if msg := b.screenFiles(m, "c1", cmd); msg != nil {
+		t.Fatalf("Expected flagged files to run, got %+v", msg)
+	}
+	if cmd.Policy == nil || cmd.Policy.Rule != "sockets" {
+		t.Errorf("Expected the flag to be kept on the command, got %+v", cmd.Policy)
+	}
+
+	cmd.Files = append(cmd.Files, executor.File{Path: "run.sh", Content: []byte("./xmrig")})
+	if msg := b.screenFiles(m, "c1", cmd); msg == nil {
+		t.Fatal("Expected a blocked upload to stop the run")
+	}
+	if cmd.Policy.Rule != "crypto-miner" {
+		t.Errorf("Expected the block to replace the flag, got %+v", cmd.Policy)
+	}
+}
@@ -0,0 +1,354 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/audit"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/scheduler"
+)
+
+// Interactive session controls
+const (
+	// Slash command and subcommands managing sessions
+	sessionCommandName = "session"
+	sessionStart       = "start"
+	sessionStop        = "stop"
+
+	// How often buffered session output is posted to the channel
+	sessionFlushInterval = time.Second
+)
+
+// Session registry errors
+var (
+	errSessionExists   = errors.New("you already have a session in this channel; end it with `/session stop`")
+	errTooManySessions = errors.New("too many interactive sessions are running; try again later")
+	errNoSession       = errors.New("you have no session in this channel")
+)
+
+// sessionKey identifies the session a user owns in a channel
+type sessionKey struct {
+	channelID string
+	userID    string
+}
+
+// sessionRegistry tracks live sessions against the global cap. A key is
+// reserved before the session starts so concurrent starts cannot exceed it.
+type sessionRegistry struct {
+	mu   sync.Mutex
+	max  int
+	live map[sessionKey]*executor.Session
+}
+
+// newSessionRegistry creates a registry allowing at most max live sessions
+func newSessionRegistry(maxSessions int) *sessionRegistry {
+	return &sessionRegistry{max: maxSessions, live: make(map[sessionKey]*executor.Session)}
+}
+
+// reserve claims key for a session that is about to start
+func (r *sessionRegistry) reserve(key sessionKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.live[key]; ok {
+		return errSessionExists
+	}
+	if len(r.live) >= r.max {
+		return errTooManySessions
+	}
+	r.live[key] = nil
+	return nil
+}
+
+// set attaches a started session to its reserved key
+func (r *sessionRegistry) set(key sessionKey, session *executor.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.live[key] = session
+}
+
+// release frees key once its session has ended or failed to start
+func (r *sessionRegistry) release(key sessionKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.live, key)
+}
+
+// get returns the started session for key
+func (r *sessionRegistry) get(key sessionKey) (*executor.Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := r.live[key]
+	return session, session != nil
+}
+
+// closeAll closes every started session, returning how many there were
+func (r *sessionRegistry) closeAll() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+	for _, session := range r.live {
+		if session != nil {
+			session.Close()
+			n++
+		}
+	}
+	return n
+}
+
+// count returns how many sessions are live or starting
+func (r *sessionRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.live)
+}
+
+// sessionCommand returns the /session application command
+func sessionCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        sessionCommandName,
+		Description: "Interactive interpreter sessions",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        sessionStart,
+				Description: "Start a session; your messages in this channel become its input",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionLanguage,
+						Description: "Interpreter language",
+						Required:    true,
+						Choices:     stringChoices(executor.InteractiveLanguageNames()),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        sessionStop,
+				Description: "End your session in this channel",
+			},
+		},
+	}
+}
+
+// onSessionCommand handles /session start and /session stop
+func (b *Bot) onSessionCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	key := sessionKey{channelID: i.ChannelID, userID: interactionUser(i).ID}
+
+	switch sub := options[0]; sub.Name {
+	case sessionStart:
+		var language string
+		for _, opt := range sub.Options {
+			if opt.Name == optionLanguage {
+				language = opt.StringValue()
+			}
+		}
+		b.startSession(s, i, key, language)
+	case sessionStop:
+		session, ok := b.sessions.get(key)
+		if !ok {
+			b.respondEphemeral(s, i.Interaction, "❌ "+errNoSession.Error())
+			return
+		}
+		session.Close()
+		b.respondEphemeral(s, i.Interaction, "Stopping your session…")
+	}
+}
+
+// startSession reserves a session and an execution slot, then starts the
+// interpreter in the background
+func (b *Bot) startSession(s *discordgo.Session, ic *discordgo.InteractionCreate, key sessionKey, language string) {
+	i := ic.Interaction
+	starter, ok := b.executor.(executor.SessionStarter)
+	if !ok {
+		b.respondEphemeral(s, i, "❌ Interactive sessions are not supported by this executor.")
+		return
+	}
+	lang, ok := executor.LookupLanguage(language)
+	if !ok || !lang.Interactive() {
+		b.respondEphemeral(s, i, fmt.Sprintf("❌ `%s` has no interactive mode. Supported: %s",
+			language, strings.Join(executor.InteractiveLanguageNames(), ", ")))
+		return
+	}
+
+	// Sessions run at the standard tier on the isolated network
+	cmd := &runCommand{Language: lang.Name, Tier: executor.TierStandard, Network: executor.NetworkIsolated}
+	who := interactionMember(ic)
+	if msg := b.checkRun(who, ic.ChannelID, cmd); msg != nil {
+		b.respondEphemeral(s, i, msg.Content)
+		return
+	}
+
+	if err := b.sessions.reserve(key); err != nil {
+		b.respondEphemeral(s, i, "❌ "+err.Error())
+		return
+	}
+
+	// Sessions count against the concurrency limit for their whole lifetime;
+	// rather than queue behind running executions, a busy bot refuses
+	ticket, ok := b.scheduler.TryAcquire(b.slotRequest(who))
+	if !ok {
+		b.sessions.release(key)
+		b.respondEphemeral(s, i, "❌ All execution slots are busy; try again later.")
+		return
+	}
+
+	b.respond(s, i, &discordgo.MessageSend{Content: fmt.Sprintf(
+		"🟢 Starting **%s** session for <@%s>. Your messages in this channel are sent to the interpreter; "+
+			"`/session stop` ends it.", lang.Name, key.userID)})
+
+	go b.runSession(s, starter, ticket, who, key, i.ID, lang.Name)
+}
+
+// runSession starts the interpreter, streams its output to the channel
+// until it ends, and releases the session's slot
+func (b *Bot) runSession(s *discordgo.Session, starter executor.SessionStarter, ticket *scheduler.Ticket,
+	who member, key sessionKey, id, language string) {
+	defer b.scheduler.Release(ticket)
+	started := time.Now()
+
+	log := logrus.WithFields(logrus.Fields{
+		"session_id": id,
+		"user_id":    key.userID,
+		"channel_id": key.channelID,
+		"language":   language,
+	})
+
+	session, err := starter.StartSession(context.Background(), &executor.SessionRequest{ID: id, Language: language})
+	if err != nil {
+		b.sessions.release(key)
+		b.recordSession(id, who, key.channelID, language, started, audit.OutcomeFailed, err)
+		log.WithError(err).Error("Failed to start session")
+		b.send(s, key.channelID, "❌ Failed to start session: "+err.Error())
+		return
+	}
+	b.sessions.set(key, session)
+
+	// A shutdown that began while the interpreter started has already
+	// closed the sessions it knew about, so this one is closed here
+	if b.closing.Load() {
+		session.Close()
+	}
+
+	b.streamSession(s, key.channelID, session)
+	<-session.Done()
+	b.recordSession(id, who, key.channelID, language, started, string(session.Reason()), nil)
+
+	b.send(s, key.channelID, fmt.Sprintf("⚪ <@%s>'s **%s** session ended: %s",
+		key.userID, language, describeSessionEnd(session.Reason())))
+
+	// Released only once the channel is told, so shutdown waits for it
+	b.sessions.release(key)
+}
+
+// streamSession posts session output in batches until the output closes
+func (b *Bot) streamSession(s *discordgo.Session, channelID string, session *executor.Session) {
+	ticker := time.NewTicker(sessionFlushInterval)
+	defer ticker.Stop()
+
+	var pending strings.Builder
+	flush := func() {
+		for _, chunk := range splitOutput(pending.String(), maxMessageLength-messageOverhead) {
+			b.send(s, channelID, fmt.Sprintf("```\n%s\n```", escapeCodeBlock(chunk)))
+		}
+		pending.Reset()
+	}
+
+	for {
+		select {
+		case chunk, ok := <-session.Output():
+			if !ok {
+				flush()
+				return
+			}
+			pending.Write(chunk)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// feedSession sends a message's content to the author's session
+func (b *Bot) feedSession(s *discordgo.Session, m *discordgo.Message, session *executor.Session) {
+	input := sessionInput(m.Content)
+	if input == "" {
+		return
+	}
+	if _, msg := b.screen(messageMember(s, m), m.ChannelID, session.Language, input); msg != nil {
+		b.reply(s, m, msg)
+		return
+	}
+	if err := session.Send(input); err != nil {
+		b.reply(s, m, &discordgo.MessageSend{Content: "❌ " + err.Error()})
+	}
+}
+
+// sessionInput extracts interpreter input from a message: the body of its
+// first code block if it has one, otherwise the whole message. Multi-line
+// input gets a trailing blank line so interpreters close open blocks.
+func sessionInput(content string) string {
+	if _, code, _, ok := parseCodeBlock(content); ok {
+		content = code
+	}
+	content = strings.TrimSpace(content)
+	if strings.Contains(content, "\n") {
+		return content + "\n\n"
+	}
+	return terminateInput(content)
+}
+
+// splitOutput breaks output into pieces of at most limit characters,
+// preferring line boundaries; blank output produces no pieces
+func splitOutput(output string, limit int) []string {
+	var pieces []string
+	for strings.TrimSpace(output) != "" {
+		head, _ := headOfOutput(output, limit)
+		output = strings.TrimPrefix(output[len(head):], "\n")
+		if strings.TrimSpace(head) != "" {
+			pieces = append(pieces, head)
+		}
+	}
+	return pieces
+}
+
+// describeSessionEnd explains why a session ended
+func describeSessionEnd(reason executor.SessionEndReason) string {
+	switch reason {
+	case executor.SessionIdle:
+		return "idle timeout reached."
+	case executor.SessionMaxRuntime:
+		return "maximum session lifetime reached."
+	case executor.SessionOutputLimit:
+		return "output exceeded the capture limit."
+	case executor.SessionClosed:
+		return "stopped by its owner."
+	default:
+		return "the interpreter exited."
+	}
+}
+
+// send posts a plain message to a channel without pinging anyone
+func (b *Bot) send(s *discordgo.Session, channelID, content string) {
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("channel_id", channelID).Error("Failed to send message")
+	}
+}
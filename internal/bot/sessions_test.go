@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSessionRegistryReserve(t *testing.T) {
+	r := newSessionRegistry(2)
+	alice := sessionKey{channelID: "c1", userID: "alice"}
+	bob := sessionKey{channelID: "c1", userID: "bob"}
+
+	if err := r.reserve(alice); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := r.reserve(alice); !errors.Is(err, errSessionExists) {
+		t.Errorf("Expected errSessionExists, got %v", err)
+	}
+	if _, ok := r.get(alice); ok {
+		t.Error("Expected reserved key without a started session to be absent")
+	}
+
+	if err := r.reserve(bob); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	carol := sessionKey{channelID: "c2", userID: "carol"}
+	if err := r.reserve(carol); !errors.Is(err, errTooManySessions) {
+		t.Errorf("Expected errTooManySessions, got %v", err)
+	}
+
+	r.release(alice)
+	if err := r.reserve(carol); err != nil {
+		t.Errorf("Expected released capacity to be reusable, got %v", err)
+	}
+}
+
+func TestSessionInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "plain", content: "print(1)", expected: "print(1)\n"},
+		{name: "inline block", content: "```x = 2```", expected: "x = 2\n"},
+		{
+			name:     "multi-line block",
+			content:  "```py\nfor i in range(2):\n    print(i)\n```",
+			expected: "for i in range(2):\n    print(i)\n\n",
+		},
+		{name: "blank", content: "   ", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionInput(tt.content); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	if pieces := splitOutput("\n\n", 10); len(pieces) != 0 {
+		t.Errorf("Expected no pieces for blank output, got %q", pieces)
+	}
+
+	output := strings.Repeat("abcd\n", 10)
+	pieces := splitOutput(output, 12)
+	if len(pieces) < 4 {
+		t.Fatalf("Expected output split into several pieces, got %q", pieces)
+	}
+	for _, p := range pieces {
+		if len(p) > 12 {
+			t.Errorf("Expected pieces of at most 12 characters, got %q", p)
+		}
+	}
+	if joined := strings.Join(pieces, "\n"); joined != strings.TrimRight(output, "\n") {
+		t.Errorf("Expected pieces to cover the output, got %q", joined)
+	}
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Shutdown timing
+const (
+	// How often shutdown checks whether executions have finished
+	shutdownPollInterval = 100 * time.Millisecond
+
+	// Part of the grace period kept for executions stopped at its end to
+	// clean up and post their replies: a quarter, at most 15 seconds
+	shutdownCleanupShare   = 4
+	shutdownCleanupTimeout = 15 * time.Second
+)
+
+// errShuttingDown is the reply to commands arriving during shutdown
+var errShuttingDown = errors.New("the bot is restarting; try again in a minute")
+
+// interruptedReply reports an execution stopped by shutdown
+func interruptedReply(exec *execution) *discordgo.MessageSend {
+	return &discordgo.MessageSend{Content: fmt.Sprintf(
+		"🛑 Execution `%s` was stopped because the bot is restarting; try again in a minute.", exec.ID,
+	)}
+}
+
+// Shutdown stops the bot gracefully. New runs are refused, queued executions
+// are stopped with a reply telling their users, and interactive sessions are
+// closed. Running executions may finish until shortly before ctx's deadline;
+// those still running are then stopped, which removes their containers. The
+// Discord connection is closed once their replies are posted or ctx is done.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	if b.closing.Swap(true) {
+		return nil
+	}
+	close(b.done)
+	started := time.Now()
+
+	queued := b.executions.interrupt(false)
+	sessions := b.sessions.closeAll()
+	log := logrus.WithFields(logrus.Fields{
+		"queued":   queued,
+		"running":  b.executions.count() - queued,
+		"sessions": sessions,
+	})
+	if deadline, ok := ctx.Deadline(); ok {
+		log = log.WithField("grace", time.Until(deadline).Round(time.Second))
+	}
+	log.Info("Shutting down; waiting for running executions")
+
+	drain, cancel := context.WithCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		cleanup := min(time.Until(deadline)/shutdownCleanupShare, shutdownCleanupTimeout)
+		drain, cancel = context.WithDeadline(ctx, deadline.Add(-cleanup))
+	}
+	defer cancel()
+
+	if !b.waitIdle(drain) {
+		stopped := b.executions.interrupt(true)
+		logrus.WithField("stopped", stopped).Warn("Grace period over; stopping running executions")
+
+		if !b.waitIdle(ctx) {
+			logrus.WithFields(logrus.Fields{
+				"executions": b.executions.count(),
+				"sessions":   b.sessions.count(),
+			}).Warn("Executions still running at shutdown")
+		}
+	}
+
+	logrus.WithField("duration", time.Since(started).Round(time.Millisecond)).Info("Executions drained")
+	return b.session.Close()
+}
+
+// waitIdle waits until no executions or sessions are left, reporting false
+// if ctx ends first
+func (b *Bot) waitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for b.executions.count() > 0 || b.sessions.count() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
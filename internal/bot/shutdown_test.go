@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newShutdownBot returns a bot with no open connection and nothing running
+func newShutdownBot(t *testing.T) *Bot {
+	t.Helper()
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Bot{
+		session:    session,
+		executions: newExecutionRegistry(),
+		sessions:   newSessionRegistry(1),
+		done:       make(chan struct{}),
+	}
+}
+
+// finishWhenStopped finishes exec once it is cancelled, as run does
+func finishWhenStopped(b *Bot, exec *execution) {
+	go func() {
+		<-exec.ctx.Done()
+		b.executions.finish(exec)
+	}()
+}
+
+func TestShutdownWaitsForRunningExecutions(t *testing.T) {
+	b := newShutdownBot(t)
+	queued := b.executions.start("queued", "u1", "c1")
+	running := b.executions.start("running", "u2", "c1")
+	if !running.begin() {
+		t.Fatal("Expected the execution to start")
+	}
+	finishWhenStopped(b, queued)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		b.executions.finish(running)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if !queued.Interrupted() || queued.begin() {
+		t.Error("Expected the queued execution to be stopped before it started")
+	}
+	if running.Interrupted() {
+		t.Error("Expected the running execution to finish within the grace period")
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected shutdown to return once executions finished")
+	}
+
+	msg := b.checkRun(member{UserID: "u1"}, "c1", &runCommand{Language: "python"})
+	if msg == nil || !strings.Contains(msg.Content, "restarting") {
+		t.Errorf("Expected new runs to be refused, got %+v", msg)
+	}
+}
+
+func TestShutdownStopsExecutionsAfterGrace(t *testing.T) {
+	b := newShutdownBot(t)
+	running := b.executions.start("running", "u1", "c1")
+	running.begin()
+	finishWhenStopped(b, running)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if !running.Interrupted() {
+		t.Error("Expected the running execution to be stopped after the grace period")
+	}
+	if n := b.executions.count(); n != 0 {
+		t.Errorf("Expected no executions left, got %d", n)
+	}
+}
+
+func TestShutdownCleansUpWithinGrace(t *testing.T) {
+	b := newShutdownBot(t)
+	running := b.executions.start("running", "u1", "c1")
+	running.begin()
+	go func() {
+		// Posting the reply takes a while after the stop
+		<-running.ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		b.executions.finish(running)
+	}()
+
+	grace := time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	started := time.Now()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= grace {
+		t.Errorf("Expected cleanup to fit in the grace period, took %v", elapsed)
+	}
+	if !running.Interrupted() || b.executions.count() != 0 {
+		t.Error("Expected the execution to be stopped early enough to finish")
+	}
+}
@@ -0,0 +1,828 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// Snippet commands and options
+const (
+	snippetCommandName = "snippet"
+
+	// Subcommands
+	snippetSave   = "save"
+	snippetRun    = "run"
+	snippetList   = "list"
+	snippetShow   = "show"
+	snippetDelete = "delete"
+	snippetExport = "export"
+	snippetImport = "import"
+
+	// Options
+	optionName    = "name"
+	optionTags    = "tags"
+	optionTag     = "tag"
+	optionShared  = "shared"
+	optionArgs    = "args"
+	optionVersion = "version"
+	optionFile    = "file"
+
+	// Most command-line arguments passed to a snippet
+	maxSnippetArgs = 32
+
+	// Import failures listed in the reply
+	maxImportFailures = 10
+
+	// History records read at a time when looking for the last run with code
+	lastRunPageSize = 25
+
+	// Name of the attachment holding exported snippets
+	snippetExportName = "snippets.json"
+
+	// Version of the export format
+	snippetExportFormat = 1
+)
+
+// Snippet errors
+var (
+	errSnippetsDisabled = errors.New("snippets are not enabled on this bot")
+	errSharedInDM       = errors.New("shared snippets are only available in servers")
+	errNoSnippetCode    = errors.New("no code to save; pass code, or run something first to save your last run")
+	errInvalidSnippet   = errors.New("snippet names are 1-32 lowercase letters, digits, - and _")
+	errNoSnippetBlock   = errors.New("wrap the code to save in ``` fences")
+
+	// errNotSnippetCommand means the message is not addressed to the snippet command
+	errNotSnippetCommand = errors.New("not a snippet command")
+)
+
+// snippetNamePattern matches valid snippet names
+var snippetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// snippetExportFile is the JSON document written by export and read by import
+type snippetExportFile struct {
+	Format   int              `json:"format"`
+	Snippets []*store.Snippet `json:"snippets"`
+}
+
+// snippetCommand returns the /snippet application command
+func snippetCommand() *discordgo.ApplicationCommand {
+	name := func(description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        optionName,
+			Description: description,
+			Required:    true,
+		}
+	}
+	shared := func(description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        optionShared,
+			Description: description,
+		}
+	}
+	minVersion := 1.0
+	version := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        optionVersion,
+		Description: "Version to use; defaults to the latest",
+		MinValue:    &minVersion,
+	}
+	lookup := "Use the server's shared snippet; by default yours is used, then the shared one"
+
+	return &discordgo.ApplicationCommand{
+		Name:        snippetCommandName,
+		Description: "Saved code snippets",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetSave,
+				Description: "Save code, or your last run, under a name; saving again adds a version",
+				Options: []*discordgo.ApplicationCommandOption{
+					name("Snippet name"),
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionCode,
+						Description: "Code to save, optionally in a ``` block; defaults to your last run",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionLanguage,
+						Description: "Language of the code",
+						Choices:     stringChoices(executor.LanguageNames()),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionTags,
+						Description: "Comma-separated tags",
+					},
+					shared("Share the snippet with everyone in this server"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetRun,
+				Description: "Run a saved snippet",
+				Options: []*discordgo.ApplicationCommandOption{
+					name("Snippet name"),
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionArgs,
+						Description: "Command-line arguments, separated by spaces",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionInput,
+						Description: "Text passed to the program's standard input",
+					},
+					version,
+					shared(lookup),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetList,
+				Description: "List your snippets and this server's shared ones",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        optionTag,
+						Description: "Only list snippets with this tag",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetShow,
+				Description: "Show a snippet's code and versions",
+				Options:     []*discordgo.ApplicationCommandOption{name("Snippet name"), version, shared(lookup)},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetDelete,
+				Description: "Delete a snippet and all its versions",
+				Options: []*discordgo.ApplicationCommandOption{
+					name("Snippet name"),
+					shared("Delete the server's shared snippet instead of yours"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetExport,
+				Description: "Download snippets as JSON",
+				Options: []*discordgo.ApplicationCommandOption{
+					shared("Export the server's shared snippets instead of yours"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        snippetImport,
+				Description: "Add snippets from a JSON export as new versions",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        optionFile,
+						Description: "File written by /snippet export",
+						Required:    true,
+					},
+					shared("Import into the server's shared snippets instead of yours"),
+				},
+			},
+		},
+	}
+}
+
+// snippetOptions holds the options given to a /snippet subcommand
+type snippetOptions struct {
+	Name     string
+	Code     string
+	Language string
+	Tags     []string
+	Tag      string
+	Args     []string
+	Input    string
+	Version  int
+	File     string
+
+	// Whether shared was given, and its value
+	SharedSet bool
+	Shared    bool
+}
+
+// parseSnippetOptions reads the options of a /snippet subcommand
+func parseSnippetOptions(sub *discordgo.ApplicationCommandInteractionDataOption) snippetOptions {
+	var o snippetOptions
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case optionName:
+			o.Name = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		case optionCode:
+			o.Code = opt.StringValue()
+		case optionLanguage:
+			o.Language = opt.StringValue()
+		case optionTags:
+			o.Tags = parseTags(opt.StringValue())
+		case optionTag:
+			o.Tag = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		case optionArgs:
+			o.Args = strings.Fields(opt.StringValue())
+		case optionInput:
+			o.Input = terminateInput(opt.StringValue())
+		case optionVersion:
+			o.Version = int(opt.IntValue())
+		case optionFile:
+			o.File, _ = opt.Value.(string)
+		case optionShared:
+			o.SharedSet, o.Shared = true, opt.BoolValue()
+		}
+	}
+	return o
+}
+
+// parseTags splits comma- or space-separated tags, lowercased, sorted and
+// without duplicates
+func parseTags(s string) []string {
+	tags := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == ',' || r == ' ' })
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// onSnippetCommand handles the /snippet subcommands
+func (b *Bot) onSnippetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	if b.store == nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+errSnippetsDisabled.Error()+".")
+		return
+	}
+	sub := options[0]
+	o := parseSnippetOptions(sub)
+	who := interactionMember(i)
+
+	switch sub.Name {
+	case snippetSave:
+		b.onSnippetSave(s, i, who, o)
+	case snippetRun:
+		b.onSnippetRun(s, i, who, o)
+	case snippetList:
+		b.onSnippetList(s, i, who, o)
+	case snippetShow:
+		b.onSnippetShow(s, i, who, o)
+	case snippetDelete:
+		b.onSnippetDelete(s, i, who, o)
+	case snippetExport:
+		b.onSnippetExport(s, i, who, o)
+	case snippetImport:
+		b.onSnippetImport(s, i, who, o)
+	}
+}
+
+// onSnippetSave saves the given code, or the invoker's last run
+func (b *Bot) onSnippetSave(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	language, code := o.Language, o.Code
+	if strings.Contains(code, "```") {
+		if tag, block, _, ok := parseCodeBlock(code); ok {
+			code = block
+			if language == "" {
+				language = tag
+			}
+		}
+	}
+	if strings.TrimSpace(code) == "" {
+		last, err := b.lastRun(who.UserID)
+		if err != nil {
+			b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+			return
+		}
+		language, code = last.Language, last.Code
+	}
+
+	sn := &store.Snippet{Name: o.Name, Language: language, Code: code, Tags: o.Tags}
+	msg, err := b.saveSnippet(s, who, i.ChannelID, sn, o.Shared)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	b.respondEphemeral(s, i.Interaction, msg)
+}
+
+// lastRun returns the invoker's latest run that had inline code, looking
+// past runs of uploaded files
+func (b *Bot) lastRun(userID string) (*store.Execution, error) {
+	for offset := 0; ; offset += lastRunPageSize {
+		records, total, err := b.store.Executions(userID, offset, lastRunPageSize)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", userID).Error("Failed to read execution history")
+			return nil, errors.New("failed to read your last run")
+		}
+		for _, record := range records {
+			if record.Code != "" {
+				return record, nil
+			}
+		}
+		if len(records) == 0 || offset+len(records) >= total {
+			return nil, errNoSnippetCode
+		}
+	}
+}
+
+// saveSnippet validates sn and saves it as the next version in the
+// invoker's or the guild's namespace, returning the confirmation to show.
+// Shared snippets may only be overwritten by their author or admins.
+func (b *Bot) saveSnippet(s *discordgo.Session, who member, channelID string, sn *store.Snippet,
+	shared bool) (string, error) {
+	ns, err := snippetNamespace(who, shared)
+	if err != nil {
+		return "", err
+	}
+	if !snippetNamePattern.MatchString(sn.Name) {
+		return "", errInvalidSnippet
+	}
+	if strings.TrimSpace(sn.Code) == "" {
+		return "", errNoSnippetCode
+	}
+	lang, ok := executor.LookupLanguage(sn.Language)
+	if !ok {
+		return "", fmt.Errorf("unsupported language %q", sn.Language)
+	}
+
+	existing, err := b.store.Snippet(ns, sn.Name, 0)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		if err := b.checkSnippetCount(ns); err != nil {
+			return "", err
+		}
+	case err != nil:
+		logrus.WithError(err).WithField("snippet", sn.Name).Error("Failed to read snippet")
+		return "", errors.New("failed to read snippets")
+	case shared && existing.AuthorID != who.UserID && !b.isAdmin(s, who, channelID, adminSnippets):
+		return "", fmt.Errorf("the shared snippet `%s` belongs to someone else", sn.Name)
+	}
+
+	sn.Namespace = ns
+	sn.Language = lang.Name
+	sn.AuthorID = who.UserID
+	sn.CreatedAt = time.Now().UTC()
+	if err := b.store.SaveSnippet(sn, b.cfg.Snippets.MaxVersions); err != nil {
+		logrus.WithError(err).WithField("snippet", sn.Name).Error("Failed to save snippet")
+		return "", errors.New("failed to save the snippet")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  who.UserID,
+		"guild_id": who.GuildID,
+		"snippet":  sn.Name,
+		"version":  sn.Version,
+		"shared":   shared,
+	}).Info("Saved snippet")
+	where := "your snippets"
+	if shared {
+		where = "this server's shared snippets"
+	}
+	return fmt.Sprintf("💾 Saved `%s` v%d (%s) to %s.", sn.Name, sn.Version, sn.Language, where), nil
+}
+
+// checkSnippetCount refuses a new snippet name when the namespace is full
+func (b *Bot) checkSnippetCount(ns store.Namespace) error {
+	limit := b.cfg.Snippets.MaxPerUser
+	if ns.Scope == store.ScopeGuild {
+		limit = b.cfg.Snippets.MaxPerGuild
+	}
+	if limit == 0 {
+		return nil
+	}
+	count, err := b.store.CountSnippets(ns)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to count snippets")
+		return errors.New("failed to read snippets")
+	}
+	if count >= limit {
+		return fmt.Errorf("the limit of %d snippets is reached; delete one first", limit)
+	}
+	return nil
+}
+
+// snippetNamespace returns the invoker's own namespace, or the guild's
+// when shared
+func snippetNamespace(who member, shared bool) (store.Namespace, error) {
+	if !shared {
+		return store.UserNamespace(who.UserID), nil
+	}
+	if who.GuildID == "" {
+		return store.Namespace{}, errSharedInDM
+	}
+	return store.GuildNamespace(who.GuildID), nil
+}
+
+// findSnippet looks a snippet up in the namespace chosen by the shared
+// option, or else in the invoker's own and then the guild's
+func (b *Bot) findSnippet(who member, o snippetOptions) (*store.Snippet, error) {
+	var namespaces []store.Namespace
+	switch {
+	case o.SharedSet:
+		ns, err := snippetNamespace(who, o.Shared)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	case who.GuildID != "":
+		namespaces = append(namespaces, store.UserNamespace(who.UserID), store.GuildNamespace(who.GuildID))
+	default:
+		namespaces = append(namespaces, store.UserNamespace(who.UserID))
+	}
+
+	for _, ns := range namespaces {
+		sn, err := b.store.Snippet(ns, o.Name, o.Version)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("snippet", o.Name).Error("Failed to read snippet")
+			return nil, errors.New("failed to read snippets")
+		}
+		return sn, nil
+	}
+	if o.Version > 0 {
+		return nil, fmt.Errorf("no snippet `%s` with version %d", o.Name, o.Version)
+	}
+	return nil, fmt.Errorf("no snippet `%s`; see /snippet list", o.Name)
+}
+
+// onSnippetRun runs a snippet like /run, with arguments and input
+func (b *Bot) onSnippetRun(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	sn, err := b.findSnippet(who, o)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	if len(o.Args) > maxSnippetArgs {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("❌ At most %d arguments may be passed.", maxSnippetArgs))
+		return
+	}
+
+	cmd := &runCommand{Language: sn.Language, Code: sn.Code, Stdin: o.Input, Args: o.Args}
+	if msg := b.checkRun(who, i.ChannelID, cmd); msg != nil {
+		b.respond(s, i.Interaction, msg)
+		return
+	}
+	b.runInteraction(s, i, cmd, nil, "")
+}
+
+// onSnippetList lists the invoker's and the guild's snippets
+func (b *Bot) onSnippetList(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	type section struct {
+		title string
+		ns    store.Namespace
+	}
+	sections := []section{{"Your snippets", store.UserNamespace(who.UserID)}}
+	if who.GuildID != "" {
+		sections = append(sections, section{"Shared in this server", store.GuildNamespace(who.GuildID)})
+	}
+
+	embed := &discordgo.MessageEmbed{Title: "📚 Snippets", Color: colorCanceled}
+	if o.Tag != "" {
+		embed.Title += " tagged " + o.Tag
+	}
+	for _, section := range sections {
+		snippets, err := b.store.Snippets(section.ns, o.Tag)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to list snippets")
+			b.respondEphemeral(s, i.Interaction, "❌ Failed to read snippets.")
+			return
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (%d)", section.title, len(snippets)),
+			Value: describeSnippets(snippets),
+		})
+	}
+	b.respondEphemeralEmbed(s, i.Interaction, embed, nil)
+}
+
+// describeSnippets lists snippets one per line within an embed field's limit
+func describeSnippets(snippets []*store.Snippet) string {
+	const fieldLimit = 1024
+	if len(snippets) == 0 {
+		return "None"
+	}
+
+	var b strings.Builder
+	for n, sn := range snippets {
+		line := fmt.Sprintf("`%s` · %s · v%d", sn.Name, sn.Language, sn.Version)
+		if len(sn.Tags) > 0 {
+			line += " · " + strings.Join(sn.Tags, ", ")
+		}
+		more := fmt.Sprintf("…and %d more", len(snippets)-n)
+		if b.Len()+len(line)+len(more)+2 > fieldLimit {
+			b.WriteString(more)
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// onSnippetShow shows a snippet's code and stored versions
+func (b *Bot) onSnippetShow(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	sn, err := b.findSnippet(who, o)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	versions, err := b.store.SnippetVersions(sn.Namespace, sn.Name)
+	if err != nil {
+		logrus.WithError(err).WithField("snippet", sn.Name).Error("Failed to read snippet versions")
+	}
+
+	var desc strings.Builder
+	lang, _ := executor.LookupLanguage(sn.Language)
+	var files []*discordgo.File
+	if writeOutputBlock(&desc, sn.Code, embedOutputLength) {
+		files = append(files, textFile(lang.FileName, sn.Code))
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s · v%d · %s", sn.Name, sn.Version, sn.Language),
+		Description: desc.String(),
+		Color:       colorCanceled,
+		Timestamp:   sn.CreatedAt.Format(time.RFC3339),
+	}
+	if sn.Namespace.Scope == store.ScopeGuild {
+		author := "a former member"
+		if sn.AuthorID != "" {
+			author = "<@" + sn.AuthorID + ">"
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Shared by", Value: author, Inline: true})
+	}
+	if len(sn.Tags) > 0 {
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "Tags", Value: strings.Join(sn.Tags, ", "), Inline: true})
+	}
+	if len(versions) > 1 {
+		names := make([]string, len(versions))
+		for n, v := range versions {
+			names[n] = fmt.Sprintf("v%d", v)
+		}
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "Versions", Value: strings.Join(names, ", "), Inline: true})
+	}
+	b.respondEphemeralEmbed(s, i.Interaction, embed, files)
+}
+
+// onSnippetDelete deletes every version of a snippet. Shared snippets may
+// only be deleted by their author or admins.
+func (b *Bot) onSnippetDelete(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	ns, err := snippetNamespace(who, o.Shared)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	sn, err := b.store.Snippet(ns, o.Name, 0)
+	if errors.Is(err, store.ErrNotFound) {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("❌ No snippet `%s`.", o.Name))
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("snippet", o.Name).Error("Failed to read snippet")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to read snippets.")
+		return
+	}
+	if o.Shared && sn.AuthorID != who.UserID && !b.isAdmin(s, who, i.ChannelID, adminSnippets) {
+		b.respondEphemeral(s, i.Interaction, fmt.Sprintf("🚫 The shared snippet `%s` belongs to someone else.", o.Name))
+		return
+	}
+
+	deleted, err := b.store.DeleteSnippet(ns, o.Name)
+	if err != nil {
+		logrus.WithError(err).WithField("snippet", o.Name).Error("Failed to delete snippet")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to delete the snippet.")
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"user_id":  who.UserID,
+		"guild_id": who.GuildID,
+		"snippet":  o.Name,
+		"shared":   o.Shared,
+	}).Info("Deleted snippet")
+	b.respondEphemeral(s, i.Interaction, fmt.Sprintf("🗑️ Deleted `%s` (%d versions).", o.Name, deleted))
+}
+
+// onSnippetExport attaches every version of the chosen snippets as JSON
+func (b *Bot) onSnippetExport(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	ns, err := snippetNamespace(who, o.Shared)
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ "+err.Error())
+		return
+	}
+	snippets, err := b.store.AllSnippets(ns)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to export snippets")
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to read snippets.")
+		return
+	}
+	if len(snippets) == 0 {
+		b.respondEphemeral(s, i.Interaction, "There are no snippets to export.")
+		return
+	}
+
+	data, err := json.MarshalIndent(snippetExportFile{Format: snippetExportFormat, Snippets: snippets}, "", "  ")
+	if err != nil {
+		b.respondEphemeral(s, i.Interaction, "❌ Failed to encode snippets.")
+		return
+	}
+	file := &discordgo.File{
+		Name:        snippetExportName,
+		ContentType: "application/json",
+		Reader:      strings.NewReader(string(data)),
+	}
+	b.respondEphemeralEmbed(s, i.Interaction, &discordgo.MessageEmbed{
+		Title:       "📦 Snippet export",
+		Description: fmt.Sprintf("%d versions. Load them with `/snippet import`.", len(snippets)),
+		Color:       colorCanceled,
+	}, []*discordgo.File{file})
+}
+
+// onSnippetImport saves the snippets in an uploaded export as new versions,
+// oldest first, reporting those that could not be saved
+func (b *Bot) onSnippetImport(s *discordgo.Session, i *discordgo.InteractionCreate, who member, o snippetOptions) {
+	var att *discordgo.MessageAttachment
+	if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+		att = resolved.Attachments[o.File]
+	}
+	if att == nil {
+		b.respondEphemeral(s, i.Interaction, "❌ Attach a file written by `/snippet export`.")
+		return
+	}
+
+	// Downloading and saving many versions easily exceeds Discord's three
+	// second limit, so the response is deferred
+	reply, err := b.deferEphemeralReply(s, i)
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to acknowledge interaction")
+		return
+	}
+
+	data, err := download(context.Background(), att.URL, b.cfg.MaxUploadBytes)
+	if err != nil {
+		reply.reply(s, "❌ Failed to read the file: "+err.Error())
+		return
+	}
+	export, err := parseSnippetExport(data)
+	if err != nil {
+		reply.reply(s, "❌ "+err.Error())
+		return
+	}
+
+	var saved int
+	var failures []string
+	for _, sn := range export.Snippets {
+		sn.Name = strings.ToLower(sn.Name)
+		sn.Tags = parseTags(strings.Join(sn.Tags, ","))
+		if _, err := b.saveSnippet(s, who, i.ChannelID, sn, o.Shared); err != nil {
+			failures = append(failures, fmt.Sprintf("`%s`: %v", sn.Name, err))
+			continue
+		}
+		saved++
+	}
+
+	content := fmt.Sprintf("📥 Imported %d of %d versions.", saved, len(export.Snippets))
+	if len(failures) > maxImportFailures {
+		failures = append(failures[:maxImportFailures], fmt.Sprintf("…and %d more", len(failures)-maxImportFailures))
+	}
+	if len(failures) > 0 {
+		content += "\n" + strings.Join(failures, "\n")
+	}
+	reply.reply(s, content)
+}
+
+// parseSnippetExport decodes an export, ordering its snippets by name and
+// version so that versions are recreated in order
+func parseSnippetExport(data []byte) (*snippetExportFile, error) {
+	var export snippetExportFile
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("the file is not a snippet export: %w", err)
+	}
+	if export.Format != snippetExportFormat {
+		return nil, fmt.Errorf("unsupported snippet export format %d", export.Format)
+	}
+	export.Snippets = slices.DeleteFunc(export.Snippets, func(sn *store.Snippet) bool { return sn == nil })
+	slices.SortStableFunc(export.Snippets, func(a, b *store.Snippet) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return a.Version - b.Version
+	})
+	return &export, nil
+}
+
+// snippetSaveCommand is a snippet saved with a prefix command
+type snippetSaveCommand struct {
+	Name     string
+	Language string
+	Code     string
+	Tags     []string
+	Shared   bool
+}
+
+// parseSnippetSave parses messages of the form
+//
+//	!snippet save <name> [language] [--tags=a,b] [--shared]
+//	```[language]
+//	code
+//	```
+//
+// The language on the command line takes precedence over the fence tag.
+func parseSnippetSave(prefix, content string) (*snippetSaveCommand, error) {
+	content = strings.TrimSpace(content)
+	command := prefix + snippetCommandName
+	if !strings.HasPrefix(content, command) {
+		return nil, errNotSnippetCommand
+	}
+	rest := content[len(command):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\n' && rest[0] != '\t' {
+		return nil, errNotSnippetCommand
+	}
+
+	header, body, _ := strings.Cut(rest, "```")
+	fields := strings.Fields(header)
+	if len(fields) < 2 || fields[0] != snippetSave {
+		return nil, fmt.Errorf("use `%s %s <name> [language]` followed by a code block; "+
+			"see /snippet for everything else", command, snippetSave)
+	}
+
+	cmd := &snippetSaveCommand{Name: strings.ToLower(fields[1])}
+	for _, field := range fields[2:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(field, "--"), "=")
+		switch {
+		case !strings.HasPrefix(field, "--") && cmd.Language == "":
+			cmd.Language = field
+		case name == optionTags:
+			cmd.Tags = parseTags(value)
+		case name == optionShared && value == "":
+			cmd.Shared = true
+		default:
+			return nil, fmt.Errorf("unexpected `%s`; use --%s=<a,b> or --%s", field, optionTags, optionShared)
+		}
+	}
+
+	tag, code, _, ok := parseCodeBlock("```" + body)
+	if !ok {
+		return nil, errNoSnippetBlock
+	}
+	if cmd.Language == "" {
+		cmd.Language = tag
+	}
+	if cmd.Language == "" {
+		return nil, errMissingLanguage
+	}
+	cmd.Code = code
+	return cmd, nil
+}
+
+// onSnippetMessage saves a snippet from a prefix command
+func (b *Bot) onSnippetMessage(s *discordgo.Session, m *discordgo.Message, cmd *snippetSaveCommand, err error) {
+	var content string
+	switch {
+	case err != nil:
+		content = "❌ " + err.Error()
+	case b.store == nil:
+		content = "❌ " + errSnippetsDisabled.Error() + "."
+	default:
+		sn := &store.Snippet{Name: cmd.Name, Language: cmd.Language, Code: cmd.Code, Tags: cmd.Tags}
+		content, err = b.saveSnippet(s, messageMember(s, m), m.ChannelID, sn, cmd.Shared)
+		if err != nil {
+			content = "❌ " + err.Error()
+		}
+	}
+	b.reply(s, m, &discordgo.MessageSend{Content: content})
+}
+
+// respondEphemeralEmbed answers an interaction with an embed only the
+// invoker sees
+func (b *Bot) respondEphemeralEmbed(s *discordgo.Session, i *discordgo.Interaction, embed *discordgo.MessageEmbed,
+	files []*discordgo.File) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:          []*discordgo.MessageEmbed{embed},
+			Files:           files,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("interaction_id", i.ID).Error("Failed to respond to interaction")
+	}
+}
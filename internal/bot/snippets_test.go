@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/store"
+)
+
+// newSnippetBot returns a bot with an empty store and the given limits
+func newSnippetBot(t *testing.T, cfg config.SnippetsConfig) *Bot {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Bot{store: db, cfg: config.BotConfig{Snippets: cfg}}
+}
+
+func TestParseSnippetSave(t *testing.T) {
+	cmd, err := parseSnippetSave("!", "!snippet save Diag --tags=net,Debug --shared\n```py\nprint(1)\n```")
+	if err != nil {
+		t.Fatalf("Expected a valid command, got %v", err)
+	}
+	if cmd.Name != "diag" || cmd.Language != "py" || cmd.Code != "print(1)" || !cmd.Shared {
+		t.Errorf("Expected name, fence language, code and sharing, got %+v", cmd)
+	}
+	if strings.Join(cmd.Tags, ",") != "debug,net" {
+		t.Errorf("Expected sorted lowercase tags, got %v", cmd.Tags)
+	}
+
+	cmd, err = parseSnippetSave("!", "!snippet save x bash ```echo hi```")
+	if err != nil || cmd.Language != "bash" || cmd.Code != "echo hi" {
+		t.Errorf("Expected the command line language, got %+v, %v", cmd, err)
+	}
+
+	for _, content := range []string{"!run py ```1```", "!snippets save x", "hello"} {
+		if _, err := parseSnippetSave("!", content); !errors.Is(err, errNotSnippetCommand) {
+			t.Errorf("Expected %q not to be a snippet command, got %v", content, err)
+		}
+	}
+	for _, content := range []string{"!snippet run x", "!snippet save x py", "!snippet save x ```1```"} {
+		if _, err := parseSnippetSave("!", content); err == nil || errors.Is(err, errNotSnippetCommand) {
+			t.Errorf("Expected %q to be rejected, got %v", content, err)
+		}
+	}
+}
+
+func TestSaveSnippet(t *testing.T) {
+	b := newSnippetBot(t, config.SnippetsConfig{MaxPerUser: 1, MaxVersions: 2})
+	who := member{GuildID: "g1", UserID: "u1"}
+
+	for i := 1; i <= 3; i++ {
+		msg, err := b.saveSnippet(nil, who, "c1", &store.Snippet{Name: "diag", Language: "py", Code: "print(1)"}, false)
+		if err != nil {
+			t.Fatalf("Expected the snippet to be saved, got %v", err)
+		}
+		if !strings.Contains(msg, fmt.Sprintf("v%d (python)", i)) {
+			t.Errorf("Expected version %d with the canonical language, got %q", i, msg)
+		}
+	}
+	if versions, _ := b.store.SnippetVersions(store.UserNamespace("u1"), "diag"); len(versions) != 2 {
+		t.Errorf("Expected 2 versions kept, got %v", versions)
+	}
+
+	tests := []struct {
+		name    string
+		snippet store.Snippet
+		who     member
+		shared  bool
+	}{
+		{"over the limit", store.Snippet{Name: "other", Language: "py", Code: "1"}, who, false},
+		{"invalid name", store.Snippet{Name: "no spaces", Language: "py", Code: "1"}, who, false},
+		{"unknown language", store.Snippet{Name: "diag", Language: "cobol", Code: "1"}, who, false},
+		{"empty code", store.Snippet{Name: "diag", Language: "py", Code: " "}, who, false},
+		{"shared in a DM", store.Snippet{Name: "diag", Language: "py", Code: "1"}, member{UserID: "u1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := b.saveSnippet(nil, tt.who, "c1", &tt.snippet, tt.shared); err == nil {
+				t.Error("Expected the snippet to be refused")
+			}
+		})
+	}
+}
+
+func TestFindSnippetPrefersOwn(t *testing.T) {
+	b := newSnippetBot(t, config.SnippetsConfig{})
+	who := member{GuildID: "g1", UserID: "u1"}
+	save := func(code string, shared bool) {
+		t.Helper()
+		sn := &store.Snippet{Name: "diag", Language: "py", Code: code}
+		if _, err := b.saveSnippet(nil, who, "c1", sn, shared); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("shared", true)
+
+	if sn, err := b.findSnippet(who, snippetOptions{Name: "diag"}); err != nil || sn.Code != "shared" {
+		t.Fatalf("Expected the shared snippet without an own one, got %+v, %v", sn, err)
+	}
+	save("own", false)
+	if sn, _ := b.findSnippet(who, snippetOptions{Name: "diag"}); sn.Code != "own" {
+		t.Errorf("Expected the own snippet first, got %q", sn.Code)
+	}
+	if sn, _ := b.findSnippet(who, snippetOptions{Name: "diag", SharedSet: true, Shared: true}); sn.Code != "shared" {
+		t.Errorf("Expected the shared snippet on request, got %q", sn.Code)
+	}
+	if _, err := b.findSnippet(member{UserID: "u2"}, snippetOptions{Name: "diag"}); err == nil {
+		t.Error("Expected another user's snippets to be out of reach in DMs")
+	}
+}
+
+func TestLastRunSkipsUploads(t *testing.T) {
+	b := newSnippetBot(t, config.SnippetsConfig{})
+	if _, err := b.lastRun("u1"); !errors.Is(err, errNoSnippetCode) {
+		t.Errorf("Expected errNoSnippetCode without history, got %v", err)
+	}
+
+	start := time.Now()
+	save := func(n int, e *store.Execution) {
+		t.Helper()
+		e.ID, e.UserID, e.Language = fmt.Sprint(n), "u1", "python"
+		e.CreatedAt = start.Add(time.Duration(n) * time.Second)
+		if err := b.store.SaveExecution(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(0, &store.Execution{Code: "print(1)"})
+	for n := 1; n <= lastRunPageSize+1; n++ {
+		save(n, &store.Execution{Files: []string{"main.py"}})
+	}
+
+	last, err := b.lastRun("u1")
+	if err != nil || last.Code != "print(1)" {
+		t.Errorf("Expected the run with code behind a page of uploads, got %+v, %v", last, err)
+	}
+}
+
+func TestParseSnippetExport(t *testing.T) {
+	export, err := parseSnippetExport([]byte(`{"format": 1, "snippets": [
+		{"name": "b", "version": 1, "language": "python", "code": "1"},
+		{"name": "a", "version": 2, "language": "python", "code": "2"},
+		null,
+		{"name": "a", "version": 1, "language": "python", "code": "1"}]}`))
+	if err != nil {
+		t.Fatalf("Expected a valid export, got %v", err)
+	}
+	var order []string
+	for _, sn := range export.Snippets {
+		order = append(order, sn.Name+sn.Code)
+	}
+	if strings.Join(order, " ") != "a1 a2 b1" {
+		t.Errorf("Expected snippets by name and version, got %v", order)
+	}
+
+	if _, err := parseSnippetExport([]byte(`{"format": 2}`)); err == nil {
+		t.Error("Expected an unknown format to be refused")
+	}
+}
+
+func TestScreenedSourceIncludesArgs(t *testing.T) {
+	b := newScreeningBot(t)
+	cmd := &runCommand{Language: "bash", Code: `exec "$@"`, Args: []string{"xmrig", "--donate-level=0"}}
+	if match, _ := b.screen(member{UserID: "u1"}, "c1", cmd.Language, cmd.screenedSource()); match == nil {
+		t.Error("Expected arguments to be screened")
+	}
+}
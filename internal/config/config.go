@@ -11,15 +11,65 @@ import (
 // Default configuration constants
 const (
 	// Default timeouts in seconds
-	DefaultDockerTimeout = 30
-	DefaultMaxRuntime    = 300 // 5 minutes
+	DefaultDockerTimeout  = 30
+	DefaultMaxRuntime     = 300 // 5 minutes
+	DefaultCompileTimeout = 60  // 1 minute
+
+	// Default idle timeout for interactive sessions in seconds
+	DefaultSessionIdleTimeout = 300 // 5 minutes
+
+	// Default window for rerunning edited commands in seconds
+	DefaultEditWindow = 300 // 5 minutes
+
+	// Default time running executions get to finish on shutdown in seconds
+	DefaultShutdownGrace = 30
+
+	// Default seconds between orphaned resource sweeps
+	DefaultReconcileInterval = 300 // 5 minutes
+
+	// Default output capture limit in bytes
+	DefaultMaxOutputBytes = 64 * 1024 // 64 KiB
+
+	// Default standard input limit in bytes
+	DefaultMaxStdinBytes = 64 * 1024 // 64 KiB
+
+	// Default total upload limit in bytes
+	DefaultMaxUploadBytes = 1 << 20 // 1 MiB
+
+	// Default total output file limit in bytes
+	DefaultMaxArtifactBytes = 8 << 20 // 8 MiB
+
+	// Default size limit of a compiled workspace in bytes
+	DefaultMaxWorkspaceBytes = 256 << 20 // 256 MiB
+
+	// Default daily CPU time per user in seconds
+	DefaultDailyCPUSeconds = 900 // 15 minutes
+
+	// Default wait before a request is served ahead of its fair turn
+	DefaultStarvationSeconds = 60
+
+	// Default shortest time between runs of a schedule in seconds
+	DefaultScheduleIntervalSeconds = 300 // 5 minutes
+
+	// Default days execution history is kept
+	DefaultHistoryRetentionDays = 30
+
+	// Default output kept per history record in bytes
+	DefaultHistoryOutputBytes = 16 * 1024 // 16 KiB
 )
 
+// DefaultSandboxReadOnlyPaths are the host directories visible in the
+// process sandbox by default; missing ones are skipped
+var DefaultSandboxReadOnlyPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc"}
+
 // Config represents the application configuration
 type Config struct {
 	// Bot configuration
 	Bot BotConfig `mapstructure:"bot"`
 
+	// Executor backend selection; limits come from the Docker configuration
+	Executor ExecutorConfig `mapstructure:"executor"`
+
 	// Docker configuration
 	Docker DockerConfig `mapstructure:"docker"`
 
@@ -28,6 +78,12 @@ type Config struct {
 
 	// Server configuration
 	Server ServerConfig `mapstructure:"server"`
+
+	// Audit log configuration
+	Audit AuditConfig `mapstructure:"audit"`
+
+	// Embedded database configuration
+	Storage StorageConfig `mapstructure:"storage"`
 }
 
 // BotConfig holds Discord bot specific configuration
@@ -43,6 +99,303 @@ type BotConfig struct {
 
 	// Maximum concurrent command executions
 	MaxConcurrentCommands int `mapstructure:"max_concurrent_commands"`
+
+	// Maximum total bytes of uploaded files per execution, after archive extraction
+	MaxUploadBytes int `mapstructure:"max_upload_bytes"`
+
+	// Maximum number of uploaded files per execution, after archive extraction
+	MaxUploadFiles int `mapstructure:"max_upload_files"`
+
+	// Maximum live interactive sessions; each also holds an execution slot
+	MaxSessions int `mapstructure:"max_sessions"`
+
+	// Seconds after posting during which editing a prefix command reruns it
+	// and deleting it removes the result; 0 disables
+	EditWindow int `mapstructure:"edit_window"`
+
+	// Seconds running executions may take to finish on shutdown before
+	// they are stopped; queued ones are stopped right away
+	ShutdownGrace int `mapstructure:"shutdown_grace"`
+
+	// Who may run what; see PermissionsConfig
+	Permissions PermissionsConfig `mapstructure:"permissions"`
+
+	// How often and how much users may run code; see LimitsConfig
+	Limits LimitsConfig `mapstructure:"limits"`
+
+	// How execution slots are shared; see SchedulerConfig
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+
+	// What code may run; see PolicyConfig
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// How many snippets may be saved; see SnippetsConfig
+	Snippets SnippetsConfig `mapstructure:"snippets"`
+
+	// Recurring runs; see SchedulesConfig
+	Schedules SchedulesConfig `mapstructure:"schedules"`
+
+	// Command aliases and code templates; see MacrosConfig
+	Macros MacrosConfig `mapstructure:"macros"`
+}
+
+// MacrosConfig defines shorthands for the run command: aliases standing for
+// a command line, such as py for "run python", and templates wrapping code
+// run with --macro=<name>. Admins may add more to their guild with /alias
+// and /macro; those replace configured ones of the same name.
+type MacrosConfig struct {
+	// Aliases for every guild, from name to the command they stand for
+	// without the prefix; an alias may stand for another alias
+	Aliases map[string]string `mapstructure:"aliases"`
+
+	// Templates for every guild by name
+	Templates map[string]MacroTemplate `mapstructure:"templates"`
+
+	// Per-guild aliases and templates keyed by guild ID, replacing global
+	// ones of the same name
+	Guilds map[string]GuildMacros `mapstructure:"guilds"`
+
+	// Aliases and templates admins may add to one guild; 0 for no limit
+	MaxPerGuild int `mapstructure:"max_per_guild"`
+}
+
+// MacroTemplate wraps submitted code in a harness
+type MacroTemplate struct {
+	// Language of the template; code run with it must be in the same one
+	Language string `mapstructure:"language"`
+
+	// Source with {{code}} where the submitted code goes
+	Template string `mapstructure:"template"`
+}
+
+// GuildMacros holds the aliases and templates of one guild
+type GuildMacros struct {
+	Aliases   map[string]string        `mapstructure:"aliases"`
+	Templates map[string]MacroTemplate `mapstructure:"templates"`
+}
+
+// SchedulesConfig limits recurring runs. Scheduled runs go through the same
+// permissions, policy, rate limits, quotas and queue as their creator's own.
+type SchedulesConfig struct {
+	// Schedules a guild may have; 0 for no limit
+	MaxPerGuild int `mapstructure:"max_per_guild"`
+
+	// Shortest time between runs of one schedule in seconds
+	MinIntervalSeconds int `mapstructure:"min_interval_seconds"`
+
+	// Failed runs in a row after which a schedule is paused; 0 never pauses
+	MaxFailures int `mapstructure:"max_failures"`
+}
+
+// SnippetsConfig limits saved snippets, which are kept in the store
+// alongside execution history. Zero disables a limit.
+type SnippetsConfig struct {
+	// Snippet names a user may keep for themselves
+	MaxPerUser int `mapstructure:"max_per_user"`
+
+	// Snippet names shared in one guild
+	MaxPerGuild int `mapstructure:"max_per_guild"`
+
+	// Versions kept of each snippet; saving over a name adds a version
+	MaxVersions int `mapstructure:"max_versions"`
+}
+
+// PolicyConfig screens submitted code before a container is created.
+// Built-in rules always block fork bombs and crypto miners; the configured
+// rules are checked after them in order, then the allowlists.
+type PolicyConfig struct {
+	// Rules blocking, flagging or allowing code
+	Rules []PolicyRule `mapstructure:"rules"`
+
+	// Commands shell code may run; code running anything else is blocked
+	Allowlists []CommandAllowlist `mapstructure:"allowlists"`
+}
+
+// PolicyRule matches code by regular expression, by token, or both
+type PolicyRule struct {
+	// Name reported in logs and replies
+	Name string `mapstructure:"name"`
+
+	// Languages the rule applies to; empty for all
+	Languages []string `mapstructure:"languages"`
+
+	// Regular expression matched against the source
+	Pattern string `mapstructure:"pattern"`
+
+	// Words matched against the source's tokens and their base names,
+	// ignoring case, e.g. "nc" also matches "/usr/bin/nc"
+	Tokens []string `mapstructure:"tokens"`
+
+	// "block" refuses to run, "flag" runs but records the match, and
+	// "allow" exempts the code from the rules after this one
+	Action string `mapstructure:"action"`
+}
+
+// CommandAllowlist restricts the commands shell code may run
+type CommandAllowlist struct {
+	// Shell languages the allowlist applies to
+	Languages []string `mapstructure:"languages"`
+
+	// Command names, without directories
+	Commands []string `mapstructure:"commands"`
+}
+
+// SchedulerConfig shares execution slots fairly: guilds get equal shares,
+// users get equal shares of their guild's, and waiting requests are served
+// least-served first
+type SchedulerConfig struct {
+	// Slots one user may hold at once; 0 is unlimited
+	MaxPerUser int `mapstructure:"max_per_user"`
+
+	// Slots one guild may hold at once; 0 is unlimited
+	MaxPerGuild int `mapstructure:"max_per_guild"`
+
+	// Seconds a request may wait before it is served ahead of its fair
+	// turn; 0 disables starvation protection
+	StarvationSeconds int `mapstructure:"starvation_seconds"`
+
+	// Larger shares of their guild's slots for members of some roles;
+	// members with several matching roles get the largest weight
+	Priorities []PriorityRole `mapstructure:"priorities"`
+}
+
+// PriorityRole weights the share of members of some roles
+type PriorityRole struct {
+	// Role IDs the weight applies to
+	Roles []string `mapstructure:"roles"`
+
+	// Share relative to other members, who have weight 1
+	Weight float64 `mapstructure:"weight"`
+}
+
+// LimitsConfig throttles runs with token buckets per user, channel and
+// guild, and caps the CPU time each user may use per UTC day
+type LimitsConfig struct {
+	// Runs per user
+	User RateLimit `mapstructure:"user"`
+
+	// Runs per channel
+	Channel RateLimit `mapstructure:"channel"`
+
+	// Runs per guild
+	Guild RateLimit `mapstructure:"guild"`
+
+	// CPU seconds each user may use per day; 0 disables the quota
+	DailyCPUSeconds int `mapstructure:"daily_cpu_seconds"`
+
+	// Per-role overrides of the user limits; members with several matching
+	// roles get the most generous limits
+	Roles []RoleLimits `mapstructure:"roles"`
+}
+
+// RateLimit is a token bucket refilled at a steady rate
+type RateLimit struct {
+	// Runs that may start back to back; 0 disables the limit
+	Burst int `mapstructure:"burst"`
+
+	// Runs regained per minute
+	PerMinute float64 `mapstructure:"per_minute"`
+}
+
+// RoleLimits overrides the user limits for members of some roles
+type RoleLimits struct {
+	// Role IDs the override applies to
+	Roles []string `mapstructure:"roles"`
+
+	// Replaces the per-user rate limit when set
+	User *RateLimit `mapstructure:"user"`
+
+	// Replaces the daily CPU quota when set; 0 means unlimited
+	DailyCPUSeconds *int `mapstructure:"daily_cpu_seconds"`
+}
+
+// PermissionsConfig controls who may run what. A user's permissions combine
+// the default grant with the grants of every rule matching them or one of
+// their roles.
+type PermissionsConfig struct {
+	// Grant for everyone
+	Default PermissionGrant `mapstructure:"default"`
+
+	// Additional grants for specific roles and users
+	Rules []PermissionRule `mapstructure:"rules"`
+
+	// Per-guild overrides keyed by guild ID
+	Guilds map[string]GuildPermissions `mapstructure:"guilds"`
+}
+
+// PermissionGrant lists what a user may do; empty fields grant nothing
+type PermissionGrant struct {
+	// Languages that may be run; "*" allows all
+	Languages []string `mapstructure:"languages"`
+
+	// Highest resource tier that may be requested (small, standard, large)
+	MaxTier string `mapstructure:"max_tier"`
+
+	// Network modes that may be requested (none, isolated, internet)
+	Network []string `mapstructure:"network"`
+
+	// Admin commands that may be used on other users' runs (cancel,
+	// delete), shared snippets (snippets), schedules (schedules) or the
+	// guild's aliases and templates (macros); "*" allows all
+	Admin []string `mapstructure:"admin"`
+}
+
+// PermissionRule grants permissions to roles and users
+type PermissionRule struct {
+	// Role IDs the rule applies to
+	Roles []string `mapstructure:"roles"`
+
+	// User IDs the rule applies to
+	Users []string `mapstructure:"users"`
+
+	PermissionGrant `mapstructure:",squash"`
+}
+
+// GuildPermissions overrides permissions within one guild
+type GuildPermissions struct {
+	// Drops the default grant, so only users matching a rule may run code
+	Lockdown bool `mapstructure:"lockdown"`
+
+	// Replaces the global default grant when set
+	Default *PermissionGrant `mapstructure:"default"`
+
+	// Rules applied in addition to the global rules
+	Rules []PermissionRule `mapstructure:"rules"`
+}
+
+// Executor backends
+const (
+	// Docker containers
+	BackendDocker = "docker"
+
+	// Linux process sandbox built from namespaces, cgroups v2 and rlimits
+	BackendSandbox = "sandbox"
+)
+
+// ExecutorConfig selects the backend code runs on. Every backend applies
+// the limits from DockerConfig.
+type ExecutorConfig struct {
+	// Backend running executions: docker or sandbox
+	Backend string `mapstructure:"backend"`
+
+	// Process sandbox configuration
+	Sandbox SandboxConfig `mapstructure:"sandbox"`
+}
+
+// SandboxConfig holds configuration for the Linux process sandbox backend
+type SandboxConfig struct {
+	// Directory scratch space for executions is created in; empty uses the
+	// system temporary directory
+	Dir string `mapstructure:"dir"`
+
+	// Delegated cgroup v2 directory executions get child cgroups in, which
+	// enforce memory, CPU and process limits; empty falls back to rlimits,
+	// which cannot limit CPU usage
+	CgroupRoot string `mapstructure:"cgroup_root"`
+
+	// Host directories mounted read-only in the sandbox; they must hold the
+	// language toolchains and the libraries they load
+	ReadOnlyPaths []string `mapstructure:"read_only_paths"`
 }
 
 // DockerConfig holds Docker runtime configuration
@@ -53,6 +406,14 @@ type DockerConfig struct {
 	// Network name for containers
 	NetworkName string `mapstructure:"network_name"`
 
+	// Identifies this bot's containers, volumes and networks; bots sharing a
+	// Docker daemon need distinct IDs
+	InstanceID string `mapstructure:"instance_id"`
+
+	// Seconds between removals of resources left without a live execution;
+	// 0 only removes them at startup
+	ReconcileInterval int `mapstructure:"reconcile_interval"`
+
 	// CPU limit for containers (as fraction of CPU)
 	CPULimit float64 `mapstructure:"cpu_limit"`
 
@@ -64,6 +425,30 @@ type DockerConfig struct {
 
 	// Memory limit for containers (in MB)
 	MemoryLimit int `mapstructure:"memory_limit"`
+
+	// Timeout for the compile phase of compiled languages (in seconds)
+	CompileTimeout int `mapstructure:"compile_timeout"`
+
+	// Memory limit for the compile phase of compiled languages (in MB)
+	CompileMemoryLimit int `mapstructure:"compile_memory_limit"`
+
+	// Maximum combined stdout/stderr bytes captured per execution
+	MaxOutputBytes int `mapstructure:"max_output_bytes"`
+
+	// Maximum bytes accepted as standard input per execution
+	MaxStdinBytes int `mapstructure:"max_stdin_bytes"`
+
+	// Seconds without input after which an interactive session ends
+	SessionIdleTimeout int `mapstructure:"session_idle_timeout"`
+
+	// Maximum number of files returned from the output directory
+	MaxArtifacts int `mapstructure:"max_artifacts"`
+
+	// Maximum total bytes of files returned from the output directory
+	MaxArtifactBytes int `mapstructure:"max_artifact_bytes"`
+
+	// Maximum bytes of the workspace carried from the compile phase to the run phase
+	MaxWorkspaceBytes int `mapstructure:"max_workspace_bytes"`
 }
 
 // LoggingConfig holds logging configuration
@@ -96,6 +481,37 @@ type ServerConfig struct {
 	WriteTimeout int `mapstructure:"write_timeout"`
 }
 
+// AuditConfig holds audit log configuration. Every execution is recorded
+// in each configured store as an entry chained to the previous one by hash.
+type AuditConfig struct {
+	// JSON Lines file entries are appended to; empty disables it
+	File string `mapstructure:"file"`
+
+	// SQLite database entries are also stored in; empty disables it
+	Database string `mapstructure:"database"`
+}
+
+// StorageConfig holds the embedded database keeping execution history
+type StorageConfig struct {
+	// SQLite database path; empty disables history
+	Path string `mapstructure:"path"`
+
+	// How long and how much history is kept; see HistoryConfig
+	History HistoryConfig `mapstructure:"history"`
+}
+
+// HistoryConfig holds the retention policy of execution history
+type HistoryConfig struct {
+	// Days a run is kept; 0 keeps runs until the per-user limit removes them
+	RetentionDays int `mapstructure:"retention_days"`
+
+	// Most recent runs kept per user; 0 for no limit
+	MaxPerUser int `mapstructure:"max_per_user"`
+
+	// Output kept per run in bytes; longer output is cut
+	MaxOutputBytes int `mapstructure:"max_output_bytes"`
+}
+
 // Load loads configuration from environment variables, config files, and CLI flags
 func Load() (*Config, error) {
 	// Set default configuration values
@@ -120,12 +536,48 @@ func Load() (*Config, error) {
 		"bot.prefix",
 		"bot.guild_id",
 		"bot.max_concurrent_commands",
+		"bot.max_upload_bytes",
+		"bot.max_upload_files",
+		"bot.max_sessions",
+		"bot.edit_window",
+		"bot.shutdown_grace",
+		"bot.permissions.default.max_tier",
+		"bot.limits.user.burst",
+		"bot.limits.user.per_minute",
+		"bot.limits.channel.burst",
+		"bot.limits.channel.per_minute",
+		"bot.limits.guild.burst",
+		"bot.limits.guild.per_minute",
+		"bot.limits.daily_cpu_seconds",
+		"bot.scheduler.max_per_user",
+		"bot.scheduler.max_per_guild",
+		"bot.scheduler.starvation_seconds",
+		"bot.snippets.max_per_user",
+		"bot.snippets.max_per_guild",
+		"bot.snippets.max_versions",
+		"bot.schedules.max_per_guild",
+		"bot.schedules.min_interval_seconds",
+		"bot.schedules.max_failures",
+		"bot.macros.max_per_guild",
+		"executor.backend",
+		"executor.sandbox.dir",
+		"executor.sandbox.cgroup_root",
 		"docker.host",
 		"docker.default_timeout",
 		"docker.max_runtime",
 		"docker.memory_limit",
 		"docker.cpu_limit",
+		"docker.compile_timeout",
+		"docker.compile_memory_limit",
 		"docker.network_name",
+		"docker.instance_id",
+		"docker.reconcile_interval",
+		"docker.max_output_bytes",
+		"docker.max_stdin_bytes",
+		"docker.session_idle_timeout",
+		"docker.max_artifacts",
+		"docker.max_artifact_bytes",
+		"docker.max_workspace_bytes",
 		"logging.level",
 		"logging.format",
 		"logging.output_file",
@@ -134,6 +586,12 @@ func Load() (*Config, error) {
 		"server.port",
 		"server.read_timeout",
 		"server.write_timeout",
+		"audit.file",
+		"audit.database",
+		"storage.path",
+		"storage.history.retention_days",
+		"storage.history.max_per_user",
+		"storage.history.max_output_bytes",
 	}
 
 	for _, key := range envBindings {
@@ -172,6 +630,35 @@ func setDefaults() {
 	// Bot defaults
 	viper.SetDefault("bot.prefix", "!")
 	viper.SetDefault("bot.max_concurrent_commands", 10)
+	viper.SetDefault("bot.max_upload_bytes", DefaultMaxUploadBytes)
+	viper.SetDefault("bot.max_upload_files", 20)
+	viper.SetDefault("bot.max_sessions", 2)
+	viper.SetDefault("bot.edit_window", DefaultEditWindow)
+	viper.SetDefault("bot.shutdown_grace", DefaultShutdownGrace)
+	viper.SetDefault("bot.permissions.default.languages", []string{"*"})
+	viper.SetDefault("bot.permissions.default.max_tier", "standard")
+	viper.SetDefault("bot.permissions.default.network", []string{"none", "isolated"})
+	viper.SetDefault("bot.limits.user.burst", 3)
+	viper.SetDefault("bot.limits.user.per_minute", 6)
+	viper.SetDefault("bot.limits.channel.burst", 10)
+	viper.SetDefault("bot.limits.channel.per_minute", 20)
+	viper.SetDefault("bot.limits.guild.burst", 30)
+	viper.SetDefault("bot.limits.guild.per_minute", 60)
+	viper.SetDefault("bot.limits.daily_cpu_seconds", DefaultDailyCPUSeconds)
+	viper.SetDefault("bot.scheduler.max_per_user", 2)
+	viper.SetDefault("bot.scheduler.max_per_guild", 0)
+	viper.SetDefault("bot.scheduler.starvation_seconds", DefaultStarvationSeconds)
+	viper.SetDefault("bot.snippets.max_per_user", 50)
+	viper.SetDefault("bot.snippets.max_per_guild", 200)
+	viper.SetDefault("bot.snippets.max_versions", 10)
+	viper.SetDefault("bot.schedules.max_per_guild", 10)
+	viper.SetDefault("bot.schedules.min_interval_seconds", DefaultScheduleIntervalSeconds)
+	viper.SetDefault("bot.schedules.max_failures", 3)
+	viper.SetDefault("bot.macros.max_per_guild", 50)
+
+	// Executor defaults
+	viper.SetDefault("executor.backend", BackendDocker)
+	viper.SetDefault("executor.sandbox.read_only_paths", DefaultSandboxReadOnlyPaths)
 
 	// Docker defaults
 	viper.SetDefault("docker.host", "unix:///var/run/docker.sock")
@@ -179,7 +666,17 @@ func setDefaults() {
 	viper.SetDefault("docker.max_runtime", DefaultMaxRuntime)
 	viper.SetDefault("docker.memory_limit", 128) // 128 MB
 	viper.SetDefault("docker.cpu_limit", 0.5)    // 50% of one CPU
+	viper.SetDefault("docker.compile_timeout", DefaultCompileTimeout)
+	viper.SetDefault("docker.compile_memory_limit", 512) // 512 MB
 	viper.SetDefault("docker.network_name", "discord-executor")
+	viper.SetDefault("docker.instance_id", "default")
+	viper.SetDefault("docker.reconcile_interval", DefaultReconcileInterval)
+	viper.SetDefault("docker.max_output_bytes", DefaultMaxOutputBytes)
+	viper.SetDefault("docker.max_stdin_bytes", DefaultMaxStdinBytes)
+	viper.SetDefault("docker.session_idle_timeout", DefaultSessionIdleTimeout)
+	viper.SetDefault("docker.max_artifacts", 5)
+	viper.SetDefault("docker.max_artifact_bytes", DefaultMaxArtifactBytes)
+	viper.SetDefault("docker.max_workspace_bytes", DefaultMaxWorkspaceBytes)
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
@@ -191,4 +688,13 @@ func setDefaults() {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.read_timeout", 10)
 	viper.SetDefault("server.write_timeout", 10)
+
+	// Audit defaults
+	viper.SetDefault("audit.file", "audit.jsonl")
+
+	// Storage defaults
+	viper.SetDefault("storage.path", "data.db")
+	viper.SetDefault("storage.history.retention_days", DefaultHistoryRetentionDays)
+	viper.SetDefault("storage.history.max_per_user", 200)
+	viper.SetDefault("storage.history.max_output_bytes", DefaultHistoryOutputBytes)
 }
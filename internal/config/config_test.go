@@ -25,12 +25,48 @@ func TestLoadConfigDefaults(t *testing.T) {
 	// Set default configuration values
 	v.SetDefault("bot.prefix", "!")
 	v.SetDefault("bot.max_concurrent_commands", 10)
+	v.SetDefault("bot.max_upload_bytes", 1048576)
+	v.SetDefault("bot.max_upload_files", 20)
+	v.SetDefault("bot.max_sessions", 2)
+	v.SetDefault("bot.edit_window", 300)
+	v.SetDefault("bot.shutdown_grace", 30)
+	v.SetDefault("bot.permissions.default.languages", []string{"*"})
+	v.SetDefault("bot.permissions.default.max_tier", "standard")
+	v.SetDefault("bot.permissions.default.network", []string{"none", "isolated"})
+	v.SetDefault("bot.limits.user.burst", 3)
+	v.SetDefault("bot.limits.user.per_minute", 6)
+	v.SetDefault("bot.limits.channel.burst", 10)
+	v.SetDefault("bot.limits.channel.per_minute", 20)
+	v.SetDefault("bot.limits.guild.burst", 30)
+	v.SetDefault("bot.limits.guild.per_minute", 60)
+	v.SetDefault("bot.limits.daily_cpu_seconds", 900)
+	v.SetDefault("bot.scheduler.max_per_user", 2)
+	v.SetDefault("bot.scheduler.max_per_guild", 0)
+	v.SetDefault("bot.scheduler.starvation_seconds", 60)
+	v.SetDefault("bot.snippets.max_per_user", 50)
+	v.SetDefault("bot.snippets.max_per_guild", 200)
+	v.SetDefault("bot.snippets.max_versions", 10)
+	v.SetDefault("bot.schedules.max_per_guild", 10)
+	v.SetDefault("bot.schedules.min_interval_seconds", 300)
+	v.SetDefault("bot.schedules.max_failures", 3)
+	v.SetDefault("bot.macros.max_per_guild", 50)
+	v.SetDefault("executor.backend", "docker")
 	v.SetDefault("docker.host", "unix:///var/run/docker.sock")
 	v.SetDefault("docker.default_timeout", 30)
 	v.SetDefault("docker.max_runtime", 300)
 	v.SetDefault("docker.memory_limit", 128)
 	v.SetDefault("docker.cpu_limit", 0.5)
+	v.SetDefault("docker.compile_timeout", 60)
+	v.SetDefault("docker.compile_memory_limit", 512)
 	v.SetDefault("docker.network_name", "discord-executor")
+	v.SetDefault("docker.instance_id", "default")
+	v.SetDefault("docker.reconcile_interval", 300)
+	v.SetDefault("docker.max_output_bytes", 65536)
+	v.SetDefault("docker.max_stdin_bytes", 65536)
+	v.SetDefault("docker.session_idle_timeout", 300)
+	v.SetDefault("docker.max_artifacts", 5)
+	v.SetDefault("docker.max_artifact_bytes", 8388608)
+	v.SetDefault("docker.max_workspace_bytes", 268435456)
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "text")
 	v.SetDefault("logging.report_caller", false)
@@ -38,6 +74,11 @@ func TestLoadConfigDefaults(t *testing.T) {
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.read_timeout", 10)
 	v.SetDefault("server.write_timeout", 10)
+	v.SetDefault("audit.file", "audit.jsonl")
+	v.SetDefault("storage.path", "data.db")
+	v.SetDefault("storage.history.retention_days", 30)
+	v.SetDefault("storage.history.max_per_user", 200)
+	v.SetDefault("storage.history.max_output_bytes", 16384)
 
 	// Configure Viper
 	v.SetConfigName("config")
@@ -78,6 +119,10 @@ func TestLoadConfigDefaults(t *testing.T) {
 		t.Errorf("Expected default max concurrent commands 10, got %d", config.Bot.MaxConcurrentCommands)
 	}
 
+	if config.Executor.Backend != "docker" {
+		t.Errorf("Expected default backend 'docker', got '%s'", config.Executor.Backend)
+	}
+
 	if config.Docker.Host != "unix:///var/run/docker.sock" {
 		t.Errorf("Expected default Docker host 'unix:///var/run/docker.sock', got '%s'", config.Docker.Host)
 	}
@@ -89,6 +134,38 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if config.Server.Port != 8080 {
 		t.Errorf("Expected default server port 8080, got %d", config.Server.Port)
 	}
+
+	if config.Bot.Permissions.Default.MaxTier != "standard" {
+		t.Errorf("Expected default max tier 'standard', got '%s'", config.Bot.Permissions.Default.MaxTier)
+	}
+	if config.Audit.File != "audit.jsonl" {
+		t.Errorf("Expected default audit file 'audit.jsonl', got '%s'", config.Audit.File)
+	}
+	if config.Bot.Scheduler.MaxPerUser != 2 {
+		t.Errorf("Expected default max per user 2, got %d", config.Bot.Scheduler.MaxPerUser)
+	}
+	if config.Bot.Snippets.MaxVersions != 10 {
+		t.Errorf("Expected default snippet versions 10, got %d", config.Bot.Snippets.MaxVersions)
+	}
+	if config.Bot.Schedules.MaxPerGuild != 10 {
+		t.Errorf("Expected default schedules per guild 10, got %d", config.Bot.Schedules.MaxPerGuild)
+	}
+	if config.Bot.ShutdownGrace != 30 {
+		t.Errorf("Expected default shutdown grace 30, got %d", config.Bot.ShutdownGrace)
+	}
+	if config.Bot.Macros.MaxPerGuild != 50 {
+		t.Errorf("Expected default macros per guild 50, got %d", config.Bot.Macros.MaxPerGuild)
+	}
+	if config.Docker.InstanceID != "default" || config.Docker.ReconcileInterval != 300 {
+		t.Errorf("Expected default instance 'default' reconciled every 300s, got '%s', %d",
+			config.Docker.InstanceID, config.Docker.ReconcileInterval)
+	}
+	if config.Storage.History.RetentionDays != 30 {
+		t.Errorf("Expected default history retention 30 days, got %d", config.Storage.History.RetentionDays)
+	}
+	if config.Docker.MaxWorkspaceBytes != 268435456 {
+		t.Errorf("Expected default max workspace bytes 268435456, got %d", config.Docker.MaxWorkspaceBytes)
+	}
 }
 
 func TestValidateRequiredFields(t *testing.T) {
@@ -104,14 +181,28 @@ func TestValidateRequiredFields(t *testing.T) {
 					Token:                 "valid.test.token.for.unit.testing.purposes.only.not.real",
 					Prefix:                "!",
 					MaxConcurrentCommands: 5,
+					MaxUploadBytes:        1048576,
+					MaxUploadFiles:        20,
+					MaxSessions:           2,
+					EditWindow:            300,
 				},
+				Executor: ExecutorConfig{Backend: BackendDocker},
 				Docker: DockerConfig{
-					Host:           "unix:///var/run/docker.sock",
-					DefaultTimeout: 30,
-					MaxRuntime:     300,
-					MemoryLimit:    128,
-					CPULimit:       0.5,
-					NetworkName:    "test-network",
+					Host:               "unix:///var/run/docker.sock",
+					DefaultTimeout:     30,
+					MaxRuntime:         300,
+					MemoryLimit:        128,
+					CPULimit:           0.5,
+					CompileTimeout:     60,
+					CompileMemoryLimit: 512,
+					NetworkName:        "test-network",
+					InstanceID:         "test",
+					MaxOutputBytes:     65536,
+					MaxStdinBytes:      65536,
+					SessionIdleTimeout: 300,
+					MaxArtifacts:       5,
+					MaxArtifactBytes:   8388608,
+					MaxWorkspaceBytes:  268435456,
 				},
 				Logging: LoggingConfig{
 					Level:  "info",
@@ -132,14 +223,28 @@ func TestValidateRequiredFields(t *testing.T) {
 				Bot: BotConfig{
 					Prefix:                "!",
 					MaxConcurrentCommands: 5,
+					MaxUploadBytes:        1048576,
+					MaxUploadFiles:        20,
+					MaxSessions:           2,
+					EditWindow:            300,
 				},
+				Executor: ExecutorConfig{Backend: BackendDocker},
 				Docker: DockerConfig{
-					Host:           "unix:///var/run/docker.sock",
-					DefaultTimeout: 30,
-					MaxRuntime:     300,
-					MemoryLimit:    128,
-					CPULimit:       0.5,
-					NetworkName:    "test-network",
+					Host:               "unix:///var/run/docker.sock",
+					DefaultTimeout:     30,
+					MaxRuntime:         300,
+					MemoryLimit:        128,
+					CPULimit:           0.5,
+					CompileTimeout:     60,
+					CompileMemoryLimit: 512,
+					NetworkName:        "test-network",
+					InstanceID:         "test",
+					MaxOutputBytes:     65536,
+					MaxStdinBytes:      65536,
+					SessionIdleTimeout: 300,
+					MaxArtifacts:       5,
+					MaxArtifactBytes:   8388608,
+					MaxWorkspaceBytes:  268435456,
 				},
 				Logging: LoggingConfig{
 					Level:  "info",
@@ -161,14 +266,28 @@ func TestValidateRequiredFields(t *testing.T) {
 					Token:                 "valid.test.token.for.unit.testing.purposes.only.not.real",
 					Prefix:                "!",
 					MaxConcurrentCommands: 5,
+					MaxUploadBytes:        1048576,
+					MaxUploadFiles:        20,
+					MaxSessions:           2,
+					EditWindow:            300,
 				},
+				Executor: ExecutorConfig{Backend: BackendDocker},
 				Docker: DockerConfig{
-					Host:           "unix:///var/run/docker.sock",
-					DefaultTimeout: 30,
-					MaxRuntime:     300,
-					MemoryLimit:    128,
-					CPULimit:       0.5,
-					NetworkName:    "test-network",
+					Host:               "unix:///var/run/docker.sock",
+					DefaultTimeout:     30,
+					MaxRuntime:         300,
+					MemoryLimit:        128,
+					CPULimit:           0.5,
+					CompileTimeout:     60,
+					CompileMemoryLimit: 512,
+					NetworkName:        "test-network",
+					InstanceID:         "test",
+					MaxOutputBytes:     65536,
+					MaxStdinBytes:      65536,
+					SessionIdleTimeout: 300,
+					MaxArtifacts:       5,
+					MaxArtifactBytes:   8388608,
+					MaxWorkspaceBytes:  268435456,
 				},
 				Logging: LoggingConfig{
 					Level:  "invalid",
@@ -243,3 +362,647 @@ func TestEnvironmentVariableOverrides(t *testing.T) {
 		t.Errorf("Expected log level from env 'debug', got '%s'", config.Logging.Level)
 	}
 }
+
+func TestValidateOutputLimit(t *testing.T) {
+	base := DockerConfig{
+		Host:               "unix:///var/run/docker.sock",
+		DefaultTimeout:     30,
+		MaxRuntime:         300,
+		MemoryLimit:        128,
+		CPULimit:           0.5,
+		CompileTimeout:     60,
+		CompileMemoryLimit: 512,
+		SessionIdleTimeout: DefaultSessionIdleTimeout,
+		NetworkName:        "test-network",
+		InstanceID:         "test",
+		MaxStdinBytes:      DefaultMaxStdinBytes,
+		MaxWorkspaceBytes:  DefaultMaxWorkspaceBytes,
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		shouldErr bool
+	}{
+		{name: "default", limit: DefaultMaxOutputBytes, shouldErr: false},
+		{name: "too small", limit: 100, shouldErr: true},
+		{name: "too large", limit: MaxOutputBytesLimit + 1, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.MaxOutputBytes = tt.limit
+			err := validateDockerConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateStdinLimit(t *testing.T) {
+	base := DockerConfig{
+		Host:               "unix:///var/run/docker.sock",
+		DefaultTimeout:     30,
+		MaxRuntime:         300,
+		MemoryLimit:        128,
+		CPULimit:           0.5,
+		CompileTimeout:     60,
+		CompileMemoryLimit: 512,
+		SessionIdleTimeout: DefaultSessionIdleTimeout,
+		NetworkName:        "test-network",
+		InstanceID:         "test",
+		MaxOutputBytes:     DefaultMaxOutputBytes,
+		MaxWorkspaceBytes:  DefaultMaxWorkspaceBytes,
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		shouldErr bool
+	}{
+		{name: "default", limit: DefaultMaxStdinBytes, shouldErr: false},
+		{name: "too small", limit: 0, shouldErr: true},
+		{name: "too large", limit: MaxStdinBytesLimit + 1, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.MaxStdinBytes = tt.limit
+			err := validateDockerConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateWorkspaceLimit(t *testing.T) {
+	base := DockerConfig{
+		Host:               "unix:///var/run/docker.sock",
+		DefaultTimeout:     30,
+		MaxRuntime:         300,
+		MemoryLimit:        128,
+		CPULimit:           0.5,
+		CompileTimeout:     60,
+		CompileMemoryLimit: 512,
+		SessionIdleTimeout: DefaultSessionIdleTimeout,
+		NetworkName:        "test-network",
+		InstanceID:         "test",
+		MaxOutputBytes:     DefaultMaxOutputBytes,
+		MaxStdinBytes:      DefaultMaxStdinBytes,
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		shouldErr bool
+	}{
+		{name: "default", limit: DefaultMaxWorkspaceBytes, shouldErr: false},
+		{name: "too small", limit: 1024, shouldErr: true},
+		{name: "too large", limit: MaxWorkspaceBytesLimit + 1, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.MaxWorkspaceBytes = tt.limit
+			err := validateDockerConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateReconcile(t *testing.T) {
+	base := DockerConfig{
+		Host:               "unix:///var/run/docker.sock",
+		DefaultTimeout:     30,
+		MaxRuntime:         300,
+		MemoryLimit:        128,
+		CPULimit:           0.5,
+		CompileTimeout:     60,
+		CompileMemoryLimit: 512,
+		SessionIdleTimeout: DefaultSessionIdleTimeout,
+		NetworkName:        "test-network",
+		MaxOutputBytes:     DefaultMaxOutputBytes,
+		MaxStdinBytes:      DefaultMaxStdinBytes,
+		MaxWorkspaceBytes:  DefaultMaxWorkspaceBytes,
+	}
+
+	tests := []struct {
+		name      string
+		instance  string
+		interval  int
+		shouldErr bool
+	}{
+		{name: "default", instance: "default", interval: DefaultReconcileInterval, shouldErr: false},
+		{name: "startup only", instance: "bot-1.eu_west", interval: 0, shouldErr: false},
+		{name: "empty instance", instance: "", interval: 300, shouldErr: true},
+		{name: "instance with spaces", instance: "bot 1", interval: 300, shouldErr: true},
+		{name: "instance with equals", instance: "a=b", interval: 300, shouldErr: true},
+		{name: "too frequent", instance: "default", interval: 5, shouldErr: true},
+		{name: "negative", instance: "default", interval: -1, shouldErr: true},
+		{name: "too rare", instance: "default", interval: MaxReconcileInterval + 1, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.InstanceID = tt.instance
+			cfg.ReconcileInterval = tt.interval
+			err := validateDockerConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateExecutor(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    ExecutorConfig
+		shouldErr bool
+	}{
+		{name: "docker", config: ExecutorConfig{Backend: BackendDocker}, shouldErr: false},
+		{
+			name: "sandbox",
+			config: ExecutorConfig{Backend: BackendSandbox, Sandbox: SandboxConfig{
+				Dir:           "/var/lib/dce",
+				CgroupRoot:    "/sys/fs/cgroup/dce",
+				ReadOnlyPaths: DefaultSandboxReadOnlyPaths,
+			}},
+			shouldErr: false,
+		},
+		{name: "unknown backend", config: ExecutorConfig{Backend: "podman"}, shouldErr: true},
+		{name: "empty backend", config: ExecutorConfig{}, shouldErr: true},
+		{
+			name:      "relative dir",
+			config:    ExecutorConfig{Backend: BackendSandbox, Sandbox: SandboxConfig{Dir: "scratch"}},
+			shouldErr: true,
+		},
+		{
+			name:      "relative cgroup",
+			config:    ExecutorConfig{Backend: BackendSandbox, Sandbox: SandboxConfig{CgroupRoot: "dce"}},
+			shouldErr: true,
+		},
+		{
+			name:      "host root",
+			config:    ExecutorConfig{Backend: BackendSandbox, Sandbox: SandboxConfig{ReadOnlyPaths: []string{"/"}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExecutorConfig(&tt.config)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMaxSessions(t *testing.T) {
+	base := BotConfig{
+		Token:                 "valid.test.token.for.unit.testing.purposes.only.not.real",
+		Prefix:                "!",
+		MaxConcurrentCommands: 4,
+		MaxUploadBytes:        DefaultMaxUploadBytes,
+		MaxUploadFiles:        20,
+	}
+
+	tests := []struct {
+		name      string
+		sessions  int
+		shouldErr bool
+	}{
+		{name: "disabled", sessions: 0, shouldErr: false},
+		{name: "within slots", sessions: 4, shouldErr: false},
+		{name: "negative", sessions: -1, shouldErr: true},
+		{name: "more than slots", sessions: 5, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.MaxSessions = tt.sessions
+			err := validateBotConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateShutdownGrace(t *testing.T) {
+	base := BotConfig{
+		Token:                 "valid.test.token.for.unit.testing.purposes.only.not.real",
+		Prefix:                "!",
+		MaxConcurrentCommands: 4,
+		MaxUploadBytes:        DefaultMaxUploadBytes,
+		MaxUploadFiles:        20,
+	}
+
+	tests := []struct {
+		name      string
+		grace     int
+		shouldErr bool
+	}{
+		{name: "immediate", grace: 0, shouldErr: false},
+		{name: "default", grace: DefaultShutdownGrace, shouldErr: false},
+		{name: "negative", grace: -1, shouldErr: true},
+		{name: "over an hour", grace: 3601, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.ShutdownGrace = tt.grace
+			err := validateBotConfig(&cfg)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions PermissionsConfig
+		shouldErr   bool
+	}{
+		{name: "empty", permissions: PermissionsConfig{}, shouldErr: false},
+		{
+			name: "valid rules and guild override",
+			permissions: PermissionsConfig{
+				Default: PermissionGrant{Languages: []string{"*"}, MaxTier: "standard", Network: []string{"isolated"}},
+				Rules: []PermissionRule{{
+					Roles:           []string{"123"},
+					PermissionGrant: PermissionGrant{MaxTier: "large", Network: []string{"internet"}, Admin: []string{"*"}},
+				}},
+				Guilds: map[string]GuildPermissions{
+					"456": {Lockdown: true},
+					"789": {Default: &PermissionGrant{MaxTier: "small"}},
+				},
+			},
+			shouldErr: false,
+		},
+		{name: "unknown tier", permissions: PermissionsConfig{Default: PermissionGrant{MaxTier: "huge"}}, shouldErr: true},
+		{
+			name:        "unknown network mode",
+			permissions: PermissionsConfig{Default: PermissionGrant{Network: []string{"host"}}},
+			shouldErr:   true,
+		},
+		{
+			name:        "unknown admin command",
+			permissions: PermissionsConfig{Default: PermissionGrant{Admin: []string{"ban"}}},
+			shouldErr:   true,
+		},
+		{
+			name:        "rule without subjects",
+			permissions: PermissionsConfig{Rules: []PermissionRule{{PermissionGrant: PermissionGrant{MaxTier: "large"}}}},
+			shouldErr:   true,
+		},
+		{
+			name: "default grant while locked down",
+			permissions: PermissionsConfig{Guilds: map[string]GuildPermissions{
+				"456": {Lockdown: true, Default: &PermissionGrant{MaxTier: "small"}},
+			}},
+			shouldErr: true,
+		},
+		{
+			name: "invalid guild rule",
+			permissions: PermissionsConfig{Guilds: map[string]GuildPermissions{
+				"456": {Rules: []PermissionRule{{Users: []string{"1"}, PermissionGrant: PermissionGrant{MaxTier: "huge"}}}},
+			}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePermissionsConfig(&tt.permissions)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	unlimited := 0
+	negative := -1
+
+	tests := []struct {
+		name      string
+		limits    LimitsConfig
+		shouldErr bool
+	}{
+		{name: "disabled", limits: LimitsConfig{}, shouldErr: false},
+		{
+			name: "valid with role override",
+			limits: LimitsConfig{
+				User:            RateLimit{Burst: 3, PerMinute: 6},
+				Guild:           RateLimit{Burst: 30, PerMinute: 60},
+				DailyCPUSeconds: 900,
+				Roles: []RoleLimits{{
+					Roles:           []string{"123"},
+					User:            &RateLimit{Burst: 10, PerMinute: 30},
+					DailyCPUSeconds: &unlimited,
+				}},
+			},
+			shouldErr: false,
+		},
+		{name: "negative burst", limits: LimitsConfig{Channel: RateLimit{Burst: -1}}, shouldErr: true},
+		{name: "burst without refill", limits: LimitsConfig{User: RateLimit{Burst: 3}}, shouldErr: true},
+		{name: "negative quota", limits: LimitsConfig{DailyCPUSeconds: -1}, shouldErr: true},
+		{name: "quota over a day", limits: LimitsConfig{DailyCPUSeconds: 86401}, shouldErr: true},
+		{name: "role override without roles", limits: LimitsConfig{Roles: []RoleLimits{{}}}, shouldErr: true},
+		{
+			name:      "invalid role quota",
+			limits:    LimitsConfig{Roles: []RoleLimits{{Roles: []string{"123"}, DailyCPUSeconds: &negative}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLimitsConfig(&tt.limits)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateScheduler(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler SchedulerConfig
+		shouldErr bool
+	}{
+		{name: "unlimited", scheduler: SchedulerConfig{}, shouldErr: false},
+		{
+			name: "valid with priorities",
+			scheduler: SchedulerConfig{
+				MaxPerUser:        2,
+				MaxPerGuild:       5,
+				StarvationSeconds: 60,
+				Priorities:        []PriorityRole{{Roles: []string{"123"}, Weight: 3}},
+			},
+			shouldErr: false,
+		},
+		{name: "negative user cap", scheduler: SchedulerConfig{MaxPerUser: -1}, shouldErr: true},
+		{name: "user cap over slots", scheduler: SchedulerConfig{MaxPerUser: 11}, shouldErr: true},
+		{name: "guild cap over slots", scheduler: SchedulerConfig{MaxPerGuild: 11}, shouldErr: true},
+		{name: "starvation over an hour", scheduler: SchedulerConfig{StarvationSeconds: 3601}, shouldErr: true},
+		{
+			name:      "priority without roles",
+			scheduler: SchedulerConfig{Priorities: []PriorityRole{{Weight: 2}}},
+			shouldErr: true,
+		},
+		{
+			name:      "priority below 1",
+			scheduler: SchedulerConfig{Priorities: []PriorityRole{{Roles: []string{"123"}, Weight: 0.5}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedulerConfig(&tt.scheduler, 10)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    PolicyConfig
+		shouldErr bool
+	}{
+		{name: "built-in rules only", policy: PolicyConfig{}, shouldErr: false},
+		{
+			name: "valid rules and allowlist",
+			policy: PolicyConfig{
+				Rules: []PolicyRule{
+					{Name: "netcat", Languages: []string{"bash"}, Tokens: []string{"nc"}, Action: "block"},
+					{Name: "sockets", Pattern: `import\s+socket`, Action: "flag"},
+				},
+				Allowlists: []CommandAllowlist{{Languages: []string{"bash"}, Commands: []string{"echo", "ls"}}},
+			},
+			shouldErr: false,
+		},
+		{
+			name:      "rule without name",
+			policy:    PolicyConfig{Rules: []PolicyRule{{Tokens: []string{"nc"}, Action: "block"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "rule without pattern or tokens",
+			policy:    PolicyConfig{Rules: []PolicyRule{{Name: "empty", Action: "block"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "invalid pattern",
+			policy:    PolicyConfig{Rules: []PolicyRule{{Name: "broken", Pattern: "(", Action: "block"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown action",
+			policy:    PolicyConfig{Rules: []PolicyRule{{Name: "nc", Tokens: []string{"nc"}, Action: "warn"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "allowlist without commands",
+			policy:    PolicyConfig{Allowlists: []CommandAllowlist{{Languages: []string{"bash"}}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePolicyConfig(&tt.policy)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	history := HistoryConfig{RetentionDays: 30, MaxPerUser: 200, MaxOutputBytes: 16384}
+	tests := []struct {
+		name      string
+		storage   StorageConfig
+		shouldErr bool
+	}{
+		{name: "disabled", storage: StorageConfig{}, shouldErr: false},
+		{name: "valid", storage: StorageConfig{Path: "data.db", History: history}, shouldErr: false},
+		{name: "shares audit database", storage: StorageConfig{Path: "audit.db", History: history}, shouldErr: true},
+		{
+			name:      "negative retention",
+			storage:   StorageConfig{Path: "data.db", History: HistoryConfig{RetentionDays: -1, MaxOutputBytes: 16384}},
+			shouldErr: true,
+		},
+		{
+			name:      "output limit too small",
+			storage:   StorageConfig{Path: "data.db", History: HistoryConfig{MaxOutputBytes: 10}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStorageConfig(&Config{Storage: tt.storage, Audit: AuditConfig{Database: "audit.db"}})
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSnippets(t *testing.T) {
+	tests := []struct {
+		name      string
+		snippets  SnippetsConfig
+		shouldErr bool
+	}{
+		{name: "unlimited", snippets: SnippetsConfig{}, shouldErr: false},
+		{name: "valid", snippets: SnippetsConfig{MaxPerUser: 50, MaxPerGuild: 200, MaxVersions: 10}, shouldErr: false},
+		{name: "negative per user", snippets: SnippetsConfig{MaxPerUser: -1}, shouldErr: true},
+		{name: "too many versions", snippets: SnippetsConfig{MaxVersions: 101}, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSnippetsConfig(&tt.snippets)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSchedules(t *testing.T) {
+	tests := []struct {
+		name      string
+		schedules SchedulesConfig
+		shouldErr bool
+	}{
+		{name: "unlimited", schedules: SchedulesConfig{}, shouldErr: false},
+		{name: "valid", schedules: SchedulesConfig{MaxPerGuild: 10, MinIntervalSeconds: 300, MaxFailures: 3}},
+		{name: "negative cap", schedules: SchedulesConfig{MaxPerGuild: -1}, shouldErr: true},
+		{name: "interval over a day", schedules: SchedulesConfig{MinIntervalSeconds: 86401}, shouldErr: true},
+		{name: "negative failures", schedules: SchedulesConfig{MaxFailures: -1}, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedulesConfig(&tt.schedules)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMacros(t *testing.T) {
+	harness := MacroTemplate{Language: "python", Template: "import sys\n{{code}}"}
+	tests := []struct {
+		name      string
+		macros    MacrosConfig
+		shouldErr bool
+	}{
+		{name: "empty", macros: MacrosConfig{}, shouldErr: false},
+		{
+			name: "valid",
+			macros: MacrosConfig{
+				Aliases:   map[string]string{"py": "run python", "aoc": "run --macro=aoc"},
+				Templates: map[string]MacroTemplate{"aoc": harness},
+				Guilds:    map[string]GuildMacros{"123": {Aliases: map[string]string{"js": "run javascript"}}},
+			},
+		},
+		{name: "invalid alias name", macros: MacrosConfig{Aliases: map[string]string{"Py!": "run python"}}, shouldErr: true},
+		{name: "empty alias", macros: MacrosConfig{Aliases: map[string]string{"py": " "}}, shouldErr: true},
+		{
+			name:      "template without code",
+			macros:    MacrosConfig{Templates: map[string]MacroTemplate{"aoc": {Language: "python", Template: "print(1)"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "template without language",
+			macros:    MacrosConfig{Templates: map[string]MacroTemplate{"aoc": {Template: "{{code}}"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "invalid guild alias",
+			macros:    MacrosConfig{Guilds: map[string]GuildMacros{"123": {Aliases: map[string]string{"py": ""}}}},
+			shouldErr: true,
+		},
+		{name: "negative cap", macros: MacrosConfig{MaxPerGuild: -1}, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMacrosConfig(&tt.macros)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected validation error, but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no validation error, but got: %v", err)
+			}
+		})
+	}
+}
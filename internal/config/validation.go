@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -11,6 +13,64 @@ const (
 	MaxDefaultTimeoutSeconds = 3600 // 1 hour
 	MaxRuntimeSeconds        = 7200 // 2 hours
 	MaxReadWriteTimeout      = 300  // 5 minutes
+	MinSessionIdleTimeout    = 10
+	MaxEditWindowSeconds     = 3600 // 1 hour
+	MaxShutdownGraceSeconds  = 3600 // 1 hour
+	MinReconcileInterval     = 10
+	MaxReconcileInterval     = 86400 // 1 day
+
+	// Largest file Discord accepts in a server without boosts; overflowing
+	// output, uploads and output files are all sent as attachments
+	DiscordAttachmentBytes = 10 << 20 // 10 MiB
+
+	// Output capture limits in bytes
+	MinOutputBytes      = 1024 // 1 KiB
+	MaxOutputBytesLimit = DiscordAttachmentBytes
+
+	// Standard input limits in bytes
+	MinStdinBytes      = 1024    // 1 KiB
+	MaxStdinBytesLimit = 1 << 20 // 1 MiB
+
+	// Upload limits
+	MinUploadBytes      = 1024 // 1 KiB
+	MaxUploadBytesLimit = DiscordAttachmentBytes
+	MaxUploadFilesLimit = 100
+
+	// Output file limits; results also attach up to two logs and two source
+	// files, and Discord allows 10 attachments per message
+	MaxArtifactsLimit     = 6
+	MaxArtifactBytesLimit = DiscordAttachmentBytes
+
+	// Compiled workspace limits in bytes
+	MinWorkspaceBytes      = 1 << 20 // 1 MiB
+	MaxWorkspaceBytesLimit = 1 << 30 // 1 GiB
+
+	// Rate limit and quota limits
+	MaxRateBurst       = 1000
+	MaxRatePerMinute   = 6000
+	MaxDailyCPUSeconds = 86400 // 1 day
+
+	// Scheduler limits
+	MaxStarvationSeconds = 3600 // 1 hour
+	MaxPriorityWeight    = 100
+
+	// Snippet limits
+	MaxSnippetsLimit        = 10000
+	MaxSnippetVersionsLimit = 100
+
+	// Schedule limits
+	MaxSchedulesLimit          = 1000
+	MaxScheduleIntervalSeconds = 86400 // 1 day
+	MaxScheduleFailures        = 100
+
+	// Macro limits
+	MaxMacrosLimit = 1000
+
+	// History limits
+	MaxHistoryRetentionDays = 3650 // 10 years
+	MaxHistoryPerUser       = 10000
+	MinHistoryOutputBytes   = 256
+	MaxHistoryOutputBytes   = 1 << 20 // 1 MiB
 
 	// Other validation constants
 	MinTokenLength     = 10 // Minimum test token length
@@ -26,6 +86,11 @@ func validateConfig(config *Config) error {
 		errors = append(errors, fmt.Sprintf("bot config: %v", err))
 	}
 
+	// Validate executor configuration
+	if err := validateExecutorConfig(&config.Executor); err != nil {
+		errors = append(errors, fmt.Sprintf("executor config: %v", err))
+	}
+
 	// Validate docker configuration
 	if err := validateDockerConfig(&config.Docker); err != nil {
 		errors = append(errors, fmt.Sprintf("docker config: %v", err))
@@ -41,6 +106,16 @@ func validateConfig(config *Config) error {
 		errors = append(errors, fmt.Sprintf("server config: %v", err))
 	}
 
+	// Validate audit configuration
+	if err := validateAuditConfig(&config.Audit); err != nil {
+		errors = append(errors, fmt.Sprintf("audit config: %v", err))
+	}
+
+	// Validate storage configuration
+	if err := validateStorageConfig(config); err != nil {
+		errors = append(errors, fmt.Sprintf("storage config: %v", err))
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("validation errors: %s", strings.Join(errors, "; "))
 	}
@@ -75,6 +150,186 @@ func validateBotConfig(config *BotConfig) error {
 		errors = append(errors, "max concurrent commands should not exceed 100")
 	}
 
+	// Upload limit validation
+	if config.MaxUploadBytes < MinUploadBytes {
+		errors = append(errors, "max upload bytes must be at least 1024")
+	}
+	if config.MaxUploadBytes > MaxUploadBytesLimit {
+		errors = append(errors, "max upload bytes should not exceed 10 MiB")
+	}
+	if config.MaxUploadFiles < 1 {
+		errors = append(errors, "max upload files must be at least 1")
+	}
+	if config.MaxUploadFiles > MaxUploadFilesLimit {
+		errors = append(errors, "max upload files should not exceed 100")
+	}
+
+	// Sessions hold execution slots, so they cannot outnumber them
+	if config.MaxSessions < 0 {
+		errors = append(errors, "max sessions cannot be negative")
+	}
+	if config.MaxSessions > config.MaxConcurrentCommands {
+		errors = append(errors, "max sessions cannot exceed max concurrent commands")
+	}
+
+	// Edit window validation
+	if config.EditWindow < 0 {
+		errors = append(errors, "edit window cannot be negative")
+	}
+	if config.EditWindow > MaxEditWindowSeconds {
+		errors = append(errors, "edit window should not exceed 1 hour")
+	}
+
+	// Shutdown grace validation
+	if config.ShutdownGrace < 0 || config.ShutdownGrace > MaxShutdownGraceSeconds {
+		errors = append(errors, fmt.Sprintf("shutdown grace must be between 0 and %d seconds", MaxShutdownGraceSeconds))
+	}
+
+	if err := validatePermissionsConfig(&config.Permissions); err != nil {
+		errors = append(errors, fmt.Sprintf("permissions: %v", err))
+	}
+
+	if err := validateLimitsConfig(&config.Limits); err != nil {
+		errors = append(errors, fmt.Sprintf("limits: %v", err))
+	}
+
+	if err := validateSchedulerConfig(&config.Scheduler, config.MaxConcurrentCommands); err != nil {
+		errors = append(errors, fmt.Sprintf("scheduler: %v", err))
+	}
+
+	if err := validatePolicyConfig(&config.Policy); err != nil {
+		errors = append(errors, fmt.Sprintf("policy: %v", err))
+	}
+
+	if err := validateSnippetsConfig(&config.Snippets); err != nil {
+		errors = append(errors, fmt.Sprintf("snippets: %v", err))
+	}
+
+	if err := validateSchedulesConfig(&config.Schedules); err != nil {
+		errors = append(errors, fmt.Sprintf("schedules: %v", err))
+	}
+
+	if err := validateMacrosConfig(&config.Macros); err != nil {
+		errors = append(errors, fmt.Sprintf("macros: %v", err))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validatePermissionsConfig validates permission grants and rules. Language
+// names are checked by the bot, which knows the supported languages.
+func validatePermissionsConfig(config *PermissionsConfig) error {
+	var errors []string
+
+	errors = append(errors, validatePermissionGrant("default", &config.Default)...)
+	errors = append(errors, validatePermissionRules("rules", config.Rules)...)
+
+	for guildID, guild := range config.Guilds {
+		if guildID == "" {
+			errors = append(errors, "guild overrides need a guild ID")
+		}
+		if guild.Lockdown && guild.Default != nil {
+			errors = append(errors, "guild "+guildID+" cannot set a default grant while locked down")
+		}
+		if guild.Default != nil {
+			errors = append(errors, validatePermissionGrant("guild "+guildID+" default", guild.Default)...)
+		}
+		errors = append(errors, validatePermissionRules("guild "+guildID+" rules", guild.Rules)...)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validatePermissionRules validates a list of permission rules
+func validatePermissionRules(name string, rules []PermissionRule) []string {
+	var errors []string
+
+	for i, rule := range rules {
+		ruleName := fmt.Sprintf("%s[%d]", name, i)
+		if len(rule.Roles) == 0 && len(rule.Users) == 0 {
+			errors = append(errors, ruleName+" must list at least one role or user")
+		}
+		errors = append(errors, validatePermissionGrant(ruleName, &rule.PermissionGrant)...)
+	}
+
+	return errors
+}
+
+// validatePermissionGrant validates the tier, network modes and admin
+// commands of a grant
+func validatePermissionGrant(name string, grant *PermissionGrant) []string {
+	var errors []string
+
+	validTiers := map[string]bool{
+		"":         true,
+		"small":    true,
+		"standard": true,
+		"large":    true,
+	}
+	if !validTiers[strings.ToLower(grant.MaxTier)] {
+		errors = append(errors, name+" max tier must be one of: small, standard, large")
+	}
+
+	validNetworks := map[string]bool{
+		"none":     true,
+		"isolated": true,
+		"internet": true,
+	}
+	for _, mode := range grant.Network {
+		if !validNetworks[strings.ToLower(mode)] {
+			errors = append(errors, name+" network modes must be one of: none, isolated, internet")
+			break
+		}
+	}
+
+	validAdmin := map[string]bool{
+		"*":         true,
+		"cancel":    true,
+		"delete":    true,
+		"snippets":  true,
+		"schedules": true,
+		"macros":    true,
+	}
+	for _, command := range grant.Admin {
+		if !validAdmin[strings.ToLower(command)] {
+			errors = append(errors, name+" admin commands must be one of: cancel, delete, snippets, schedules, macros, *")
+			break
+		}
+	}
+
+	return errors
+}
+
+// validateLimitsConfig validates rate limits and quotas
+func validateLimitsConfig(config *LimitsConfig) error {
+	var errors []string
+
+	errors = append(errors, validateRateLimit("user", &config.User)...)
+	errors = append(errors, validateRateLimit("channel", &config.Channel)...)
+	errors = append(errors, validateRateLimit("guild", &config.Guild)...)
+	errors = append(errors, validateDailyCPUSeconds("daily", config.DailyCPUSeconds)...)
+
+	for i, role := range config.Roles {
+		name := fmt.Sprintf("roles[%d]", i)
+		if len(role.Roles) == 0 {
+			errors = append(errors, name+" must list at least one role")
+		}
+		if role.User != nil {
+			errors = append(errors, validateRateLimit(name+" user", role.User)...)
+		}
+		if role.DailyCPUSeconds != nil {
+			errors = append(errors, validateDailyCPUSeconds(name, *role.DailyCPUSeconds)...)
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("%s", strings.Join(errors, "; "))
 	}
@@ -82,6 +337,223 @@ func validateBotConfig(config *BotConfig) error {
 	return nil
 }
 
+// validateRateLimit validates a token bucket
+func validateRateLimit(name string, limit *RateLimit) []string {
+	var errors []string
+
+	if limit.Burst < 0 {
+		errors = append(errors, name+" burst cannot be negative")
+	}
+	if limit.Burst > MaxRateBurst {
+		errors = append(errors, name+" burst should not exceed 1000")
+	}
+	if limit.Burst > 0 && limit.PerMinute <= 0 {
+		errors = append(errors, name+" per minute must be greater than 0")
+	}
+	if limit.PerMinute > MaxRatePerMinute {
+		errors = append(errors, name+" per minute should not exceed 6000")
+	}
+
+	return errors
+}
+
+// validateDailyCPUSeconds validates a daily CPU quota
+func validateDailyCPUSeconds(name string, seconds int) []string {
+	var errors []string
+
+	if seconds < 0 {
+		errors = append(errors, name+" CPU seconds cannot be negative")
+	}
+	if seconds > MaxDailyCPUSeconds {
+		errors = append(errors, name+" CPU seconds should not exceed one day")
+	}
+
+	return errors
+}
+
+// validateSchedulerConfig validates slot sharing against the number of slots
+func validateSchedulerConfig(config *SchedulerConfig, slots int) error {
+	var errors []string
+
+	if config.MaxPerUser < 0 {
+		errors = append(errors, "max per user cannot be negative")
+	}
+	if config.MaxPerUser > slots {
+		errors = append(errors, "max per user cannot exceed max concurrent commands")
+	}
+	if config.MaxPerGuild < 0 {
+		errors = append(errors, "max per guild cannot be negative")
+	}
+	if config.MaxPerGuild > slots {
+		errors = append(errors, "max per guild cannot exceed max concurrent commands")
+	}
+	if config.StarvationSeconds < 0 {
+		errors = append(errors, "starvation seconds cannot be negative")
+	}
+	if config.StarvationSeconds > MaxStarvationSeconds {
+		errors = append(errors, "starvation seconds should not exceed 1 hour")
+	}
+
+	for i, priority := range config.Priorities {
+		name := fmt.Sprintf("priorities[%d]", i)
+		if len(priority.Roles) == 0 {
+			errors = append(errors, name+" must list at least one role")
+		}
+		if priority.Weight < 1 {
+			errors = append(errors, name+" weight must be at least 1")
+		}
+		if priority.Weight > MaxPriorityWeight {
+			errors = append(errors, name+" weight should not exceed 100")
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validatePolicyConfig validates code screening rules and allowlists
+func validatePolicyConfig(config *PolicyConfig) error {
+	var errors []string
+
+	actions := map[string]bool{"block": true, "flag": true, "allow": true}
+	for i, rule := range config.Rules {
+		name := fmt.Sprintf("rules[%d]", i)
+		if rule.Name == "" {
+			errors = append(errors, name+" must have a name")
+		}
+		if rule.Pattern == "" && len(rule.Tokens) == 0 {
+			errors = append(errors, name+" must have a pattern or tokens")
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				errors = append(errors, fmt.Sprintf("%s pattern is invalid: %v", name, err))
+			}
+		}
+		if !actions[rule.Action] {
+			errors = append(errors, name+" action must be one of: block, flag, allow")
+		}
+	}
+
+	for i, allowlist := range config.Allowlists {
+		name := fmt.Sprintf("allowlists[%d]", i)
+		if len(allowlist.Languages) == 0 {
+			errors = append(errors, name+" must list at least one language")
+		}
+		if len(allowlist.Commands) == 0 {
+			errors = append(errors, name+" must list at least one command")
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validateSnippetsConfig validates snippet limits
+func validateSnippetsConfig(config *SnippetsConfig) error {
+	var errors []string
+
+	if config.MaxPerUser < 0 || config.MaxPerUser > MaxSnippetsLimit {
+		errors = append(errors, fmt.Sprintf("max per user must be between 0 and %d", MaxSnippetsLimit))
+	}
+	if config.MaxPerGuild < 0 || config.MaxPerGuild > MaxSnippetsLimit {
+		errors = append(errors, fmt.Sprintf("max per guild must be between 0 and %d", MaxSnippetsLimit))
+	}
+	if config.MaxVersions < 0 || config.MaxVersions > MaxSnippetVersionsLimit {
+		errors = append(errors, fmt.Sprintf("max versions must be between 0 and %d", MaxSnippetVersionsLimit))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validateSchedulesConfig validates schedule limits
+func validateSchedulesConfig(config *SchedulesConfig) error {
+	var errors []string
+
+	if config.MaxPerGuild < 0 || config.MaxPerGuild > MaxSchedulesLimit {
+		errors = append(errors, fmt.Sprintf("max per guild must be between 0 and %d", MaxSchedulesLimit))
+	}
+	if config.MinIntervalSeconds < 0 || config.MinIntervalSeconds > MaxScheduleIntervalSeconds {
+		errors = append(errors, fmt.Sprintf("min interval must be between 0 and %d seconds", MaxScheduleIntervalSeconds))
+	}
+	if config.MaxFailures < 0 || config.MaxFailures > MaxScheduleFailures {
+		errors = append(errors, fmt.Sprintf("max failures must be between 0 and %d", MaxScheduleFailures))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// instanceIDPattern matches valid executor instance IDs
+var instanceIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// macroNamePattern matches valid alias and template names
+var macroNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// validateMacrosConfig validates alias and template names and templates.
+// Languages and alias loops are checked by the bot.
+func validateMacrosConfig(config *MacrosConfig) error {
+	var errors []string
+
+	if config.MaxPerGuild < 0 || config.MaxPerGuild > MaxMacrosLimit {
+		errors = append(errors, fmt.Sprintf("max per guild must be between 0 and %d", MaxMacrosLimit))
+	}
+
+	errors = append(errors, validateMacros("", config.Aliases, config.Templates)...)
+	for guildID, guild := range config.Guilds {
+		if guildID == "" {
+			errors = append(errors, "guild macros need a guild ID")
+		}
+		errors = append(errors, validateMacros("guild "+guildID+" ", guild.Aliases, guild.Templates)...)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validateMacros validates one set of aliases and templates
+func validateMacros(prefix string, aliases map[string]string, templates map[string]MacroTemplate) []string {
+	var errors []string
+
+	for name, command := range aliases {
+		if !macroNamePattern.MatchString(name) {
+			errors = append(errors, fmt.Sprintf("%salias %q must be 1-32 lowercase letters, digits, - and _", prefix, name))
+		}
+		if strings.TrimSpace(command) == "" {
+			errors = append(errors, fmt.Sprintf("%salias %q must stand for a command", prefix, name))
+		}
+	}
+
+	for name, template := range templates {
+		if !macroNamePattern.MatchString(name) {
+			errors = append(errors, fmt.Sprintf("%stemplate %q must be 1-32 lowercase letters, digits, - and _", prefix, name))
+		}
+		if template.Language == "" {
+			errors = append(errors, fmt.Sprintf("%stemplate %q must have a language", prefix, name))
+		}
+		if !strings.Contains(template.Template, "{{code}}") {
+			errors = append(errors, fmt.Sprintf("%stemplate %q must contain {{code}}", prefix, name))
+		}
+	}
+
+	return errors
+}
+
 // validateDockerConfig validates Docker-specific configuration
 func validateDockerConfig(config *DockerConfig) error {
 	var errors []string
@@ -114,6 +586,20 @@ func validateDockerConfig(config *DockerConfig) error {
 		errors = append(errors, "memory limit should not exceed 4096 MB")
 	}
 
+	if config.CompileTimeout < 1 {
+		errors = append(errors, "compile timeout must be at least 1 second")
+	}
+	if config.CompileTimeout > MaxDefaultTimeoutSeconds {
+		errors = append(errors, "compile timeout should not exceed 1 hour")
+	}
+
+	if config.CompileMemoryLimit < 16 {
+		errors = append(errors, "compile memory limit must be at least 16 MB")
+	}
+	if config.CompileMemoryLimit > 4096 {
+		errors = append(errors, "compile memory limit should not exceed 4096 MB")
+	}
+
 	if config.CPULimit <= 0 {
 		errors = append(errors, "CPU limit must be greater than 0")
 	}
@@ -121,11 +607,100 @@ func validateDockerConfig(config *DockerConfig) error {
 		errors = append(errors, "CPU limit should not exceed 8.0")
 	}
 
+	// Output capture validation
+	if config.MaxOutputBytes < MinOutputBytes {
+		errors = append(errors, "max output bytes must be at least 1024")
+	}
+	if config.MaxOutputBytes > MaxOutputBytesLimit {
+		errors = append(errors, "max output bytes should not exceed 10 MiB")
+	}
+
+	// Standard input validation
+	if config.MaxStdinBytes < MinStdinBytes {
+		errors = append(errors, "max stdin bytes must be at least 1024")
+	}
+	if config.MaxStdinBytes > MaxStdinBytesLimit {
+		errors = append(errors, "max stdin bytes should not exceed 1 MiB")
+	}
+
+	// Session idle timeout validation
+	if config.SessionIdleTimeout < MinSessionIdleTimeout {
+		errors = append(errors, "session idle timeout must be at least 10 seconds")
+	}
+	if config.SessionIdleTimeout > MaxDefaultTimeoutSeconds {
+		errors = append(errors, "session idle timeout should not exceed 1 hour")
+	}
+
+	// Output file validation
+	if config.MaxArtifacts < 0 {
+		errors = append(errors, "max artifacts cannot be negative")
+	}
+	if config.MaxArtifacts > MaxArtifactsLimit {
+		errors = append(errors, "max artifacts should not exceed 6")
+	}
+	if config.MaxArtifactBytes < 0 {
+		errors = append(errors, "max artifact bytes cannot be negative")
+	}
+	if config.MaxArtifactBytes > MaxArtifactBytesLimit {
+		errors = append(errors, "max artifact bytes should not exceed 10 MiB")
+	}
+
+	// Compiled workspace validation
+	if config.MaxWorkspaceBytes < MinWorkspaceBytes {
+		errors = append(errors, "max workspace bytes must be at least 1 MiB")
+	}
+	if config.MaxWorkspaceBytes > MaxWorkspaceBytesLimit {
+		errors = append(errors, "max workspace bytes should not exceed 1 GiB")
+	}
+
 	// Network name validation
 	if config.NetworkName == "" {
 		errors = append(errors, "network name cannot be empty")
 	}
 
+	// Resources are found by their instance label
+	if !instanceIDPattern.MatchString(config.InstanceID) {
+		errors = append(errors, "instance id must be 1-63 letters, digits, '.', '_' or '-'")
+	}
+	if config.ReconcileInterval < 0 {
+		errors = append(errors, "reconcile interval cannot be negative")
+	}
+	if config.ReconcileInterval > 0 && config.ReconcileInterval < MinReconcileInterval {
+		errors = append(errors, "reconcile interval must be 0 or at least 10 seconds")
+	}
+	if config.ReconcileInterval > MaxReconcileInterval {
+		errors = append(errors, "reconcile interval should not exceed 1 day")
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// validateExecutorConfig validates the backend selection and sandbox paths
+func validateExecutorConfig(config *ExecutorConfig) error {
+	var errors []string
+
+	if config.Backend != BackendDocker && config.Backend != BackendSandbox {
+		errors = append(errors, fmt.Sprintf("backend must be either '%s' or '%s'", BackendDocker, BackendSandbox))
+	}
+
+	// The sandbox resolves paths from a different root, so relative ones are refused
+	sandbox := &config.Sandbox
+	if sandbox.Dir != "" && !filepath.IsAbs(sandbox.Dir) {
+		errors = append(errors, "sandbox dir must be an absolute path")
+	}
+	if sandbox.CgroupRoot != "" && !filepath.IsAbs(sandbox.CgroupRoot) {
+		errors = append(errors, "sandbox cgroup root must be an absolute path")
+	}
+	for _, p := range sandbox.ReadOnlyPaths {
+		if !filepath.IsAbs(p) || filepath.Clean(p) == "/" {
+			errors = append(errors, fmt.Sprintf("sandbox read-only path %q must be an absolute path below /", p))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("%s", strings.Join(errors, "; "))
 	}
@@ -199,6 +774,45 @@ func validateServerConfig(config *ServerConfig) error {
 	return nil
 }
 
+// validateAuditConfig validates audit log configuration
+func validateAuditConfig(config *AuditConfig) error {
+	if config.File != "" && config.File == config.Database {
+		return fmt.Errorf("audit file and database must be different paths")
+	}
+	return nil
+}
+
+// validateStorageConfig validates the embedded database and history retention
+func validateStorageConfig(config *Config) error {
+	var errors []string
+	storage := &config.Storage
+
+	// Without a database there is no history to limit
+	if storage.Path == "" {
+		return nil
+	}
+	if storage.Path == config.Audit.File || storage.Path == config.Audit.Database {
+		errors = append(errors, "storage path must differ from the audit log paths")
+	}
+
+	history := &storage.History
+	if history.RetentionDays < 0 || history.RetentionDays > MaxHistoryRetentionDays {
+		errors = append(errors, fmt.Sprintf("history retention must be between 0 and %d days", MaxHistoryRetentionDays))
+	}
+	if history.MaxPerUser < 0 || history.MaxPerUser > MaxHistoryPerUser {
+		errors = append(errors, fmt.Sprintf("history max per user must be between 0 and %d", MaxHistoryPerUser))
+	}
+	if history.MaxOutputBytes < MinHistoryOutputBytes || history.MaxOutputBytes > MaxHistoryOutputBytes {
+		errors = append(errors, fmt.Sprintf("history max output bytes must be between %d and %d",
+			MinHistoryOutputBytes, MaxHistoryOutputBytes))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
 // isValidBotToken performs basic validation on Discord bot token format
 func isValidBotToken(token string) bool {
 	// Basic validation - Discord bot tokens are typically 59+ characters
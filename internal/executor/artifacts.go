@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// outDir is where programs write files they want returned to the user
+const outDir = "/out"
+
+// sniffLength is how many leading bytes content type detection looks at
+const sniffLength = 512
+
+// Artifact is a file a program wrote to the output directory
+type Artifact struct {
+	// File name, flattened from its path under the output directory
+	Name string
+
+	// Detected MIME type
+	ContentType string
+
+	// File contents
+	Content []byte
+}
+
+// IsImage reports whether the artifact can be displayed inline as an image
+func (a *Artifact) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// outDirArchive returns a tar archive creating the writable output directory
+func outDirArchive() ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	dir := &tar.Header{Name: strings.TrimPrefix(outDir, "/") + "/", Mode: workspaceDirMode, Typeflag: tar.TypeDir}
+	if err := tw.WriteHeader(dir); err != nil {
+		return nil, fmt.Errorf("failed to build output directory archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build output directory archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readArtifacts reads regular files from a tar stream of the output
+// directory, keeping at most maxCount files and maxBytes in total. Files
+// beyond either limit are skipped and reported through skipped.
+func readArtifacts(r io.Reader, maxCount, maxBytes int) (artifacts []Artifact, skipped bool, err error) {
+	tr := tar.NewReader(r)
+	total := 0
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return artifacts, skipped, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read output directory: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if len(artifacts) >= maxCount || total+int(header.Size) > maxBytes {
+			skipped = true
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(tr, int64(maxBytes-total)+1))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read output file %s: %w", header.Name, err)
+		}
+		if total+len(content) > maxBytes {
+			skipped = true
+			continue
+		}
+		total += len(content)
+
+		name := artifactName(header.Name)
+		artifacts = append(artifacts, Artifact{
+			Name:        name,
+			ContentType: detectContentType(name, content),
+			Content:     content,
+		})
+	}
+}
+
+// artifactName flattens an archive path such as "out/plots/a.png" into a
+// single attachment file name such as "plots_a.png"
+func artifactName(name string) string {
+	name = strings.TrimPrefix(path.Clean(name), strings.TrimPrefix(outDir, "/")+"/")
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// detectContentType sniffs the content, preferring the extension for
+// text formats that sniffing reports as plain text
+func detectContentType(name string, content []byte) string {
+	sniffed := http.DetectContentType(content[:min(len(content), sniffLength)])
+	if !strings.HasPrefix(sniffed, "text/plain") {
+		return sniffed
+	}
+	if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+		return byExt
+	}
+	return sniffed
+}
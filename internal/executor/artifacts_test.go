@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+// outArchive builds a tar stream shaped like CopyFromContainer's output for /out
+func outArchive(t *testing.T, files map[string][]byte, order []string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "out/", Mode: 0o777, Typeflag: tar.TypeDir}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range order {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestReadArtifacts(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	files := map[string][]byte{
+		"out/plot.png":      png,
+		"out/data/rows.csv": []byte("a,b\n1,2\n"),
+	}
+
+	artifacts, skipped, err := readArtifacts(outArchive(t, files, []string{"out/plot.png", "out/data/rows.csv"}), 5, 1024)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if skipped {
+		t.Error("Expected no files to be skipped")
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %d", len(artifacts))
+	}
+
+	if artifacts[0].Name != "plot.png" || artifacts[0].ContentType != "image/png" || !artifacts[0].IsImage() {
+		t.Errorf("Unexpected image artifact %s (%s)", artifacts[0].Name, artifacts[0].ContentType)
+	}
+	if artifacts[1].Name != "data_rows.csv" || artifacts[1].IsImage() {
+		t.Errorf("Unexpected artifact %s (%s)", artifacts[1].Name, artifacts[1].ContentType)
+	}
+}
+
+func TestReadArtifactsLimits(t *testing.T) {
+	files := map[string][]byte{
+		"out/a.txt": bytes.Repeat([]byte("a"), 10),
+		"out/b.txt": bytes.Repeat([]byte("b"), 10),
+		"out/c.txt": bytes.Repeat([]byte("c"), 100),
+	}
+	order := []string{"out/a.txt", "out/c.txt", "out/b.txt"}
+
+	tests := []struct {
+		name     string
+		maxCount int
+		maxBytes int
+		expected []string
+	}{
+		{name: "count", maxCount: 1, maxBytes: 1024, expected: []string{"a.txt"}},
+		{name: "bytes", maxCount: 5, maxBytes: 50, expected: []string{"a.txt", "b.txt"}},
+		{name: "disabled", maxCount: 0, maxBytes: 1024, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifacts, skipped, err := readArtifacts(outArchive(t, files, order), tt.maxCount, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !skipped {
+				t.Error("Expected files to be skipped")
+			}
+
+			var names []string
+			for _, a := range artifacts {
+				names = append(names, a.Name)
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, names)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, names)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// conformanceConfig returns limits small enough for the suite to run quickly
+func conformanceConfig() config.DockerConfig {
+	return config.DockerConfig{
+		Host:               "unix:///var/run/docker.sock",
+		NetworkName:        "discord-executor-conformance",
+		InstanceID:         "conformance",
+		CPULimit:           1,
+		DefaultTimeout:     2,
+		MaxRuntime:         10,
+		MemoryLimit:        128,
+		CompileTimeout:     60,
+		CompileMemoryLimit: 512,
+		MaxOutputBytes:     4096,
+		MaxStdinBytes:      1024,
+		SessionIdleTimeout: 10,
+		MaxArtifacts:       2,
+		MaxArtifactBytes:   1 << 20,
+		MaxWorkspaceBytes:  1 << 20,
+	}
+}
+
+// testConformance checks the behaviour every backend must share. Backends
+// get the limits from conformanceConfig.
+func testConformance(t *testing.T, e Executor) {
+	tests := []struct {
+		name  string
+		req   Request
+		check func(t *testing.T, res *Result)
+	}{
+		{
+			name: "stdout and args",
+			req:  Request{Language: "bash", Code: `echo "hello $1"`, Args: []string{"world"}},
+			check: func(t *testing.T, res *Result) {
+				if res.Reason != TerminationSuccess || res.Stdout != "hello world\n" {
+					t.Errorf("Expected a greeting, got %s %q", res.Reason, res.Stdout)
+				}
+			},
+		},
+		{
+			name: "stderr and exit code",
+			req:  Request{Language: "bash", Code: "echo oops >&2; exit 3"},
+			check: func(t *testing.T, res *Result) {
+				if res.Reason != TerminationNonZeroExit || res.ExitCode != 3 || res.Stderr != "oops\n" {
+					t.Errorf("Expected exit code 3 with stderr, got %s %d %q", res.Reason, res.ExitCode, res.Stderr)
+				}
+			},
+		},
+		{
+			name: "stdin",
+			req:  Request{Language: "bash", Code: `read line; echo "got $line"; cat`, Stdin: "ping\nrest"},
+			check: func(t *testing.T, res *Result) {
+				if res.Stdout != "got ping\nrest" {
+					t.Errorf("Expected stdin echoed until EOF, got %q", res.Stdout)
+				}
+			},
+		},
+		{
+			name: "workspace files",
+			req: Request{
+				Language: "bash",
+				Code:     "source lib/greet.sh; greet",
+				Files:    []File{{Path: "lib/greet.sh", Content: []byte("greet() { echo hi from lib; }")}},
+			},
+			check: func(t *testing.T, res *Result) {
+				if res.Stdout != "hi from lib\n" {
+					t.Errorf("Expected the uploaded file to be sourced, got %q %q", res.Stdout, res.Stderr)
+				}
+			},
+		},
+		{
+			name: "signal",
+			req:  Request{Language: "bash", Code: `sh -c 'kill -SEGV $$'`},
+			check: func(t *testing.T, res *Result) {
+				if res.Reason != TerminationSignal || res.Signal != 11 {
+					t.Errorf("Expected SIGSEGV, got %s %d", res.Reason, res.Signal)
+				}
+			},
+		},
+		{
+			name: "timeout",
+			req:  Request{Language: "bash", Code: "sleep 30"},
+			check: func(t *testing.T, res *Result) {
+				if res.Reason != TerminationTimeout || res.Duration > 10*time.Second {
+					t.Errorf("Expected a timeout after 2s, got %s after %v", res.Reason, res.Duration)
+				}
+			},
+		},
+		{
+			name: "output limit",
+			req:  Request{Language: "bash", Code: "yes"},
+			check: func(t *testing.T, res *Result) {
+				if res.Reason != TerminationOutputLimit || !res.Truncated || len(res.Output()) > 4096 {
+					t.Errorf("Expected output cut at 4096 bytes, got %s with %d bytes", res.Reason, len(res.Output()))
+				}
+			},
+		},
+		{
+			name: "memory limit",
+			req:  Request{Language: "bash", Code: `x=$(head -c 300m /dev/zero | tr '\0' a); echo ${#x}`},
+			check: func(t *testing.T, res *Result) {
+				if !res.Failed() {
+					t.Error("Expected holding 300 MiB to fail under a 128 MiB limit")
+				}
+			},
+		},
+		{
+			name: "artifacts",
+			req:  Request{Language: "bash", Code: "mkdir /out/plots; echo data > /out/plots/a.txt; exit 1"},
+			check: func(t *testing.T, res *Result) {
+				if len(res.Artifacts) != 1 || res.Artifacts[0].Name != "plots_a.txt" ||
+					string(res.Artifacts[0].Content) != "data\n" {
+					t.Errorf("Expected the output file from a failed run, got %+v", res.Artifacts)
+				}
+			},
+		},
+		{
+			name: "writable tmp only",
+			req:  Request{Language: "bash", Code: "echo x > /tmp/a && cat /tmp/a && echo y > /etc/sandbox-test"},
+			check: func(t *testing.T, res *Result) {
+				if res.Stdout != "x\n" || !res.Failed() {
+					t.Errorf("Expected /tmp writable and /etc not, got %s %q", res.Reason, res.Stdout)
+				}
+			},
+		},
+		{
+			name: "no network",
+			req:  Request{Language: "bash", Code: "exec 3<>/dev/tcp/1.1.1.1/53", Network: NetworkNone},
+			check: func(t *testing.T, res *Result) {
+				if !res.Failed() {
+					t.Error("Expected outside connections to fail")
+				}
+			},
+		},
+		{
+			name: "compiled",
+			req:  Request{Language: "c", Code: "#include <stdio.h>\nint main(void) { puts(\"compiled\"); }\n"},
+			check: func(t *testing.T, res *Result) {
+				if res.Compile == nil || res.CompileFailed() || res.Stdout != "compiled\n" {
+					t.Errorf("Expected the program built and run, got %+v", res)
+				}
+			},
+		},
+		{
+			name: "compile error",
+			req:  Request{Language: "c", Code: "int main( {"},
+			check: func(t *testing.T, res *Result) {
+				if !res.CompileFailed() || res.Compile.Stderr == "" {
+					t.Errorf("Expected a compiler error, got %+v", res)
+				}
+			},
+		},
+		{
+			name: "oversized build",
+			req:  Request{Language: "c", Code: "char big[2 << 20] = {1};\nint main(void) { return big[0]; }\n"},
+			check: func(t *testing.T, res *Result) {
+				if !res.CompileFailed() || res.Stdout != "" {
+					t.Errorf("Expected a build over the workspace limit to fail, got %+v", res)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.ID = "conformance-" + strings.ReplaceAll(tt.name, " ", "-")
+			res, err := e.Execute(context.Background(), &req)
+			if errors.Is(err, errToolchainMissing) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			tt.check(t, res)
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(500*time.Millisecond, cancel)
+		res, err := e.Execute(ctx, &Request{ID: "conformance-canceled", Language: "bash", Code: "sleep 30"})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if res.Reason != TerminationCanceled {
+			t.Errorf("Expected the run to be cancelled, got %s", res.Reason)
+		}
+	})
+}
+
+func TestDockerConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Pulls language images")
+	}
+	e, err := NewDockerExecutor(context.Background(), conformanceConfig())
+	if err != nil {
+		t.Skipf("Docker is unavailable: %v", err)
+	}
+	defer e.Close()
+
+	testConformance(t, e)
+}
@@ -0,0 +1,444 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// Container settings shared by every execution
+const (
+	workDir       = "/workspace"
+	containerUser = "65534:65534" // nobody
+	pidsLimit     = 64
+
+	// Timeout for cleanup calls that must run after the request context ends
+	cleanupTimeout = 10 * time.Second
+)
+
+// DockerExecutor runs code in short-lived Docker containers
+type DockerExecutor struct {
+	cli *client.Client
+	cfg config.DockerConfig
+
+	// Containers created and not yet removed, by execution, so Close can
+	// remove them and the reconciler leaves them alone
+	mu         sync.Mutex
+	containers map[string]string
+	live       map[string]int
+
+	// Closed by Close to stop the periodic reconciler
+	stop chan struct{}
+}
+
+// NewDockerExecutor connects to the Docker daemon and prepares the execution network
+func NewDockerExecutor(ctx context.Context, cfg config.DockerConfig) (*DockerExecutor, error) {
+	cli, err := client.NewClientWithOpts(client.WithHost(cfg.Host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	e := &DockerExecutor{
+		cli:        cli,
+		cfg:        cfg,
+		containers: make(map[string]string),
+		live:       make(map[string]int),
+		stop:       make(chan struct{}),
+	}
+	if err := e.ensureNetwork(ctx); err != nil {
+		_ = cli.Close()
+		return nil, err
+	}
+
+	// Resources left by a previous run of this instance are removed before
+	// the first execution
+	e.Reconcile(ctx)
+	if cfg.ReconcileInterval > 0 {
+		go e.reconcileEvery(time.Duration(cfg.ReconcileInterval) * time.Second)
+	}
+
+	return e, nil
+}
+
+// Close kills and removes containers still left by executions and sessions,
+// then releases the Docker client
+func (e *DockerExecutor) Close() error {
+	close(e.stop)
+
+	e.mu.Lock()
+	leftover := make([]string, 0, len(e.containers))
+	for id := range e.containers {
+		leftover = append(leftover, id)
+	}
+	e.mu.Unlock()
+
+	for _, id := range leftover {
+		e.killContainer(id)
+		e.removeContainer(id)
+	}
+	if len(leftover) > 0 {
+		logrus.WithField("containers", len(leftover)).Info("Removed leftover containers")
+	}
+	return e.cli.Close()
+}
+
+// Execute runs the request in fresh containers and removes them afterwards
+func (e *DockerExecutor) Execute(ctx context.Context, req *Request) (*Result, error) {
+	return execute(ctx, e, e.cfg, req)
+}
+
+// prepare pulls the language's image if it is not available locally
+func (e *DockerExecutor) prepare(ctx context.Context, lang *Language) error {
+	return e.ensureImage(ctx, lang.Image)
+}
+
+// runPhase runs one phase to completion in its own container
+func (e *DockerExecutor) runPhase(ctx context.Context, executionID string, p *phase) (*PhaseResult, []byte, error) {
+	log := logrus.WithFields(logrus.Fields{"execution_id": executionID, "phase": p.name})
+
+	id, err := e.createContainer(ctx, executionID, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer e.removeContainer(id)
+
+	err = e.cli.CopyToContainer(ctx, id, "/", bytes.NewReader(p.workspace), container.CopyToContainerOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy workspace into container: %w", err)
+	}
+	if p.collectArtifacts {
+		out, err := outDirArchive()
+		if err != nil {
+			return nil, nil, err
+		}
+		err = e.cli.CopyToContainer(ctx, id, "/", bytes.NewReader(out), container.CopyToContainerOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	attachOpts := container.AttachOptions{Stream: true, Stdin: true, Stdout: true, Stderr: true}
+	attach, err := e.cli.ContainerAttach(ctx, id, attachOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to attach to container: %w", err)
+	}
+	defer attach.Close()
+
+	phaseCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	output := newOutputCapture(e.cfg.MaxOutputBytes, func() {
+		log.Warn("Output limit exceeded, stopping container")
+		e.killContainer(id)
+	})
+	output.live = p.stream
+
+	// Register the wait before starting so a fast exit cannot be missed
+	waitCh, waitErrCh := e.cli.ContainerWait(phaseCtx, id, container.WaitConditionNextExit)
+
+	start := time.Now()
+	if err := e.cli.ContainerStart(phaseCtx, id, container.StartOptions{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Feed stdin and close it so programs reading to EOF terminate; this runs
+	// in the background because a program that never reads would block it
+	go func() {
+		if p.stdin != "" {
+			if _, err := io.WriteString(attach.Conn, p.stdin); err != nil {
+				log.WithError(err).Debug("Failed to write stdin")
+			}
+		}
+		if err := attach.CloseWrite(); err != nil {
+			log.WithError(err).Debug("Failed to close stdin")
+		}
+	}()
+
+	copyDone := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(output.Stdout(), output.Stderr(), attach.Reader)
+		copyDone <- err
+	}()
+
+	statsCtx, stopStats := context.WithCancel(ctx)
+	statsDone := make(chan resourceUsage, 1)
+	go func() {
+		statsDone <- e.watchStats(statsCtx, id)
+	}()
+
+	// A cancelled or expired context ends the wait; the container is then
+	// killed here rather than left running until Docker notices
+	canceled, timedOut := false, false
+	select {
+	case <-waitCh:
+	case err := <-waitErrCh:
+		switch {
+		case ctx.Err() != nil:
+			canceled = true
+			log.Info("Execution cancelled, stopping container")
+		case phaseCtx.Err() != nil:
+			timedOut = true
+		default:
+			stopStats()
+			return nil, nil, fmt.Errorf("failed waiting for container: %w", err)
+		}
+		e.killContainer(id)
+	}
+	duration := time.Since(start)
+
+	if err := <-copyDone; err != nil {
+		log.WithError(err).Debug("Output stream ended with error")
+	}
+	stopStats()
+	usage := <-statsDone
+
+	state, err := e.exitState(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	state.Canceled = canceled
+	state.TimedOut = timedOut
+	state.Truncated = output.Truncated()
+
+	result := &PhaseResult{
+		ExitCode:    state.ExitCode,
+		Duration:    duration,
+		CPUTime:     usage.cpuTime,
+		PeakMemory:  usage.peakMemory,
+		MemoryLimit: uint64(p.memoryMB) << 20,
+		Timeout:     p.timeout,
+		Truncated:   state.Truncated,
+	}
+	result.Reason, result.Signal = classifyTermination(state)
+	result.Stdout, result.Stderr = output.Strings()
+
+	// Files are collected even from failed runs, which may have written
+	// partial results before crashing
+	if p.collectArtifacts {
+		result.Artifacts, result.ArtifactsSkipped, err = e.collectArtifacts(id)
+		if err != nil {
+			log.WithError(err).Warn("Failed to collect output files")
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"exit_code":   result.ExitCode,
+		"reason":      result.Reason,
+		"duration":    result.Duration,
+		"cpu_time":    result.CPUTime,
+		"peak_memory": result.PeakMemory,
+	}).Debug("Phase finished")
+
+	if !p.collectWorkspace || result.Failed() {
+		return result, nil, nil
+	}
+
+	workspace, err := e.archiveWorkspace(ctx, id)
+	if errors.Is(err, errWorkspaceTooLarge) {
+		log.WithError(err).Info("Workspace exceeded the size limit")
+		result.Reason = TerminationWorkspaceLimit
+		return result, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, workspace, nil
+}
+
+// exitState reads the exit code and OOM flag of a stopped container
+func (e *DockerExecutor) exitState(id string) (exitState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	info, err := e.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return exitState{}, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if info.State == nil {
+		return exitState{}, fmt.Errorf("container %s has no state", id)
+	}
+
+	return exitState{ExitCode: info.State.ExitCode, OOMKilled: info.State.OOMKilled}, nil
+}
+
+// createContainer creates a locked-down container for a phase of an execution
+func (e *DockerExecutor) createContainer(ctx context.Context, executionID string, p *phase) (string, error) {
+	containerConfig := &container.Config{
+		Labels:     e.labels(executionID),
+		Image:      p.image,
+		Cmd:        p.command,
+		WorkingDir: workDir,
+		User:       containerUser,
+		Env:        append([]string{"HOME=/tmp"}, p.env...),
+
+		// Stdin is attached once and closed after the input is written
+		AttachStdin: true,
+		OpenStdin:   true,
+		StdinOnce:   true,
+	}
+
+	// The workspace cannot be a sized tmpfs: archives are copied in before
+	// start and out after exit, when a tmpfs is not mounted. A file size
+	// limit bounds what the program writes there instead, and the archive
+	// read after exit is capped as well.
+	pids := int64(pidsLimit)
+	fileSize := int64(e.cfg.MaxWorkspaceBytes)
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode(p.network.dockerNetwork(e.cfg.NetworkName)),
+		CapDrop:     []string{"ALL"},
+		SecurityOpt: []string{"no-new-privileges"},
+		Tmpfs:       map[string]string{"/tmp": "rw,exec,size=64m"},
+		Resources: container.Resources{
+			Memory:    int64(p.memoryMB) << 20,
+			NanoCPUs:  int64(e.cfg.CPULimit * 1e9),
+			PidsLimit: &pids,
+			Ulimits:   []*container.Ulimit{{Name: "fsize", Soft: fileSize, Hard: fileSize}},
+		},
+	}
+
+	// The execution counts as live before the container exists, so the
+	// reconciler cannot remove a container being created
+	e.mu.Lock()
+	e.live[executionID]++
+	e.mu.Unlock()
+
+	resp, err := e.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		e.mu.Lock()
+		e.release(executionID)
+		e.mu.Unlock()
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	e.mu.Lock()
+	e.containers[resp.ID] = executionID
+	e.mu.Unlock()
+
+	return resp.ID, nil
+}
+
+// collectArtifacts reads the output directory of a stopped container
+func (e *DockerExecutor) collectArtifacts(id string) ([]Artifact, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	rc, _, err := e.cli.CopyFromContainer(ctx, id, outDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to copy output directory from container: %w", err)
+	}
+	defer rc.Close()
+
+	return readArtifacts(rc, e.cfg.MaxArtifacts, e.cfg.MaxArtifactBytes)
+}
+
+// archiveWorkspace copies the workspace directory out of a stopped container,
+// failing with errWorkspaceTooLarge once the archive passes the size limit
+func (e *DockerExecutor) archiveWorkspace(ctx context.Context, id string) ([]byte, error) {
+	rc, _, err := e.cli.CopyFromContainer(ctx, id, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy workspace from container: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := readWorkspace(rc, e.cfg.MaxWorkspaceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy workspace from container: %w", err)
+	}
+
+	return data, nil
+}
+
+// killContainer force-stops a running container, ignoring already-stopped ones
+func (e *DockerExecutor) killContainer(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	if err := e.cli.ContainerKill(ctx, id, "KILL"); err != nil && !cerrdefs.IsNotFound(err) {
+		logrus.WithError(err).WithField("container_id", id).Debug("Failed to kill container")
+	}
+}
+
+// removeContainer deletes a container and its anonymous volumes
+func (e *DockerExecutor) removeContainer(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	opts := container.RemoveOptions{Force: true, RemoveVolumes: true}
+	if err := e.cli.ContainerRemove(ctx, id, opts); err != nil && !cerrdefs.IsNotFound(err) {
+		logrus.WithError(err).WithField("container_id", id).Warn("Failed to remove container")
+		return
+	}
+
+	e.mu.Lock()
+	if executionID, ok := e.containers[id]; ok {
+		delete(e.containers, id)
+		e.release(executionID)
+	}
+	e.mu.Unlock()
+}
+
+// release drops one container from an execution's live count; e.mu must be held
+func (e *DockerExecutor) release(executionID string) {
+	if e.live[executionID]--; e.live[executionID] <= 0 {
+		delete(e.live, executionID)
+	}
+}
+
+// ensureImage pulls the image if it is not available locally
+func (e *DockerExecutor) ensureImage(ctx context.Context, ref string) error {
+	if _, err := e.cli.ImageInspect(ctx, ref); err == nil {
+		return nil
+	} else if !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	logrus.WithField("image", ref).Info("Pulling image")
+	rc, err := e.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer rc.Close()
+
+	// The pull only completes once the progress stream has been consumed
+	if _, err := io.Copy(io.Discard, rc); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// ensureNetwork creates the internal execution network if it does not exist
+func (e *DockerExecutor) ensureNetwork(ctx context.Context) error {
+	_, err := e.cli.NetworkInspect(ctx, e.cfg.NetworkName, network.InspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to inspect network %s: %w", e.cfg.NetworkName, err)
+	}
+
+	// Internal networks have no route to the outside world
+	opts := network.CreateOptions{Internal: true, Labels: e.labels("")}
+	_, err = e.cli.NetworkCreate(ctx, e.cfg.NetworkName, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create network %s: %w", e.cfg.NetworkName, err)
+	}
+
+	logrus.WithField("network", e.cfg.NetworkName).Info("Created execution network")
+	return nil
+}
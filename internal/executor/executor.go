@@ -0,0 +1,150 @@
+// Package executor runs user-submitted code inside isolated sandboxes:
+// Docker containers, or Linux processes confined by namespaces, cgroups
+// and rlimits.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// Request describes a single code execution
+type Request struct {
+	// Unique identifier for the execution
+	ID string
+
+	// Language name or alias (e.g. "python", "js")
+	Language string
+
+	// Source code to execute; may be empty when Files holds the entrypoint
+	Code string
+
+	// Additional files placed in the workspace next to the source
+	Files []File
+
+	// Data written to the program's standard input before it is closed
+	Stdin string
+
+	// Command-line arguments passed to the program
+	Args []string
+
+	// Resources for the run phase; the zero tier is standard
+	Tier Tier
+
+	// Network for the run phase; the zero mode is isolated
+	Network NetworkMode
+
+	// Optional live copy of captured output from both phases, written as
+	// it is produced; writes must not block
+	Output io.Writer
+}
+
+// Result holds the outcome of an execution. The embedded PhaseResult
+// describes the run phase.
+type Result struct {
+	PhaseResult
+
+	// Canonical name of the language that was executed
+	Language string
+
+	// Outcome of the compile phase, nil for interpreted languages
+	Compile *PhaseResult
+}
+
+// CompileFailed reports whether the program never ran because compilation failed
+func (r *Result) CompileFailed() bool {
+	return r.Compile != nil && r.Compile.Failed()
+}
+
+// PhaseResult holds the outcome of a single execution phase
+type PhaseResult struct {
+	// Captured standard output (bounded by the output limit)
+	Stdout string
+
+	// Captured standard error (bounded by the output limit)
+	Stderr string
+
+	// Process exit code
+	ExitCode int
+
+	// Why the phase ended
+	Reason TerminationReason
+
+	// Signal that killed the process when Reason is TerminationSignal
+	Signal int
+
+	// Wall-clock time between container start and exit
+	Duration time.Duration
+
+	// CPU time consumed, as last reported by container stats
+	CPUTime time.Duration
+
+	// Peak memory usage observed in container stats (in bytes)
+	PeakMemory uint64
+
+	// Memory limit the phase ran with (in bytes)
+	MemoryLimit uint64
+
+	// Wall-clock limit the phase ran with
+	Timeout time.Duration
+
+	// Whether output exceeded the capture limit and was cut
+	Truncated bool
+
+	// Files the program wrote to the output directory
+	Artifacts []Artifact
+
+	// Whether output files were left out for exceeding the artifact limits
+	ArtifactsSkipped bool
+}
+
+// Output returns stdout followed by stderr
+func (p *PhaseResult) Output() string {
+	return p.Stdout + p.Stderr
+}
+
+// Failed reports whether the phase did not complete successfully
+func (p *PhaseResult) Failed() bool {
+	return p.Reason != TerminationSuccess
+}
+
+// Executor runs code and reports the result
+type Executor interface {
+	// Execute runs the request to completion and returns its result.
+	// Cancelling ctx kills the running program; the result then reports
+	// TerminationCanceled, or ctx's error is returned if it had not started.
+	Execute(ctx context.Context, req *Request) (*Result, error)
+}
+
+// Backend is an Executor holding resources that Close releases
+type Backend interface {
+	Executor
+
+	// Close stops the backend and removes what its executions left behind
+	Close() error
+}
+
+// New starts the backend selected by cfg; every backend applies the limits
+// in docker
+func New(ctx context.Context, cfg config.ExecutorConfig, docker config.DockerConfig) (Backend, error) {
+	switch cfg.Backend {
+	case config.BackendDocker:
+		e, err := NewDockerExecutor(ctx, docker)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	case config.BackendSandbox:
+		e, err := NewSandboxExecutor(cfg.Sandbox, docker)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	default:
+		return nil, fmt.Errorf("unknown executor backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,206 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Placeholders substituted in compile and run commands
+const (
+	// Workspace-relative path of the entrypoint file, e.g. "app.py"
+	entrypointPlaceholder = "{entrypoint}"
+
+	// Entrypoint file name without directory or extension, e.g. "Main"
+	stemPlaceholder = "{stem}"
+)
+
+// Language describes how source code for a programming language is run
+type Language struct {
+	// Canonical language name
+	Name string
+
+	// Alternative names accepted from users
+	Aliases []string
+
+	// Docker image the code runs in
+	Image string
+
+	// File name inline source is written to, and the preferred entrypoint
+	// among uploaded files
+	FileName string
+
+	// Source file extensions, used to pick an entrypoint among uploads
+	Extensions []string
+
+	// Extra environment variables for both phases
+	Env []string
+
+	// Command that builds the workspace; nil for interpreted languages
+	Compile []string
+
+	// Command that runs the program
+	Command []string
+
+	// Command starting an interpreter that reads statements from stdin
+	// without prompts; nil for languages without interactive sessions
+	REPL []string
+}
+
+// Interactive reports whether the language supports interactive sessions
+func (l *Language) Interactive() bool {
+	return l.REPL != nil
+}
+
+// Entrypoint picks the file to run among the uploaded workspace paths:
+// FileName if present, otherwise the only top-level file with a matching
+// extension
+func (l *Language) Entrypoint(paths []string) (string, error) {
+	var candidates []string
+	for _, p := range paths {
+		if p == l.FileName {
+			return p, nil
+		}
+		if !strings.Contains(p, "/") && slices.Contains(l.Extensions, path.Ext(p)) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("cannot pick an entrypoint: upload %s or a single top-level %s file",
+		l.FileName, strings.Join(l.Extensions, "/"))
+}
+
+// expandCommand substitutes the entrypoint placeholders in a command
+func expandCommand(command []string, entrypoint string) []string {
+	stem := strings.TrimSuffix(path.Base(entrypoint), path.Ext(entrypoint))
+	replacer := strings.NewReplacer(entrypointPlaceholder, entrypoint, stemPlaceholder, stem)
+
+	expanded := make([]string, len(command))
+	for i, arg := range command {
+		expanded[i] = replacer.Replace(arg)
+	}
+	return expanded
+}
+
+// languages lists every supported language. Compiled languages build every
+// top-level source file in the workspace so multi-file uploads work.
+var languages = []Language{
+	{
+		Name:       "python",
+		Aliases:    []string{"py", "python3"},
+		Image:      "python:3.12-alpine",
+		FileName:   "main.py",
+		Extensions: []string{".py"},
+		Command:    []string{"python3", entrypointPlaceholder},
+		REPL:       []string{"python3", "-u", "-i", "-c", "import sys; sys.ps1 = sys.ps2 = ''"},
+	},
+	{
+		Name:       "javascript",
+		Aliases:    []string{"js", "node"},
+		Image:      "node:20-alpine",
+		FileName:   "main.js",
+		Extensions: []string{".js", ".mjs"},
+		Command:    []string{"node", entrypointPlaceholder},
+		REPL:       []string{"node", "-e", "require('repl').start({prompt: ''})"},
+	},
+	{
+		Name:       "go",
+		Aliases:    []string{"golang"},
+		Image:      "golang:1.22-alpine",
+		FileName:   "main.go",
+		Extensions: []string{".go"},
+		// Build in GOPATH mode unless the upload includes a go.mod
+		Env:     []string{"GO111MODULE=auto"},
+		Compile: []string{"go", "build", "-o", "main", "."},
+		Command: []string{"./main"},
+	},
+	{
+		Name:       "rust",
+		Aliases:    []string{"rs"},
+		Image:      "rust:1-slim",
+		FileName:   "main.rs",
+		Extensions: []string{".rs"},
+		// rustc follows mod declarations from the entrypoint
+		Compile: []string{"rustc", "-O", "-o", "main", entrypointPlaceholder},
+		Command: []string{"./main"},
+	},
+	{
+		Name:       "c",
+		Image:      "gcc:14",
+		FileName:   "main.c",
+		Extensions: []string{".c"},
+		Compile:    []string{"sh", "-c", "gcc -O2 -o main *.c -lm"},
+		Command:    []string{"./main"},
+	},
+	{
+		Name:       "cpp",
+		Aliases:    []string{"c++", "cxx"},
+		Image:      "gcc:14",
+		FileName:   "main.cpp",
+		Extensions: []string{".cpp"},
+		Compile:    []string{"sh", "-c", "g++ -O2 -std=c++20 -o main *.cpp"},
+		Command:    []string{"./main"},
+	},
+	{
+		Name:       "java",
+		Image:      "eclipse-temurin:21-jdk",
+		FileName:   "Main.java",
+		Extensions: []string{".java"},
+		Compile:    []string{"sh", "-c", "javac *.java"},
+		Command:    []string{"java", "-cp", ".", stemPlaceholder},
+	},
+	{
+		Name:       "bash",
+		Aliases:    []string{"sh", "shell"},
+		Image:      "bash:5",
+		FileName:   "main.sh",
+		Extensions: []string{".sh", ".bash"},
+		Command:    []string{"bash", entrypointPlaceholder},
+		REPL:       []string{"bash"},
+	},
+}
+
+// LookupLanguage finds a language by name or alias (case-insensitive)
+func LookupLanguage(name string) (*Language, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i := range languages {
+		lang := &languages[i]
+		if lang.Name == name {
+			return lang, true
+		}
+		for _, alias := range lang.Aliases {
+			if alias == name {
+				return lang, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// LanguageNames returns the canonical names of all supported languages
+func LanguageNames() []string {
+	names := make([]string, 0, len(languages))
+	for i := range languages {
+		names = append(names, languages[i].Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InteractiveLanguageNames returns the canonical names of languages that
+// support interactive sessions
+func InteractiveLanguageNames() []string {
+	var names []string
+	for i := range languages {
+		if languages[i].Interactive() {
+			names = append(names, languages[i].Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// outputCapture collects stdout and stderr up to a shared byte limit.
+// Once the limit is reached further output is discarded and onExceed is
+// called exactly once so the caller can stop the running process.
+type outputCapture struct {
+	mu        sync.Mutex
+	limit     int
+	written   int
+	truncated bool
+	onExceed  func()
+	stdout    bytes.Buffer
+	stderr    bytes.Buffer
+
+	// Optional writer receiving kept output from both streams in order
+	live io.Writer
+}
+
+// newOutputCapture creates a capture that keeps at most limit bytes
+func newOutputCapture(limit int, onExceed func()) *outputCapture {
+	return &outputCapture{limit: limit, onExceed: onExceed}
+}
+
+// Stdout returns a writer for the standard output stream
+func (c *outputCapture) Stdout() io.Writer {
+	return &captureWriter{capture: c, buf: &c.stdout}
+}
+
+// Stderr returns a writer for the standard error stream
+func (c *outputCapture) Stderr() io.Writer {
+	return &captureWriter{capture: c, buf: &c.stderr}
+}
+
+// Truncated reports whether any output was discarded
+func (c *outputCapture) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.truncated
+}
+
+// Strings returns the captured stdout and stderr
+func (c *outputCapture) Strings() (stdout, stderr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stdout.String(), c.stderr.String()
+}
+
+// write appends p to buf while honoring the shared limit
+func (c *outputCapture) write(buf *bytes.Buffer, p []byte) {
+	c.mu.Lock()
+	remaining := c.limit - c.written
+	if len(p) <= remaining {
+		c.keep(buf, p)
+		c.mu.Unlock()
+		return
+	}
+
+	if remaining > 0 {
+		c.keep(buf, p[:remaining])
+	}
+	first := !c.truncated
+	c.truncated = true
+	c.mu.Unlock()
+
+	if first && c.onExceed != nil {
+		c.onExceed()
+	}
+}
+
+// keep stores p and copies it to the live writer; the caller holds mu
+func (c *outputCapture) keep(buf *bytes.Buffer, p []byte) {
+	buf.Write(p)
+	c.written += len(p)
+	if c.live != nil {
+		_, _ = c.live.Write(p)
+	}
+}
+
+// captureWriter feeds a single stream into an outputCapture
+type captureWriter struct {
+	capture *outputCapture
+	buf     *bytes.Buffer
+}
+
+// Write always reports success so the stream keeps draining after the limit
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.capture.write(w.buf, p)
+	return len(p), nil
+}
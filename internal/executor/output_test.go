@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutputCaptureWithinLimit(t *testing.T) {
+	exceeded := 0
+	capture := newOutputCapture(16, func() { exceeded++ })
+
+	if _, err := capture.Stdout().Write([]byte("hello ")); err != nil {
+		t.Fatalf("Unexpected write error: %v", err)
+	}
+	if _, err := capture.Stderr().Write([]byte("world")); err != nil {
+		t.Fatalf("Unexpected write error: %v", err)
+	}
+
+	stdout, stderr := capture.Strings()
+	if stdout != "hello " || stderr != "world" {
+		t.Errorf("Expected 'hello '/'world', got '%s'/'%s'", stdout, stderr)
+	}
+	if capture.Truncated() {
+		t.Error("Expected output not to be truncated")
+	}
+	if exceeded != 0 {
+		t.Errorf("Expected onExceed not to be called, got %d calls", exceeded)
+	}
+}
+
+func TestOutputCaptureTruncates(t *testing.T) {
+	exceeded := 0
+	capture := newOutputCapture(10, func() { exceeded++ })
+
+	stdout := capture.Stdout()
+	stderr := capture.Stderr()
+
+	n, err := stdout.Write([]byte("12345678"))
+	if err != nil || n != 8 {
+		t.Fatalf("Expected full write, got n=%d err=%v", n, err)
+	}
+
+	// Writes past the limit must still report success so the stream keeps draining
+	n, err = stderr.Write([]byte("abcdef"))
+	if err != nil || n != 6 {
+		t.Fatalf("Expected write past limit to succeed, got n=%d err=%v", n, err)
+	}
+	if _, err := stdout.Write([]byte(strings.Repeat("x", 100))); err != nil {
+		t.Fatalf("Unexpected write error: %v", err)
+	}
+
+	out, errOut := capture.Strings()
+	if out != "12345678" {
+		t.Errorf("Expected stdout '12345678', got '%s'", out)
+	}
+	if errOut != "ab" {
+		t.Errorf("Expected stderr 'ab', got '%s'", errOut)
+	}
+	if !capture.Truncated() {
+		t.Error("Expected output to be truncated")
+	}
+	if exceeded != 1 {
+		t.Errorf("Expected onExceed to be called once, got %d calls", exceeded)
+	}
+}
+
+func TestOutputCaptureLive(t *testing.T) {
+	var live strings.Builder
+	capture := newOutputCapture(8, nil)
+	capture.live = &live
+
+	_, _ = capture.Stdout().Write([]byte("out "))
+	_, _ = capture.Stderr().Write([]byte("err "))
+	_, _ = capture.Stdout().Write([]byte("dropped"))
+
+	if live.String() != "out err " {
+		t.Errorf("Expected live copy of kept output in order, got '%s'", live.String())
+	}
+}
@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// Execution phase names
+const (
+	phaseCompile = "compile"
+	phaseRun     = "run"
+	phaseSession = "session"
+)
+
+// phase describes a single sandboxed run within an execution
+type phase struct {
+	name     string
+	image    string
+	command  []string
+	env      []string
+	timeout  time.Duration
+	memoryMB int
+	stdin    string
+
+	// Network the program joins; the zero mode is isolated
+	network NetworkMode
+
+	// Tar archive of the workspace directory, extracted before start
+	workspace []byte
+
+	// Optional live copy of captured output
+	stream io.Writer
+
+	// Whether to archive the workspace after exit for a following phase
+	collectWorkspace bool
+
+	// Whether to provide the output directory and collect files written to it
+	collectArtifacts bool
+}
+
+// errToolchainMissing is returned when a backend cannot run a language on this host
+var errToolchainMissing = errors.New("language toolchain is not installed")
+
+// phaseRunner is implemented by each backend to run phases in fresh sandboxes
+type phaseRunner interface {
+	// prepare makes the language's toolchain available before its first phase
+	prepare(ctx context.Context, lang *Language) error
+
+	// runPhase runs one phase to completion. When the phase asks for it, the
+	// workspace is archived after exit and returned as well.
+	runPhase(ctx context.Context, executionID string, p *phase) (*PhaseResult, []byte, error)
+}
+
+// execute runs a request on a backend with the configured limits. Compiled
+// languages first build in a compile phase with its own limits; the
+// resulting workspace is then extracted for the run phase.
+func execute(ctx context.Context, r phaseRunner, cfg config.DockerConfig, req *Request) (*Result, error) {
+	lang, ok := LookupLanguage(req.Language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", req.Language)
+	}
+	if len(req.Stdin) > cfg.MaxStdinBytes {
+		return nil, fmt.Errorf("stdin is %d bytes, limit is %d", len(req.Stdin), cfg.MaxStdinBytes)
+	}
+
+	if err := r.prepare(ctx, lang); err != nil {
+		return nil, err
+	}
+
+	files, entrypoint, err := workspaceFiles(lang, req)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := workspaceArchive(files)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Language: lang.Name}
+
+	if lang.Compile != nil {
+		compile, compiled, err := r.runPhase(ctx, req.ID, &phase{
+			name:             phaseCompile,
+			image:            lang.Image,
+			command:          expandCommand(lang.Compile, entrypoint),
+			env:              lang.Env,
+			timeout:          time.Duration(cfg.CompileTimeout) * time.Second,
+			memoryMB:         cfg.CompileMemoryLimit,
+			workspace:        workspace,
+			stream:           req.Output,
+			collectWorkspace: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Compile = compile
+		if compile.Failed() {
+			result.ExitCode = compile.ExitCode
+			return result, nil
+		}
+		workspace = compiled
+	}
+
+	timeout := req.Tier.timeout(time.Duration(cfg.DefaultTimeout)*time.Second, time.Duration(cfg.MaxRuntime)*time.Second)
+	run, _, err := r.runPhase(ctx, req.ID, &phase{
+		name:      phaseRun,
+		image:     lang.Image,
+		command:   append(expandCommand(lang.Command, entrypoint), req.Args...),
+		env:       lang.Env,
+		timeout:   timeout,
+		memoryMB:  req.Tier.memoryMB(cfg.MemoryLimit),
+		network:   req.Network,
+		stdin:     req.Stdin,
+		workspace: workspace,
+		stream:    req.Output,
+
+		// A zero limit disables the output directory entirely
+		collectArtifacts: cfg.MaxArtifacts > 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.PhaseResult = *run
+
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/sirupsen/logrus"
+)
+
+// Labels set on every container, volume and network the executor creates
+const (
+	labelInstance  = "discord-command-executor.instance"
+	labelExecution = "discord-command-executor.execution"
+)
+
+// Resource kinds, also the keys of the orphaned resource metric
+const (
+	kindContainers = "containers"
+	kindVolumes    = "volumes"
+	kindNetworks   = "networks"
+)
+
+// Time a reconciliation pass may take in total
+const reconcileTimeout = time.Minute
+
+// orphansRemoved counts resources removed by the reconciler, by kind
+var orphansRemoved = expvar.NewMap("orphans_removed")
+
+// labels returns the labels for a resource of an execution; resources shared
+// by executions carry the instance label only
+func (e *DockerExecutor) labels(executionID string) map[string]string {
+	labels := map[string]string{labelInstance: e.cfg.InstanceID}
+	if executionID != "" {
+		labels[labelExecution] = executionID
+	}
+	return labels
+}
+
+// instanceFilter selects the resources labeled with this instance
+func (e *DockerExecutor) instanceFilter() filters.Args {
+	return filters.NewArgs(filters.Arg("label", labelInstance+"="+e.cfg.InstanceID))
+}
+
+// orphaned reports whether a labeled resource belongs to no live execution
+func (e *DockerExecutor) orphaned(containerID string, labels map[string]string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.containers[containerID]; ok {
+		return false
+	}
+	return e.live[labels[labelExecution]] == 0
+}
+
+// Reconcile removes containers, volumes and networks labeled with this
+// instance that no live execution owns, such as those left by a crash.
+// The execution network in use stays, as it is shared by every execution.
+func (e *DockerExecutor) Reconcile(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+
+	removed := map[string]int{
+		kindContainers: e.reconcileContainers(ctx),
+		kindVolumes:    e.reconcileVolumes(ctx),
+		kindNetworks:   e.reconcileNetworks(ctx),
+	}
+
+	log := logrus.WithField("instance", e.cfg.InstanceID)
+	total := 0
+	for kind, n := range removed {
+		orphansRemoved.Add(kind, int64(n))
+		log = log.WithField(kind, n)
+		total += n
+	}
+	if total > 0 {
+		log.Warn("Removed orphaned resources")
+	} else {
+		log.Debug("No orphaned resources found")
+	}
+}
+
+// reconcileEvery runs Reconcile at the interval until Close
+func (e *DockerExecutor) reconcileEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.Reconcile(context.Background())
+		}
+	}
+}
+
+// reconcileContainers removes orphaned containers, returning how many
+func (e *DockerExecutor) reconcileContainers(ctx context.Context) int {
+	containers, err := e.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: e.instanceFilter()})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to list containers for reconciliation")
+		return 0
+	}
+
+	removed := 0
+	for _, c := range containers {
+		if !e.orphaned(c.ID, c.Labels) {
+			continue
+		}
+		opts := container.RemoveOptions{Force: true, RemoveVolumes: true}
+		if err := e.cli.ContainerRemove(ctx, c.ID, opts); err != nil && !cerrdefs.IsNotFound(err) {
+			logrus.WithError(err).WithField("container_id", c.ID).Warn("Failed to remove orphaned container")
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// reconcileVolumes removes orphaned volumes, returning how many
+func (e *DockerExecutor) reconcileVolumes(ctx context.Context) int {
+	resp, err := e.cli.VolumeList(ctx, volume.ListOptions{Filters: e.instanceFilter()})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to list volumes for reconciliation")
+		return 0
+	}
+
+	removed := 0
+	for _, v := range resp.Volumes {
+		if !e.orphaned("", v.Labels) {
+			continue
+		}
+		// Volumes still mounted are refused by the daemon and retried next pass
+		if err := e.cli.VolumeRemove(ctx, v.Name, false); err != nil && !cerrdefs.IsNotFound(err) {
+			logrus.WithError(err).WithField("volume", v.Name).Warn("Failed to remove orphaned volume")
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// reconcileNetworks removes labeled networks other than the execution
+// network once no container is attached, returning how many
+func (e *DockerExecutor) reconcileNetworks(ctx context.Context) int {
+	networks, err := e.cli.NetworkList(ctx, network.ListOptions{Filters: e.instanceFilter()})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to list networks for reconciliation")
+		return 0
+	}
+
+	removed := 0
+	for _, n := range networks {
+		if n.Name == e.cfg.NetworkName || !e.orphaned("", n.Labels) {
+			continue
+		}
+		// Listing leaves out attached containers, so each network is inspected
+		inspect, err := e.cli.NetworkInspect(ctx, n.ID, network.InspectOptions{})
+		if err != nil || len(inspect.Containers) > 0 {
+			continue
+		}
+		if err := e.cli.NetworkRemove(ctx, n.ID); err != nil && !cerrdefs.IsNotFound(err) {
+			logrus.WithError(err).WithField("network", n.Name).Warn("Failed to remove orphaned network")
+			continue
+		}
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+func TestOrphaned(t *testing.T) {
+	e := &DockerExecutor{
+		cfg:        config.DockerConfig{InstanceID: "bot-1"},
+		containers: map[string]string{"c1": "e1"},
+		live:       map[string]int{"e1": 1, "e2": 1},
+	}
+
+	labels := e.labels("e2")
+	if labels[labelInstance] != "bot-1" || labels[labelExecution] != "e2" {
+		t.Errorf("Expected instance and execution labels, got %v", labels)
+	}
+	if _, ok := e.labels("")[labelExecution]; ok {
+		t.Error("Expected shared resources to carry no execution label")
+	}
+
+	if e.orphaned("c1", nil) {
+		t.Error("Expected a tracked container to be kept")
+	}
+	if e.orphaned("c2", labels) {
+		t.Error("Expected a container of an execution being created to be kept")
+	}
+	if !e.orphaned("c3", e.labels("e3")) {
+		t.Error("Expected a container of a finished execution to be orphaned")
+	}
+
+	e.release("e2")
+	if !e.orphaned("c2", labels) {
+		t.Error("Expected the container to be orphaned once its execution released it")
+	}
+	if _, ok := e.live["e2"]; ok {
+		t.Error("Expected released executions to be dropped")
+	}
+}
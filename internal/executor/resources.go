@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tier selects the resources the run phase gets, relative to the configured
+// memory limit and default timeout
+type Tier string
+
+// Resource tiers, from least to most resources
+const (
+	// Half the configured memory and time
+	TierSmall Tier = "small"
+
+	// The configured memory and time
+	TierStandard Tier = "standard"
+
+	// Twice the configured memory and time; time stays within the max runtime
+	TierLarge Tier = "large"
+)
+
+// tiers lists the resource tiers from least to most resources
+var tiers = []Tier{TierSmall, TierStandard, TierLarge}
+
+// ParseTier returns the tier with the given name
+func ParseTier(name string) (Tier, error) {
+	for _, t := range tiers {
+		if string(t) == strings.ToLower(name) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown resource tier %q; use one of: %s", name, strings.Join(TierNames(), ", "))
+}
+
+// TierNames returns the tier names from least to most resources
+func TierNames() []string {
+	names := make([]string, len(tiers))
+	for i, t := range tiers {
+		names[i] = string(t)
+	}
+	return names
+}
+
+// Rank orders tiers by resources; the zero tier ranks as standard
+func (t Tier) Rank() int {
+	if t == "" {
+		t = TierStandard
+	}
+	for i, candidate := range tiers {
+		if candidate == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// memoryMB scales a memory limit to the tier
+func (t Tier) memoryMB(base int) int {
+	switch t {
+	case TierSmall:
+		return base / 2
+	case TierLarge:
+		return base * 2
+	default:
+		return base
+	}
+}
+
+// timeout scales a wall-clock limit to the tier, keeping it within limit
+// when limit is positive
+func (t Tier) timeout(base, limit time.Duration) time.Duration {
+	switch t {
+	case TierSmall:
+		base /= 2
+	case TierLarge:
+		base *= 2
+	}
+	if limit > 0 && base > limit {
+		base = limit
+	}
+	return max(base, time.Second)
+}
+
+// NetworkMode selects the network the run container joins
+type NetworkMode string
+
+// Network modes
+const (
+	// Loopback only
+	NetworkNone NetworkMode = "none"
+
+	// The internal execution network, without outside access
+	NetworkIsolated NetworkMode = "isolated"
+
+	// Docker's default bridge network, with outside access
+	NetworkInternet NetworkMode = "internet"
+)
+
+// networkModes lists the network modes from most to least restricted
+var networkModes = []NetworkMode{NetworkNone, NetworkIsolated, NetworkInternet}
+
+// ParseNetworkMode returns the network mode with the given name
+func ParseNetworkMode(name string) (NetworkMode, error) {
+	for _, m := range networkModes {
+		if string(m) == strings.ToLower(name) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown network mode %q; use one of: %s", name, strings.Join(NetworkModeNames(), ", "))
+}
+
+// NetworkModeNames returns the network mode names from most to least restricted
+func NetworkModeNames() []string {
+	names := make([]string, len(networkModes))
+	for i, m := range networkModes {
+		names[i] = string(m)
+	}
+	return names
+}
+
+// dockerNetwork returns the Docker network mode for a network mode; the
+// zero mode is isolated
+func (m NetworkMode) dockerNetwork(isolated string) string {
+	switch m {
+	case NetworkNone:
+		return "none"
+	case NetworkInternet:
+		return "bridge"
+	default:
+		return isolated
+	}
+}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTier(t *testing.T) {
+	if tier, err := ParseTier("Large"); err != nil || tier != TierLarge {
+		t.Errorf("Expected large tier, got %q, %v", tier, err)
+	}
+	if _, err := ParseTier("huge"); err == nil {
+		t.Error("Expected error for unknown tier")
+	}
+}
+
+func TestTierRank(t *testing.T) {
+	if TierSmall.Rank() >= TierStandard.Rank() || TierStandard.Rank() >= TierLarge.Rank() {
+		t.Error("Expected tiers to rank small < standard < large")
+	}
+	if Tier("").Rank() != TierStandard.Rank() {
+		t.Error("Expected the zero tier to rank as standard")
+	}
+	if Tier("huge").Rank() != -1 {
+		t.Error("Expected unknown tiers to rank -1")
+	}
+}
+
+func TestTierLimits(t *testing.T) {
+	tests := []struct {
+		tier    Tier
+		memory  int
+		timeout time.Duration
+	}{
+		{tier: "", memory: 128, timeout: 30 * time.Second},
+		{tier: TierSmall, memory: 64, timeout: 15 * time.Second},
+		{tier: TierStandard, memory: 128, timeout: 30 * time.Second},
+		{tier: TierLarge, memory: 256, timeout: 45 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.tier), func(t *testing.T) {
+			if got := tt.tier.memoryMB(128); got != tt.memory {
+				t.Errorf("Expected %d MB, got %d", tt.memory, got)
+			}
+			if got := tt.tier.timeout(30*time.Second, 45*time.Second); got != tt.timeout {
+				t.Errorf("Expected %s, got %s", tt.timeout, got)
+			}
+		})
+	}
+}
+
+func TestNetworkModes(t *testing.T) {
+	if mode, err := ParseNetworkMode("internet"); err != nil || mode != NetworkInternet {
+		t.Errorf("Expected internet mode, got %q, %v", mode, err)
+	}
+	if _, err := ParseNetworkMode("host"); err == nil {
+		t.Error("Expected error for unknown network mode")
+	}
+
+	tests := map[NetworkMode]string{
+		"":              "discord-executor",
+		NetworkIsolated: "discord-executor",
+		NetworkNone:     "none",
+		NetworkInternet: "bridge",
+	}
+	for mode, want := range tests {
+		if got := mode.dockerNetwork("discord-executor"); got != want {
+			t.Errorf("Expected %q for mode %q, got %q", want, mode, got)
+		}
+	}
+}
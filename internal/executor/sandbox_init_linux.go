@@ -0,0 +1,609 @@
+//go:build linux
+
+package executor
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Sizes of the writable file systems in the sandbox besides the workspace,
+// which is sized by the workspace limit
+const (
+	sandboxTmpSize = "64m"
+	sandboxOutSize = "64m"
+)
+
+// Device nodes bound from the host into the sandbox's /dev
+var sandboxDevices = []string{"null", "zero", "full", "random", "urandom"}
+
+// The sandbox init process is the bot itself, re-executed with a marker
+// argv[0]. It takes over before main runs and never returns.
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == sandboxInitArg {
+		os.Exit(sandboxInit())
+	}
+}
+
+// sandboxInit builds the sandbox inside fresh namespaces, runs the program
+// as its only child and archives its output once everything has exited.
+// It returns the program's exit code.
+func sandboxInit() int {
+	// Capabilities are per thread, so the thread dropping them must be the
+	// one starting the program
+	runtime.LockOSThread()
+
+	// As init of its PID namespace the process ignores signals it has no
+	// handler for, so the handler is registered first
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM)
+
+	for fd := sandboxStatusFD; fd <= sandboxArtifactsFD; fd++ {
+		syscall.CloseOnExec(fd)
+	}
+	status := os.NewFile(sandboxStatusFD, "status")
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxSpecEnv)), &spec); err != nil {
+		fmt.Fprintf(status, "invalid spec: %v", err)
+		return 1
+	}
+	cmd, err := spec.start()
+	if err != nil {
+		fmt.Fprint(status, err)
+		return 1
+	}
+	status.Close()
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- cmd.Wait()
+	}()
+	select {
+	case <-waitDone:
+	case <-stop:
+		_ = syscall.Kill(-1, syscall.SIGKILL)
+		<-waitDone
+	}
+
+	// Background processes must be gone before their files are archived
+	killAll()
+
+	code := sandboxExitCode(cmd.ProcessState)
+	if spec.Artifacts {
+		if err := archiveDir(os.NewFile(sandboxArtifactsFD, "artifacts"), outDir, "out"); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		}
+	}
+	if spec.CollectWorkspace {
+		if err := archiveDir(os.NewFile(sandboxWorkspaceOutFD, "workspace"), workDir, "workspace"); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		}
+	}
+	return code
+}
+
+// killAll kills every other process in the PID namespace and reaps them
+func killAll() {
+	_ = syscall.Kill(-1, syscall.SIGKILL)
+	for {
+		_, err := syscall.Wait4(-1, nil, 0, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// start builds the sandbox root, switches into it and starts the program
+func (s *sandboxSpec) start() (*exec.Cmd, error) {
+	syscall.Umask(0)
+
+	if err := s.buildRoot(); err != nil {
+		return nil, err
+	}
+	workspace := os.NewFile(sandboxWorkspaceInFD, "workspace")
+	if err := extractWorkspace(workspace, filepath.Join(s.Root, workDir)); err != nil {
+		return nil, err
+	}
+	workspace.Close()
+
+	if err := pivotRoot(s.Root); err != nil {
+		return nil, err
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return nil, fmt.Errorf("failed to set hostname: %w", err)
+	}
+	if err := loopbackUp(); err != nil {
+		return nil, err
+	}
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return nil, fmt.Errorf("failed to disable core dumps: %w", err)
+	}
+	if err := dropPrivileges(); err != nil {
+		return nil, err
+	}
+
+	// The command is looked up in the sandbox's search path
+	for _, env := range s.Env {
+		if p, ok := strings.CutPrefix(env, "PATH="); ok {
+			os.Setenv("PATH", p)
+		}
+	}
+	if len(s.Command) == 0 {
+		return nil, errors.New("no command")
+	}
+	cmd := exec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = s.Env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL, Ptrace: s.rlimited()}
+	if s.Nobody {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: nobodyID, Gid: nobodyID}
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	if s.rlimited() {
+		if err := s.limit(cmd.Process.Pid); err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// buildRoot mounts the new root file system: read-only host directories,
+// fresh writable directories, /proc and a minimal /dev
+func (s *sandboxSpec) buildRoot() error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	if err := mountTmpfs(s.Root, "size=1m,mode=0755"); err != nil {
+		return err
+	}
+
+	for _, p := range s.ReadOnlyPaths {
+		if err := bindReadOnly(p, filepath.Join(s.Root, p)); err != nil {
+			return err
+		}
+	}
+
+	writable := []struct{ dir, opts string }{
+		{workDir, fmt.Sprintf("size=%d,mode=0777", s.WorkspaceBytes)},
+		{"/tmp", "size=" + sandboxTmpSize + ",mode=1777"},
+		{"/dev", "size=64k,mode=0755"},
+	}
+	if s.Artifacts {
+		writable = append(writable, struct{ dir, opts string }{outDir, "size=" + sandboxOutSize + ",mode=0777"})
+	}
+	for _, w := range writable {
+		if err := mountTmpfs(filepath.Join(s.Root, w.dir), w.opts); err != nil {
+			return err
+		}
+	}
+
+	proc := filepath.Join(s.Root, "proc")
+	if err := os.MkdirAll(proc, 0o555); err != nil {
+		return fmt.Errorf("failed to create /proc: %w", err)
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	return populateDev(filepath.Join(s.Root, "dev"))
+}
+
+// mountTmpfs mounts a fresh tmpfs at dir, creating it first
+func mountTmpfs(dir, opts string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, opts); err != nil {
+		return fmt.Errorf("failed to mount tmpfs at %s: %w", dir, err)
+	}
+	return nil
+}
+
+// bindReadOnly makes a host path visible read-only at target. Symlinks
+// such as /lib on merged-usr systems are recreated instead; missing paths
+// are skipped.
+func bindReadOnly(source, target string) error {
+	info, err := os.Lstat(source)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", source, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return fmt.Errorf("failed to read link %s: %w", source, err)
+		}
+		return os.Symlink(link, target)
+	case info.IsDir():
+		err = os.Mkdir(target, 0o755)
+	default:
+		err = os.WriteFile(target, nil, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+
+	if err := unix.Mount(source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind %s: %w", source, err)
+	}
+	return remountReadOnly(target)
+}
+
+// remountReadOnly makes a bind mount read-only. Flags the mount already
+// has are kept, as a user namespace may not clear them.
+func remountReadOnly(target string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", target, err)
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	for _, f := range []struct{ st, ms uintptr }{
+		{unix.ST_NOSUID, unix.MS_NOSUID},
+		{unix.ST_NODEV, unix.MS_NODEV},
+		{unix.ST_NOEXEC, unix.MS_NOEXEC},
+		{unix.ST_NOATIME, unix.MS_NOATIME},
+		{unix.ST_NODIRATIME, unix.MS_NODIRATIME},
+		{unix.ST_RELATIME, unix.MS_RELATIME},
+	} {
+		if uintptr(st.Flags)&f.st != 0 {
+			flags |= f.ms
+		}
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make %s read-only: %w", target, err)
+	}
+	return nil
+}
+
+// populateDev binds the harmless host devices and adds the usual links
+func populateDev(dev string) error {
+	for _, name := range sandboxDevices {
+		target := filepath.Join(dev, name)
+		if err := os.WriteFile(target, nil, 0o666); err != nil {
+			return fmt.Errorf("failed to create %s: %w", target, err)
+		}
+		if err := unix.Mount(filepath.Join("/dev", name), target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("failed to bind /dev/%s: %w", name, err)
+		}
+	}
+
+	links := map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("failed to link /dev/%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// pivotRoot switches to the new root, detaches the host's file systems
+// and makes the root itself read-only
+func pivotRoot(root string) error {
+	old := filepath.Join(root, ".old")
+	if err := os.Mkdir(old, 0o700); err != nil {
+		return fmt.Errorf("failed to create old root: %w", err)
+	}
+	if err := unix.PivotRoot(root, old); err != nil {
+		return fmt.Errorf("failed to pivot root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return fmt.Errorf("failed to enter new root: %w", err)
+	}
+	if err := unix.Unmount("/.old", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach old root: %w", err)
+	}
+	if err := os.Remove("/.old"); err != nil {
+		return fmt.Errorf("failed to remove old root: %w", err)
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV)
+	if err := unix.Mount("", "/", "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make root read-only: %w", err)
+	}
+	return nil
+}
+
+// loopbackUp brings up the loopback interface of the new network namespace,
+// which starts down
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return fmt.Errorf("failed to configure loopback: %w", err)
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("failed to configure loopback: %w", err)
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	if err := unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("failed to configure loopback: %w", err)
+	}
+	return nil
+}
+
+// rlimited reports whether memory and processes are limited with rlimits
+func (s *sandboxSpec) rlimited() bool {
+	return s.RlimitMemory > 0 || s.RlimitProcs > 0
+}
+
+// limit applies the rlimits to the program, which is stopped by tracing
+// right after exec so that none of its code runs without them. They are
+// never set on the init process, whose runtime would then fail to start
+// threads.
+func (s *sandboxSpec) limit(pid int) error {
+	var status unix.WaitStatus
+	for {
+		_, err := unix.Wait4(pid, &status, 0, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to wait for program to start: %w", err)
+		}
+		break
+	}
+	if !status.Stopped() || status.StopSignal() != unix.SIGTRAP {
+		return fmt.Errorf("program did not stop after exec: status %#x", status)
+	}
+
+	procs := s.RlimitProcs
+	if procs > 0 && !s.Nobody {
+		// The program shares its user with this process, whose threads
+		// count towards the limit as well
+		threads, err := os.ReadDir("/proc/self/task")
+		if err != nil {
+			return fmt.Errorf("failed to count threads: %w", err)
+		}
+		procs += uint64(len(threads))
+	}
+
+	if s.Nobody {
+		// Without CAP_SYS_RESOURCE only a process with the program's real
+		// user and group may set its limits
+		restore, err := actAsNobody()
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_DATA, s.RlimitMemory},
+		{unix.RLIMIT_NPROC, procs},
+	}
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+		rlimit := &unix.Rlimit{Cur: l.value, Max: l.value}
+		if err := unix.Prlimit(pid, l.resource, rlimit, nil); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %w", l.resource, err)
+		}
+	}
+
+	if err := unix.PtraceDetach(pid); err != nil {
+		return fmt.Errorf("failed to resume program: %w", err)
+	}
+	return nil
+}
+
+// actAsNobody switches the real user and group to nobody, keeping root as
+// the effective user so that no capabilities are lost, and returns the
+// function switching them back
+func actAsNobody() (func(), error) {
+	if err := syscall.Setresgid(nobodyID, -1, -1); err != nil {
+		return nil, fmt.Errorf("failed to switch group: %w", err)
+	}
+	if err := syscall.Setresuid(nobodyID, -1, -1); err != nil {
+		_ = syscall.Setresgid(0, -1, -1)
+		return nil, fmt.Errorf("failed to switch user: %w", err)
+	}
+	return func() {
+		_ = syscall.Setresuid(0, -1, -1)
+		_ = syscall.Setresgid(0, -1, -1)
+	}, nil
+}
+
+// dropPrivileges empties the capability bounding set and forbids gaining
+// privileges, so the program has no capabilities even as root of its user
+// namespace and setuid binaries do not help
+func dropPrivileges() error {
+	// Newer kernels may know more capabilities than this build; unknown
+	// ones are reported as invalid
+	for c := 0; c < 64; c++ {
+		err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0)
+		if err != nil && !errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("failed to drop capability %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	return nil
+}
+
+// extractWorkspace writes a workspace archive into dir. Archives of a
+// previous phase come from the program, so entries may not reach through
+// symlinks or outside dir.
+func extractWorkspace(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read workspace: %w", err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), path.Base(workDir))
+		name = strings.TrimPrefix(name, "/")
+		if name == "" {
+			continue
+		}
+		name, err = CleanPath(name)
+		if err != nil {
+			return fmt.Errorf("failed to extract workspace: %w", err)
+		}
+		target := filepath.Join(dir, name)
+		if err := ensureParents(dir, name); err != nil {
+			return err
+		}
+
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.Mkdir(target, mode); err != nil && !errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("failed to extract workspace: %w", err)
+			}
+		case tar.TypeReg:
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, mode)
+			if err != nil {
+				return fmt.Errorf("failed to extract workspace: %w", err)
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract workspace: %w", err)
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("failed to extract workspace: %w", err)
+			}
+		}
+	}
+}
+
+// ensureParents creates the directories leading to name below dir,
+// refusing any that is a symlink or not a directory
+func ensureParents(dir, name string) error {
+	current := dir
+	parts := strings.Split(name, "/")
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			if err := os.Mkdir(current, workspaceDirMode); err != nil {
+				return fmt.Errorf("failed to extract workspace: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract workspace: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("failed to extract workspace: %w: %q", errUnsafePath, name)
+		}
+	}
+	return nil
+}
+
+// archiveDir writes dir as a tar archive with entries under prefix, in the
+// layout Docker uses when copying a directory out of a container. Symlinks
+// are stored, not followed, and special files are left out.
+func archiveDir(w io.Writer, dir, prefix string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{Name: name, Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()}
+		switch {
+		case d.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		case d.Type()&fs.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			if header.Linkname, err = os.Readlink(p); err != nil {
+				return err
+			}
+			return tw.WriteHeader(header)
+		case d.Type().IsRegular():
+			return archiveFile(tw, p, header)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	return nil
+}
+
+// archiveFile adds a regular file, opened without following symlinks
+func archiveFile(tw *tar.Writer, p string, header *tar.Header) error {
+	f, err := os.OpenFile(p, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header.Typeflag = tar.TypeReg
+	header.Size = info.Size()
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,615 @@
+//go:build linux
+
+package executor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// Process sandbox settings
+const (
+	// argv[0] the bot re-executes itself with to set up a sandbox
+	sandboxInitArg = "discord-executor-sandbox-init"
+
+	// Environment variable carrying the sandbox spec to the init process
+	sandboxSpecEnv = "DCE_SANDBOX_SPEC"
+
+	// Account programs run as when the bot runs as root
+	nobodyID = 65534
+
+	// cgroup v2 CPU accounting period in microseconds
+	cpuPeriod = 100000
+)
+
+// Files the init process gets after stdin, stdout and stderr
+const (
+	// Setup errors are written here; it is closed once the program runs
+	sandboxStatusFD = 3 + iota
+
+	// Workspace archive extracted before start
+	sandboxWorkspaceInFD
+
+	// Workspace archive written after exit when collected
+	sandboxWorkspaceOutFD
+
+	// Output directory archive written after exit when collected
+	sandboxArtifactsFD
+)
+
+// Namespaces every sandbox gets; the user namespace is added when the bot
+// is not root, which maps the bot's user to root inside
+const sandboxCloneFlags = syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+	syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | syscall.CLONE_NEWCGROUP
+
+// sandboxSpec tells the init process how to build the sandbox and what to run
+type sandboxSpec struct {
+	// Host directory the sandbox root is mounted on
+	Root string
+
+	// Host directories mounted read-only at the same paths
+	ReadOnlyPaths []string
+
+	Command []string
+	Env     []string
+
+	// Whether to run the program as nobody; otherwise it runs as root of
+	// its user namespace, without capabilities
+	Nobody bool
+
+	// Memory limit applied to the program with RLIMIT_DATA, and the process
+	// limit with RLIMIT_NPROC, when no cgroup enforces them. Processes are
+	// counted per user, so as nobody they include the host's nobody processes.
+	RlimitMemory uint64
+	RlimitProcs  uint64
+
+	// Whether to provide the output directory and archive it after exit
+	Artifacts bool
+
+	// Whether to archive the workspace after exit
+	CollectWorkspace bool
+
+	// Size of the workspace file system in bytes
+	WorkspaceBytes int
+}
+
+// SandboxExecutor runs code as host processes confined by Linux namespaces,
+// cgroups v2 and rlimits. Languages use the toolchains installed on the host.
+type SandboxExecutor struct {
+	cfg     config.DockerConfig
+	sandbox config.SandboxConfig
+
+	// Directory holding the scratch directories of this instance's phases
+	scratch string
+
+	// Whether the bot runs as root, so programs run as nobody and no user
+	// namespace is needed
+	root bool
+
+	// Host search path, also used inside the sandbox
+	path string
+}
+
+// NewSandboxExecutor removes what a previous run of this instance left and
+// checks that a sandbox can be started
+func NewSandboxExecutor(sandbox config.SandboxConfig, cfg config.DockerConfig) (*SandboxExecutor, error) {
+	dir := sandbox.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	e := &SandboxExecutor{
+		cfg:     cfg,
+		sandbox: sandbox,
+		scratch: filepath.Join(dir, "discord-executor-"+cfg.InstanceID),
+		root:    os.Geteuid() == 0,
+		path:    os.Getenv("PATH"),
+	}
+	if err := os.RemoveAll(e.scratch); err != nil {
+		return nil, fmt.Errorf("failed to remove sandbox scratch directory: %w", err)
+	}
+	if err := os.MkdirAll(e.scratch, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create sandbox scratch directory: %w", err)
+	}
+
+	if sandbox.CgroupRoot != "" {
+		if err := e.prepareCgroups(); err != nil {
+			return nil, err
+		}
+	} else {
+		logrus.Warn("No sandbox cgroup root configured; memory and process limits use rlimits and CPU is not limited")
+	}
+
+	// Namespaces may be unavailable, e.g. inside containers without privileges
+	res, _, err := e.runPhase(context.Background(), "probe", &phase{
+		name:     "probe",
+		command:  []string{"true"},
+		timeout:  cleanupTimeout,
+		memoryMB: cfg.MemoryLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("process sandbox is unavailable: %w", err)
+	}
+	if res.Failed() {
+		return nil, fmt.Errorf("process sandbox is unavailable: probe ended with %s", res.Reason)
+	}
+
+	logrus.WithFields(logrus.Fields{"scratch": e.scratch, "cgroup_root": sandbox.CgroupRoot}).
+		Info("Process sandbox ready")
+	return e, nil
+}
+
+// Close removes the scratch directory
+func (e *SandboxExecutor) Close() error {
+	return os.RemoveAll(e.scratch)
+}
+
+// Execute runs the request in fresh sandboxes
+func (e *SandboxExecutor) Execute(ctx context.Context, req *Request) (*Result, error) {
+	return execute(ctx, e, e.cfg, req)
+}
+
+// prepare checks that the host has the language's toolchain
+func (e *SandboxExecutor) prepare(_ context.Context, lang *Language) error {
+	for _, command := range [][]string{lang.Compile, lang.Command} {
+		if len(command) == 0 || strings.Contains(command[0], "/") {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			return fmt.Errorf("%w: %s needs %s", errToolchainMissing, lang.Name, command[0])
+		}
+	}
+	return nil
+}
+
+// runPhase runs one phase to completion in its own sandbox
+func (e *SandboxExecutor) runPhase(ctx context.Context, executionID string, p *phase) (*PhaseResult, []byte, error) {
+	if p.network == NetworkInternet {
+		return nil, nil, errors.New("the sandbox backend has no internet access; use the none or isolated network")
+	}
+	log := logrus.WithFields(logrus.Fields{"execution_id": executionID, "phase": p.name})
+
+	dir, err := os.MkdirTemp(e.scratch, executionID+"-"+p.name+"-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := newSandboxFiles(dir, p.workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer files.close()
+
+	spec := sandboxSpec{
+		Root:             filepath.Join(dir, "root"),
+		ReadOnlyPaths:    e.sandbox.ReadOnlyPaths,
+		Command:          p.command,
+		Env:              append([]string{"PATH=" + e.path, "HOME=/tmp"}, p.env...),
+		Nobody:           e.root,
+		Artifacts:        p.collectArtifacts,
+		CollectWorkspace: p.collectWorkspace,
+		WorkspaceBytes:   e.cfg.MaxWorkspaceBytes,
+	}
+	if err := os.Mkdir(spec.Root, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+
+	var cg *sandboxCgroup
+	if e.sandbox.CgroupRoot != "" {
+		if cg, err = e.createCgroup(filepath.Base(dir), p.memoryMB); err != nil {
+			return nil, nil, err
+		}
+		defer cg.remove()
+	} else {
+		spec.RlimitMemory = uint64(p.memoryMB) << 20
+		spec.RlimitProcs = pidsLimit
+	}
+
+	specJSON, err := json.Marshal(&spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode sandbox spec: %w", err)
+	}
+
+	cmd := &exec.Cmd{
+		Path:        "/proc/self/exe",
+		Args:        []string{sandboxInitArg},
+		Env:         []string{sandboxSpecEnv + "=" + string(specJSON)},
+		Stdin:       strings.NewReader(p.stdin),
+		ExtraFiles:  files.extra(),
+		SysProcAttr: e.sysProcAttr(cg),
+		WaitDelay:   cleanupTimeout,
+	}
+
+	// Stopping asks the init process to kill the program and still archive
+	// the output directory; it is killed outright if that takes too long.
+	// The timer is created unarmed so that the first stop, which may come
+	// from the output capture, only has to reset it.
+	forceKill := time.AfterFunc(cleanupTimeout, func() { _ = cmd.Process.Kill() })
+	forceKill.Stop()
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+			forceKill.Reset(cleanupTimeout)
+		})
+	}
+
+	output := newOutputCapture(e.cfg.MaxOutputBytes, func() {
+		log.Warn("Output limit exceeded, stopping sandbox")
+		stop()
+	})
+	output.live = p.stream
+	cmd.Stdout = output.Stdout()
+	cmd.Stderr = output.Stderr()
+
+	phaseCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	if err := files.started(); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, nil, err
+	}
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- cmd.Wait()
+	}()
+
+	canceled, timedOut := false, false
+	select {
+	case err = <-waitDone:
+	case <-phaseCtx.Done():
+		if ctx.Err() != nil {
+			canceled = true
+			log.Info("Execution cancelled, stopping sandbox")
+		} else {
+			timedOut = true
+		}
+		stop()
+		err = <-waitDone
+	}
+	duration := time.Since(start)
+	forceKill.Stop()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		log.WithError(err).Debug("Sandbox output stream ended with error")
+	}
+
+	state := exitState{
+		ExitCode:  sandboxExitCode(cmd.ProcessState),
+		Canceled:  canceled,
+		TimedOut:  timedOut,
+		Truncated: output.Truncated(),
+	}
+	usage := rusageOf(cmd.ProcessState)
+	if cg != nil {
+		usage, state.OOMKilled = cg.usage(usage)
+	}
+
+	result := &PhaseResult{
+		ExitCode:    state.ExitCode,
+		Duration:    duration,
+		CPUTime:     usage.cpuTime,
+		PeakMemory:  usage.peakMemory,
+		MemoryLimit: uint64(p.memoryMB) << 20,
+		Timeout:     p.timeout,
+		Truncated:   state.Truncated,
+	}
+	result.Reason, result.Signal = classifyTermination(state)
+	result.Stdout, result.Stderr = output.Strings()
+
+	// Files are collected even from failed runs, which may have written
+	// partial results before crashing
+	if p.collectArtifacts {
+		result.Artifacts, result.ArtifactsSkipped, err = files.artifacts(e.cfg.MaxArtifacts, e.cfg.MaxArtifactBytes)
+		if err != nil {
+			log.WithError(err).Warn("Failed to collect output files")
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"exit_code":   result.ExitCode,
+		"reason":      result.Reason,
+		"duration":    result.Duration,
+		"cpu_time":    result.CPUTime,
+		"peak_memory": result.PeakMemory,
+	}).Debug("Phase finished")
+
+	if !p.collectWorkspace || result.Failed() {
+		return result, nil, nil
+	}
+
+	workspace, err := files.workspace(e.cfg.MaxWorkspaceBytes)
+	if errors.Is(err, errWorkspaceTooLarge) {
+		log.WithError(err).Info("Workspace exceeded the size limit")
+		result.Reason = TerminationWorkspaceLimit
+		return result, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, workspace, nil
+}
+
+// sysProcAttr returns the namespaces, and cgroup if any, for the init process
+func (e *SandboxExecutor) sysProcAttr(cg *sandboxCgroup) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		Cloneflags: sandboxCloneFlags,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	if !e.root {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	}
+	if cg != nil {
+		attr.UseCgroupFD = true
+		attr.CgroupFD = int(cg.fd.Fd())
+	}
+	return attr
+}
+
+// sandboxExitCode follows Docker's convention of reporting a program killed
+// by a signal as 128 plus the signal number
+func sandboxExitCode(state *os.ProcessState) int {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		return signalExitBase + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// rusageOf returns the CPU time and peak memory of the init process and the
+// program it waited for
+func rusageOf(state *os.ProcessState) resourceUsage {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return resourceUsage{}
+	}
+	return resourceUsage{
+		peakMemory: uint64(ru.Maxrss) << 10,
+		cpuTime:    time.Duration(ru.Utime.Nano() + ru.Stime.Nano()),
+	}
+}
+
+// sandboxFiles are the archives exchanged with the init process and the
+// pipe it reports setup errors on
+type sandboxFiles struct {
+	status, statusW *os.File
+	workspaceIn     *os.File
+	workspaceOut    *os.File
+	artifactsOut    *os.File
+}
+
+// newSandboxFiles creates the exchange files in dir, holding the workspace archive
+func newSandboxFiles(dir string, workspace []byte) (*sandboxFiles, error) {
+	f := &sandboxFiles{}
+	var err error
+	for _, file := range []struct {
+		name string
+		dst  **os.File
+	}{
+		{"workspace-in.tar", &f.workspaceIn},
+		{"workspace-out.tar", &f.workspaceOut},
+		{"artifacts.tar", &f.artifactsOut},
+	} {
+		if *file.dst, err = os.Create(filepath.Join(dir, file.name)); err != nil {
+			f.close()
+			return nil, fmt.Errorf("failed to create sandbox file: %w", err)
+		}
+	}
+	if _, err := f.workspaceIn.Write(workspace); err != nil {
+		f.close()
+		return nil, fmt.Errorf("failed to write workspace: %w", err)
+	}
+	if _, err := f.workspaceIn.Seek(0, io.SeekStart); err != nil {
+		f.close()
+		return nil, fmt.Errorf("failed to write workspace: %w", err)
+	}
+	if f.status, f.statusW, err = os.Pipe(); err != nil {
+		f.close()
+		return nil, fmt.Errorf("failed to create status pipe: %w", err)
+	}
+	return f, nil
+}
+
+// extra returns the files in the order of the init process's descriptors
+func (f *sandboxFiles) extra() []*os.File {
+	return []*os.File{f.statusW, f.workspaceIn, f.workspaceOut, f.artifactsOut}
+}
+
+// started waits until the init process has started the program, returning
+// its setup error if it failed instead
+func (f *sandboxFiles) started() error {
+	f.statusW.Close()
+	f.statusW = nil
+
+	msg, err := io.ReadAll(f.status)
+	if err != nil {
+		return fmt.Errorf("failed to read sandbox status: %w", err)
+	}
+	if len(msg) > 0 {
+		return fmt.Errorf("failed to set up sandbox: %s", msg)
+	}
+	return nil
+}
+
+// artifacts reads the output directory archive within the artifact limits
+func (f *sandboxFiles) artifacts(maxCount, maxBytes int) ([]Artifact, bool, error) {
+	if _, err := f.artifactsOut.Seek(0, io.SeekStart); err != nil {
+		return nil, false, fmt.Errorf("failed to read output directory: %w", err)
+	}
+	return readArtifacts(f.artifactsOut, maxCount, maxBytes)
+}
+
+// workspace returns the workspace archive written after exit, failing with
+// errWorkspaceTooLarge once it passes maxBytes
+func (f *sandboxFiles) workspace(maxBytes int) ([]byte, error) {
+	if _, err := f.workspaceOut.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+	data, err := readWorkspace(f.workspaceOut, maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+	return data, nil
+}
+
+// close closes every file that was opened
+func (f *sandboxFiles) close() {
+	for _, file := range []*os.File{f.status, f.statusW, f.workspaceIn, f.workspaceOut, f.artifactsOut} {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// sandboxCgroup is the cgroup v2 directory limiting one phase
+type sandboxCgroup struct {
+	dir string
+	fd  *os.File
+}
+
+// cgroupPrefix starts the names of this instance's phase cgroups
+func (e *SandboxExecutor) cgroupPrefix() string {
+	return "discord-executor-" + e.cfg.InstanceID + "-"
+}
+
+// prepareCgroups enables the controllers phases need and removes cgroups
+// left by a previous run of this instance
+func (e *SandboxExecutor) prepareCgroups() error {
+	root := e.sandbox.CgroupRoot
+	controllers := "+memory +cpu +pids"
+	if err := os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte(controllers), 0o644); err != nil {
+		return fmt.Errorf("failed to enable cgroup controllers in %s; it must be a delegated cgroup v2 "+
+			"directory without processes of its own: %w", root, err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("failed to read cgroup root: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), e.cgroupPrefix()) {
+			(&sandboxCgroup{dir: filepath.Join(root, entry.Name())}).remove()
+			removed++
+		}
+	}
+	if removed > 0 {
+		logrus.WithField("cgroups", removed).Warn("Removed leftover sandbox cgroups")
+	}
+	return nil
+}
+
+// createCgroup creates a cgroup applying the memory, CPU and process limits
+func (e *SandboxExecutor) createCgroup(name string, memoryMB int) (*sandboxCgroup, error) {
+	cg := &sandboxCgroup{dir: filepath.Join(e.sandbox.CgroupRoot, e.cgroupPrefix()+name)}
+	if err := os.Mkdir(cg.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	quota := max(int(e.cfg.CPULimit*cpuPeriod), 1000)
+	limits := []struct{ file, value string }{
+		{"memory.max", strconv.Itoa(memoryMB << 20)},
+		{"cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)},
+		{"pids.max", strconv.Itoa(pidsLimit)},
+	}
+	for _, limit := range limits {
+		if err := os.WriteFile(filepath.Join(cg.dir, limit.file), []byte(limit.value), 0o644); err != nil {
+			cg.remove()
+			return nil, fmt.Errorf("failed to set %s: %w", limit.file, err)
+		}
+	}
+	// Swap accounting may be disabled, leaving no file to write
+	swap := filepath.Join(cg.dir, "memory.swap.max")
+	if err := os.WriteFile(swap, []byte("0"), 0o644); err != nil && !errors.Is(err, os.ErrNotExist) {
+		cg.remove()
+		return nil, fmt.Errorf("failed to set memory.swap.max: %w", err)
+	}
+
+	fd, err := os.Open(cg.dir)
+	if err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	cg.fd = fd
+	return cg, nil
+}
+
+// usage returns the cgroup's CPU time and peak memory, falling back to the
+// rusage figures for counters the kernel lacks, and whether the kernel
+// killed a process for exceeding the memory limit
+func (cg *sandboxCgroup) usage(fallback resourceUsage) (resourceUsage, bool) {
+	usage := fallback
+	if peak, err := os.ReadFile(filepath.Join(cg.dir, "memory.peak")); err == nil {
+		if n, err := strconv.ParseUint(strings.TrimSpace(string(peak)), 10, 64); err == nil {
+			usage.peakMemory = n
+		}
+	}
+	if usec, ok := cg.stat("cpu.stat", "usage_usec"); ok {
+		usage.cpuTime = time.Duration(usec) * time.Microsecond
+	}
+	oomKills, _ := cg.stat("memory.events", "oom_kill")
+	return usage, oomKills > 0
+}
+
+// stat reads one counter from a flat keyed cgroup file
+func (cg *sandboxCgroup) stat(file, key string) (uint64, bool) {
+	f, err := os.Open(filepath.Join(cg.dir, file))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, _ := strings.Cut(scanner.Text(), " ")
+		if name == key {
+			n, err := strconv.ParseUint(value, 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// remove kills anything left in the cgroup and deletes it; the kernel
+// needs a moment to release exited processes
+func (cg *sandboxCgroup) remove() {
+	if cg.fd != nil {
+		cg.fd.Close()
+	}
+	_ = os.WriteFile(filepath.Join(cg.dir, "cgroup.kill"), []byte("1"), 0o644)
+
+	var err error
+	for range 50 {
+		if err = os.Remove(cg.dir); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	logrus.WithError(err).WithField("cgroup", cg.dir).Warn("Failed to remove sandbox cgroup")
+}
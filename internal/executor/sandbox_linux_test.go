@@ -0,0 +1,118 @@
+//go:build linux
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// newTestSandbox starts a sandbox, skipping the test where namespaces are unavailable
+func newTestSandbox(t *testing.T, cgroupRoot string) *SandboxExecutor {
+	t.Helper()
+	e, err := NewSandboxExecutor(config.SandboxConfig{
+		Dir:           t.TempDir(),
+		CgroupRoot:    cgroupRoot,
+		ReadOnlyPaths: config.DefaultSandboxReadOnlyPaths,
+	}, conformanceConfig())
+	if err != nil {
+		t.Skipf("Process sandbox is unavailable: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestSandboxConformance(t *testing.T) {
+	testConformance(t, newTestSandbox(t, ""))
+}
+
+// The cgroup variant needs a delegated cgroup v2 directory, e.g. one made
+// with systemd-run --user -p Delegate=yes
+func TestSandboxConformanceCgroup(t *testing.T) {
+	root := os.Getenv("DCE_TEST_CGROUP_ROOT")
+	if root == "" {
+		t.Skip("DCE_TEST_CGROUP_ROOT is not set")
+	}
+	testConformance(t, newTestSandbox(t, root))
+}
+
+// The init process must keep its own limits, or its runtime fails to
+// start threads and writes the crash into the program's output
+func TestSandboxRlimitsOnlyProgram(t *testing.T) {
+	e := newTestSandbox(t, "")
+	res, err := e.Execute(context.Background(), &Request{
+		ID:       "rlimits",
+		Language: "bash",
+		Code:     `ulimit -d; grep "Max data size" /proc/1/limits`,
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(res.Stdout), "\n")
+	if len(lines) != 2 || lines[0] != "131072" {
+		t.Fatalf("Expected the program's data size limited to 128 MiB, got %q %q", res.Stdout, res.Stderr)
+	}
+	if !strings.Contains(lines[1], "unlimited") {
+		t.Errorf("Expected the init process to be unlimited, got %q", lines[1])
+	}
+}
+
+func TestWorkspaceRoundTrip(t *testing.T) {
+	archive, err := workspaceArchive([]File{
+		{Path: "main.py", Content: []byte("print(1)")},
+		{Path: "pkg/util.py", Content: []byte("x = 1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := extractWorkspace(bytes.NewReader(archive), dir); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if err := os.Symlink("/etc", filepath.Join(dir, "etc")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := archiveDir(&out, dir, "workspace"); err != nil {
+		t.Fatalf("Failed to archive: %v", err)
+	}
+	again := t.TempDir()
+	if err := extractWorkspace(&out, again); err != nil {
+		t.Fatalf("Failed to extract the archived workspace: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(again, "pkg", "util.py")); err != nil || string(data) != "x = 1" {
+		t.Errorf("Expected nested files to survive, got %q, %v", data, err)
+	}
+	if link, err := os.Readlink(filepath.Join(again, "etc")); err != nil || link != "/etc" {
+		t.Errorf("Expected the symlink stored as a link, got %q, %v", link, err)
+	}
+}
+
+func TestExtractWorkspaceRefusesSymlinkParents(t *testing.T) {
+	outside := t.TempDir()
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := workspaceArchive([]File{{Path: "link/escaped", Content: []byte("x")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := extractWorkspace(bytes.NewReader(archive), dir); !errors.Is(err, errUnsafePath) {
+		t.Errorf("Expected writing through the symlink to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "escaped")); err == nil {
+		t.Error("Expected nothing written outside the workspace")
+	}
+}
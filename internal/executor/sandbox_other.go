@@ -0,0 +1,31 @@
+//go:build !linux
+
+package executor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+// errSandboxUnsupported is returned on hosts without Linux namespaces
+var errSandboxUnsupported = errors.New("the sandbox backend requires Linux")
+
+// SandboxExecutor runs code as confined host processes, which needs Linux
+type SandboxExecutor struct{}
+
+// NewSandboxExecutor reports that the sandbox is unavailable on this platform
+func NewSandboxExecutor(config.SandboxConfig, config.DockerConfig) (*SandboxExecutor, error) {
+	return nil, errSandboxUnsupported
+}
+
+// Execute reports that the sandbox is unavailable on this platform
+func (e *SandboxExecutor) Execute(context.Context, *Request) (*Result, error) {
+	return nil, errSandboxUnsupported
+}
+
+// Close does nothing
+func (e *SandboxExecutor) Close() error {
+	return nil
+}
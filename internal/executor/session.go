@@ -0,0 +1,277 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionEndReason explains why an interactive session ended
+type SessionEndReason string
+
+// Session end reasons
+const (
+	// The interpreter exited on its own
+	SessionExited SessionEndReason = "exited"
+
+	// No input arrived within the idle timeout
+	SessionIdle SessionEndReason = "idle"
+
+	// The session reached its maximum lifetime
+	SessionMaxRuntime SessionEndReason = "max_runtime"
+
+	// Output since the last input exceeded the capture limit
+	SessionOutputLimit SessionEndReason = "output_limit"
+
+	// The session was stopped by its owner
+	SessionClosed SessionEndReason = "closed"
+)
+
+// sessionOutputBuffer is how many output chunks may queue before the
+// interpreter is blocked on its output stream
+const sessionOutputBuffer = 64
+
+// errSessionEnded is returned when sending input to an ended session
+var errSessionEnded = errors.New("session has ended")
+
+// SessionRequest describes an interactive session to start
+type SessionRequest struct {
+	// Unique session ID, used for logging
+	ID string
+
+	// Language name or alias; the language must support sessions
+	Language string
+}
+
+// SessionStarter is implemented by executors that support interactive sessions
+type SessionStarter interface {
+	// StartSession starts a long-lived interpreter. The session ends when the
+	// interpreter exits, after the idle timeout or maximum lifetime, or when
+	// it is closed.
+	StartSession(ctx context.Context, req *SessionRequest) (*Session, error)
+}
+
+// Session is a long-lived interpreter fed input statement by statement
+type Session struct {
+	// Session ID from the request
+	ID string
+
+	// Canonical language name
+	Language string
+
+	input       io.Writer
+	output      chan []byte
+	done        chan struct{}
+	ended       chan struct{}
+	idleTimeout time.Duration
+	outputLimit int
+
+	mu      sync.Mutex
+	idle    *time.Timer
+	written int
+	reason  SessionEndReason
+	stop    context.CancelFunc
+}
+
+// newSession creates a session that ends through stop
+func newSession(id, language string, input io.Writer, idleTimeout time.Duration, outputLimit int,
+	stop context.CancelFunc) *Session {
+	s := &Session{
+		ID:          id,
+		Language:    language,
+		input:       input,
+		output:      make(chan []byte, sessionOutputBuffer),
+		done:        make(chan struct{}),
+		ended:       make(chan struct{}),
+		idleTimeout: idleTimeout,
+		outputLimit: outputLimit,
+		stop:        stop,
+	}
+
+	// The timer may fire before newSession returns, and end reads it
+	s.mu.Lock()
+	s.idle = time.AfterFunc(idleTimeout, func() { s.end(SessionIdle) })
+	s.mu.Unlock()
+	return s
+}
+
+// Send writes input to the interpreter and restarts the idle timer
+func (s *Session) Send(input string) error {
+	s.mu.Lock()
+	if s.reason != "" {
+		s.mu.Unlock()
+		return errSessionEnded
+	}
+	s.idle.Reset(s.idleTimeout)
+	s.written = 0
+	s.mu.Unlock()
+
+	if _, err := io.WriteString(s.input, input); err != nil {
+		return fmt.Errorf("failed to send input: %w", err)
+	}
+	return nil
+}
+
+// Output delivers output chunks as they arrive and is closed once the
+// session has ended and all output was delivered
+func (s *Session) Output() <-chan []byte {
+	return s.output
+}
+
+// Done is closed once the session has ended and its container is gone
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Reason returns why the session ended, or an empty reason while it runs
+func (s *Session) Reason() SessionEndReason {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+// Close ends the session on behalf of its owner
+func (s *Session) Close() {
+	s.end(SessionClosed)
+}
+
+// end records the first end reason and stops the interpreter
+func (s *Session) end(reason SessionEndReason) {
+	s.mu.Lock()
+	if s.reason == "" {
+		s.reason = reason
+		close(s.ended)
+	}
+	s.idle.Stop()
+	s.mu.Unlock()
+
+	s.stop()
+}
+
+// Write queues an output chunk, ending the session once output since the
+// last input exceeds the limit. Once the session has ended, chunks that do
+// not fit in the buffer are dropped rather than blocking the output copy.
+func (s *Session) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	s.written += len(p)
+	exceeded := s.written > s.outputLimit
+	s.mu.Unlock()
+
+	if exceeded {
+		s.end(SessionOutputLimit)
+		return len(p), nil
+	}
+
+	chunk := bytes.Clone(p)
+	select {
+	case s.output <- chunk:
+	default:
+		select {
+		case s.output <- chunk:
+		case <-s.ended:
+		}
+	}
+	return len(p), nil
+}
+
+// StartSession starts an interpreter in a container that lives until the
+// session ends. Output from both streams is delivered in arrival order.
+func (e *DockerExecutor) StartSession(ctx context.Context, req *SessionRequest) (*Session, error) {
+	lang, ok := LookupLanguage(req.Language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", req.Language)
+	}
+	if !lang.Interactive() {
+		return nil, fmt.Errorf("%s does not support interactive sessions", lang.Name)
+	}
+
+	if err := e.ensureImage(ctx, lang.Image); err != nil {
+		return nil, err
+	}
+
+	p := &phase{
+		name:     phaseSession,
+		image:    lang.Image,
+		command:  lang.REPL,
+		env:      lang.Env,
+		memoryMB: e.cfg.MemoryLimit,
+	}
+	id, err := e.createContainer(ctx, req.ID, p)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := workspaceArchive(nil)
+	if err != nil {
+		e.removeContainer(id)
+		return nil, err
+	}
+	err = e.cli.CopyToContainer(ctx, id, "/", bytes.NewReader(workspace), container.CopyToContainerOptions{})
+	if err != nil {
+		e.removeContainer(id)
+		return nil, fmt.Errorf("failed to copy workspace into container: %w", err)
+	}
+
+	// The session outlives the request context, bounded by the maximum runtime
+	sessionCtx, stop := context.WithTimeout(context.Background(), time.Duration(e.cfg.MaxRuntime)*time.Second)
+
+	attachOpts := container.AttachOptions{Stream: true, Stdin: true, Stdout: true, Stderr: true}
+	attach, err := e.cli.ContainerAttach(sessionCtx, id, attachOpts)
+	if err != nil {
+		stop()
+		e.removeContainer(id)
+		return nil, fmt.Errorf("failed to attach to container: %w", err)
+	}
+
+	session := newSession(req.ID, lang.Name, attach.Conn,
+		time.Duration(e.cfg.SessionIdleTimeout)*time.Second, e.cfg.MaxOutputBytes, stop)
+
+	waitCh, waitErrCh := e.cli.ContainerWait(sessionCtx, id, container.WaitConditionNextExit)
+	if err := e.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		session.end(SessionClosed)
+		attach.Close()
+		e.removeContainer(id)
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	log := logrus.WithFields(logrus.Fields{"session_id": req.ID, "language": lang.Name})
+	log.Info("Session started")
+
+	copyDone := make(chan struct{})
+	go func() {
+		defer close(copyDone)
+		if _, err := stdcopy.StdCopy(session, session, attach.Reader); err != nil {
+			log.WithError(err).Debug("Session output stream ended with error")
+		}
+	}()
+
+	go func() {
+		select {
+		case <-waitCh:
+			session.end(SessionExited)
+		case <-waitErrCh:
+			if errors.Is(sessionCtx.Err(), context.DeadlineExceeded) {
+				session.end(SessionMaxRuntime)
+			}
+			e.killContainer(id)
+			session.end(SessionExited)
+		}
+
+		<-copyDone
+		attach.Close()
+		close(session.output)
+		e.removeContainer(id)
+		log.WithField("reason", session.Reason()).Info("Session ended")
+		close(session.done)
+	}()
+
+	return session, nil
+}
@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionOutputLimit(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	var input bytes.Buffer
+	s := newSession("s1", "python", &input, time.Minute, 10, stop)
+
+	if _, err := s.Write([]byte("12345")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Send("x\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Sending input resets the budget, so this fits
+	if _, err := s.Write([]byte("1234567890")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Reason() != "" {
+		t.Fatalf("Expected session to keep running, got %s", s.Reason())
+	}
+
+	if _, err := s.Write([]byte("!")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Reason() != SessionOutputLimit {
+		t.Errorf("Expected %s, got %s", SessionOutputLimit, s.Reason())
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the session context to be cancelled")
+	}
+	if len(s.Output()) != 2 {
+		t.Errorf("Expected 2 queued chunks, got %d", len(s.Output()))
+	}
+
+	if err := s.Send("y\n"); !errors.Is(err, errSessionEnded) {
+		t.Errorf("Expected errSessionEnded, got %v", err)
+	}
+	if input.String() != "x\n" {
+		t.Errorf("Expected only the first input to be written, got %q", input.String())
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	s := newSession("s1", "python", &bytes.Buffer{}, 20*time.Millisecond, 1024, stop)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the idle timeout to end the session")
+	}
+	if s.Reason() != SessionIdle {
+		t.Errorf("Expected %s, got %s", SessionIdle, s.Reason())
+	}
+
+	// Later ends keep the first reason
+	s.Close()
+	if s.Reason() != SessionIdle {
+		t.Errorf("Expected %s to be kept, got %s", SessionIdle, s.Reason())
+	}
+}
+
+func TestSessionWriteAfterEnd(t *testing.T) {
+	s := newSession("s1", "python", &bytes.Buffer{}, time.Minute, 1<<20, func() {})
+	for range sessionOutputBuffer {
+		if _, err := s.Write([]byte("x")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		s.Write([]byte("blocked"))
+	}()
+	s.Close()
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a write into a full buffer to return once the session ended")
+	}
+	if len(s.Output()) != sessionOutputBuffer {
+		t.Errorf("Expected the buffered chunks to be kept, got %d", len(s.Output()))
+	}
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/sirupsen/logrus"
+)
+
+// resourceUsage is the peak resource consumption seen in container stats
+type resourceUsage struct {
+	peakMemory uint64
+	cpuTime    time.Duration
+}
+
+// observe folds a stats sample into the usage totals
+func (u *resourceUsage) observe(stats *container.StatsResponse) {
+	// MaxUsage is only reported on cgroup v1; fall back to current usage
+	memory := max(stats.MemoryStats.MaxUsage, stats.MemoryStats.Usage)
+	u.peakMemory = max(u.peakMemory, memory)
+
+	cpu := time.Duration(stats.CPUStats.CPUUsage.TotalUsage)
+	u.cpuTime = max(u.cpuTime, cpu)
+}
+
+// watchStats streams container stats until the container stops or ctx ends,
+// returning the peak usage observed. Stats are sampled roughly once a second,
+// so very short runs may report little or no usage.
+func (e *DockerExecutor) watchStats(ctx context.Context, id string) resourceUsage {
+	var usage resourceUsage
+
+	stats, err := e.cli.ContainerStats(ctx, id, true)
+	if err != nil {
+		logrus.WithError(err).WithField("container_id", id).Debug("Failed to stream container stats")
+		return usage
+	}
+	defer stats.Body.Close()
+
+	decoder := json.NewDecoder(stats.Body)
+	for {
+		var sample container.StatsResponse
+		if err := decoder.Decode(&sample); err != nil {
+			return usage
+		}
+		usage.observe(&sample)
+	}
+}
@@ -0,0 +1,96 @@
+package executor
+
+import "fmt"
+
+// TerminationReason explains why an execution phase ended
+type TerminationReason string
+
+// Termination reasons, from the container exit state and the executor's own limits
+const (
+	// The program exited with status 0
+	TerminationSuccess TerminationReason = "success"
+
+	// The program exited with a non-zero status
+	TerminationNonZeroExit TerminationReason = "non_zero_exit"
+
+	// The program was killed by a signal it did not handle
+	TerminationSignal TerminationReason = "signal"
+
+	// The kernel killed the program for exceeding its memory limit
+	TerminationOOMKilled TerminationReason = "oom_killed"
+
+	// The executor killed the program after its wall-clock timeout
+	TerminationTimeout TerminationReason = "timeout"
+
+	// The executor killed the program after it exceeded the output limit
+	TerminationOutputLimit TerminationReason = "output_limit"
+
+	// The executor killed the program because its context was cancelled
+	TerminationCanceled TerminationReason = "canceled"
+
+	// The compiled workspace exceeded the workspace size limit
+	TerminationWorkspaceLimit TerminationReason = "workspace_limit"
+)
+
+// signalExitBase is added to the signal number in the exit status of a
+// process killed by a signal, following the shell convention Docker uses
+const signalExitBase = 128
+
+// exitState is the subset of the container exit state used to classify terminations
+type exitState struct {
+	ExitCode  int
+	OOMKilled bool
+	Canceled  bool
+	TimedOut  bool
+	Truncated bool
+}
+
+// classifyTermination derives the termination reason and, for signal
+// terminations, the signal number. Kills issued by the executor take
+// precedence because they also surface as SIGKILL exits.
+func classifyTermination(state exitState) (reason TerminationReason, signal int) {
+	switch {
+	case state.Canceled:
+		return TerminationCanceled, 0
+	case state.TimedOut:
+		return TerminationTimeout, 0
+	case state.Truncated:
+		return TerminationOutputLimit, 0
+	case state.OOMKilled:
+		return TerminationOOMKilled, 0
+	case state.ExitCode > signalExitBase && state.ExitCode < signalExitBase+65:
+		return TerminationSignal, state.ExitCode - signalExitBase
+	case state.ExitCode != 0:
+		return TerminationNonZeroExit, 0
+	default:
+		return TerminationSuccess, 0
+	}
+}
+
+// SignalName returns a readable name such as "SIGSEGV" for a signal number
+func SignalName(signal int) string {
+	if name, ok := linuxSignalNames[signal]; ok {
+		return name
+	}
+	return fmt.Sprintf("signal %d", signal)
+}
+
+// linuxSignalNames maps the Linux numbers of signals user programs commonly
+// die from. Containers always run Linux, whatever the bot's host platform is.
+var linuxSignalNames = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	14: "SIGALRM",
+	15: "SIGTERM",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+}
@@ -0,0 +1,41 @@
+package executor
+
+import "testing"
+
+func TestClassifyTermination(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  exitState
+		reason TerminationReason
+		signal int
+	}{
+		{name: "success", state: exitState{ExitCode: 0}, reason: TerminationSuccess},
+		{name: "non-zero exit", state: exitState{ExitCode: 1}, reason: TerminationNonZeroExit},
+		{name: "segfault", state: exitState{ExitCode: 139}, reason: TerminationSignal, signal: 11},
+		{name: "oom kill", state: exitState{ExitCode: 137, OOMKilled: true}, reason: TerminationOOMKilled},
+		{name: "timeout kill", state: exitState{ExitCode: 137, TimedOut: true}, reason: TerminationTimeout},
+		{name: "output limit kill", state: exitState{ExitCode: 137, Truncated: true}, reason: TerminationOutputLimit},
+		{name: "cancel kill", state: exitState{ExitCode: 137, Canceled: true, Truncated: true}, reason: TerminationCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, signal := classifyTermination(tt.state)
+			if reason != tt.reason {
+				t.Errorf("Expected reason %s, got %s", tt.reason, reason)
+			}
+			if signal != tt.signal {
+				t.Errorf("Expected signal %d, got %d", tt.signal, signal)
+			}
+		})
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	if name := SignalName(9); name != "SIGKILL" {
+		t.Errorf("Expected SIGKILL, got %s", name)
+	}
+	if name := SignalName(42); name != "signal 42" {
+		t.Errorf("Expected 'signal 42', got %s", name)
+	}
+}
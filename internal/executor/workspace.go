@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Workspace file modes; directories let the unprivileged container user
+// write build outputs
+const (
+	workspaceDirMode  = 0o777
+	workspaceFileMode = 0o644
+)
+
+// File is an uploaded file materialized into the workspace
+type File struct {
+	// Slash-separated path relative to the workspace
+	Path string
+
+	// File contents
+	Content []byte
+}
+
+// errUnsafePath is returned for paths that could escape the workspace
+var errUnsafePath = errors.New("unsafe path")
+
+// errWorkspaceTooLarge is returned for workspace archives over the size limit
+var errWorkspaceTooLarge = errors.New("workspace is too large")
+
+// CleanPath validates a user-supplied relative path and returns its
+// canonical form. Absolute paths, parent references and control characters
+// are rejected so nothing can be written outside the workspace.
+func CleanPath(p string) (string, error) {
+	p = strings.ReplaceAll(p, "\\", "/")
+	if p == "" || strings.HasPrefix(p, "/") || (len(p) > 1 && p[1] == ':') {
+		return "", fmt.Errorf("%w: %q", errUnsafePath, p)
+	}
+	for _, r := range p {
+		if r < ' ' || r == 0x7f {
+			return "", fmt.Errorf("%w: %q", errUnsafePath, p)
+		}
+	}
+
+	cleaned := path.Clean(p)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %q", errUnsafePath, p)
+	}
+
+	return cleaned, nil
+}
+
+// workspaceFiles combines inline source and uploads into the workspace
+// contents and picks the entrypoint. Inline source is always the entrypoint.
+func workspaceFiles(lang *Language, req *Request) (files []File, entrypoint string, err error) {
+	seen := make(map[string]bool, len(req.Files)+1)
+
+	if req.Code != "" {
+		files = append(files, File{Path: lang.FileName, Content: []byte(req.Code)})
+		seen[lang.FileName] = true
+		entrypoint = lang.FileName
+	}
+
+	paths := make([]string, 0, len(req.Files))
+	for _, f := range req.Files {
+		cleaned, err := CleanPath(f.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		if seen[cleaned] {
+			return nil, "", fmt.Errorf("duplicate file %s", cleaned)
+		}
+		seen[cleaned] = true
+
+		files = append(files, File{Path: cleaned, Content: f.Content})
+		paths = append(paths, cleaned)
+	}
+
+	if entrypoint == "" {
+		if entrypoint, err = lang.Entrypoint(paths); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return files, entrypoint, nil
+}
+
+// workspaceArchive builds a tar archive of the workspace directory holding
+// files, ready to be extracted at the container root
+func workspaceArchive(files []File) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	dirs := map[string]bool{}
+	writeDir := func(dir string) error {
+		if dirs[dir] {
+			return nil
+		}
+		dirs[dir] = true
+		return tw.WriteHeader(&tar.Header{Name: dir + "/", Mode: workspaceDirMode, Typeflag: tar.TypeDir})
+	}
+
+	if err := writeDir("workspace"); err != nil {
+		return nil, fmt.Errorf("failed to build workspace archive: %w", err)
+	}
+
+	for _, f := range files {
+		name := path.Join("workspace", f.Path)
+
+		// Parent directories are created explicitly so they stay writable
+		var parents []string
+		for dir := path.Dir(name); dir != "workspace"; dir = path.Dir(dir) {
+			parents = append(parents, dir)
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			if err := writeDir(parents[i]); err != nil {
+				return nil, fmt.Errorf("failed to build workspace archive: %w", err)
+			}
+		}
+
+		header := &tar.Header{Name: name, Mode: workspaceFileMode, Size: int64(len(f.Content))}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to build workspace archive: %w", err)
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return nil, fmt.Errorf("failed to build workspace archive: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build workspace archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readWorkspace reads a workspace archive of at most limit bytes
+func readWorkspace(r io.Reader, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("%w: over %d bytes", errWorkspaceTooLarge, limit)
+	}
+	return data, nil
+}
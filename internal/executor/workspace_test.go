@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		unsafe   bool
+	}{
+		{input: "main.py", expected: "main.py"},
+		{input: "pkg/util.py", expected: "pkg/util.py"},
+		{input: "./pkg//util.py", expected: "pkg/util.py"},
+		{input: "pkg\\util.py", expected: "pkg/util.py"},
+		{input: "pkg/../main.py", expected: "main.py"},
+		{input: "", unsafe: true},
+		{input: ".", unsafe: true},
+		{input: "/etc/passwd", unsafe: true},
+		{input: "../escape.py", unsafe: true},
+		{input: "pkg/../../escape.py", unsafe: true},
+		{input: "C:\\windows", unsafe: true},
+		{input: "bad\nname.py", unsafe: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := CleanPath(tt.input)
+			if tt.unsafe {
+				if !errors.Is(err, errUnsafePath) {
+					t.Errorf("Expected unsafe path error, got '%s' (err=%v)", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWorkspaceFilesEntrypoint(t *testing.T) {
+	python, _ := LookupLanguage("python")
+
+	tests := []struct {
+		name       string
+		req        Request
+		entrypoint string
+		shouldErr  bool
+	}{
+		{
+			name:       "inline code",
+			req:        Request{Code: "print(1)", Files: []File{{Path: "util.py"}}},
+			entrypoint: "main.py",
+		},
+		{
+			name:       "uploaded main file",
+			req:        Request{Files: []File{{Path: "util.py"}, {Path: "main.py"}}},
+			entrypoint: "main.py",
+		},
+		{
+			name:       "single source file",
+			req:        Request{Files: []File{{Path: "app.py"}, {Path: "data.csv"}}},
+			entrypoint: "app.py",
+		},
+		{
+			name:      "ambiguous entrypoint",
+			req:       Request{Files: []File{{Path: "a.py"}, {Path: "b.py"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "upload shadows inline code",
+			req:       Request{Code: "print(1)", Files: []File{{Path: "./main.py"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "traversal",
+			req:       Request{Files: []File{{Path: "../main.py"}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, entrypoint, err := workspaceFiles(python, &tt.req)
+			if tt.shouldErr {
+				if err == nil {
+					t.Errorf("Expected error, got entrypoint '%s'", entrypoint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entrypoint != tt.entrypoint {
+				t.Errorf("Expected entrypoint '%s', got '%s'", tt.entrypoint, entrypoint)
+			}
+		})
+	}
+}
+
+func TestWorkspaceArchive(t *testing.T) {
+	data, err := workspaceArchive([]File{
+		{Path: "main.py", Content: []byte("import pkg.util")},
+		{Path: "pkg/util.py", Content: []byte("x = 1")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build archive: %v", err)
+	}
+
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+
+	expected := []string{"workspace/", "workspace/main.py", "workspace/pkg/", "workspace/pkg/util.py"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected entries %v, got %v", expected, names)
+	}
+}
+
+func TestReadWorkspace(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		shouldErr bool
+	}{
+		{name: "under limit", size: 10, shouldErr: false},
+		{name: "at limit", size: 16, shouldErr: false},
+		{name: "over limit", size: 17, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := readWorkspace(bytes.NewReader(make([]byte, tt.size)), 16)
+			if tt.shouldErr {
+				if !errors.Is(err, errWorkspaceTooLarge) {
+					t.Errorf("Expected errWorkspaceTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil || len(data) != tt.size {
+				t.Errorf("Expected %d bytes, got %d (%v)", tt.size, len(data), err)
+			}
+		})
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	got := expandCommand([]string{"java", "-cp", ".", stemPlaceholder, entrypointPlaceholder}, "Main.java")
+	expected := []string{"java", "-cp", ".", "Main", "Main.java"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,35 @@
+package policy
+
+import "regexp"
+
+// builtinRules block code that must never reach Docker, whatever the
+// configuration says: fork bombs exhaust the host's process table before
+// limits can react, and miners burn every user's CPU share
+var builtinRules = []rule{
+	{
+		// :(){ :|:& };: and the same with a named function
+		name:   "fork-bomb",
+		action: ActionBlock,
+		pattern: regexp.MustCompile(
+			`([A-Za-z_]\w*|:)\s*\(\s*\)\s*\{\s*([A-Za-z_]\w*|:)\s*\|\s*([A-Za-z_]\w*|:)\s*&\s*\}`),
+	},
+	{
+		// fork() in an endless loop, as in while True: os.fork(), for(;;) fork();
+		// or perl's fork while fork
+		name:   "fork-loop",
+		action: ActionBlock,
+		pattern: regexp.MustCompile(
+			`(while\s*\(?\s*(true|True|1)\s*\)?\s*:?\s*\{?|for\s*\(\s*;\s*;\s*\)\s*\{?|loop\s*\{)\s*` +
+				`([\w.]+\.)?fork\s*\(|\bfork\s+while\s+fork\b`),
+	},
+	{
+		name:    "crypto-miner",
+		action:  ActionBlock,
+		pattern: regexp.MustCompile(`(?i)stratum\+(tcp|ssl|tls)://`),
+		tokens: map[string]bool{
+			"xmrig": true, "xmr-stak": true, "minerd": true, "cpuminer": true, "cgminer": true,
+			"bfgminer": true, "ethminer": true, "nbminer": true, "lolminer": true, "phoenixminer": true,
+			"nanominer": true, "teamredminer": true, "srbminer": true, "ccminer": true,
+		},
+	},
+}
@@ -0,0 +1,197 @@
+// Package policy screens submitted code against block, flag and allow rules
+// before it reaches a container.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+	"github.com/anchitjain1234/discord-command-executor/internal/executor"
+)
+
+// Action is what happens to code matching a rule
+type Action string
+
+// Rule actions
+const (
+	// Exempts the code from the rules after the matching one
+	ActionAllow Action = "allow"
+
+	// Lets the code run but records the match
+	ActionFlag Action = "flag"
+
+	// Refuses to run the code
+	ActionBlock Action = "block"
+)
+
+// Name of the rule reported when a command is missing from an allowlist
+const allowlistRule = "command-allowlist"
+
+// Longest excerpt of matching code kept in a match
+const maxExcerpt = 80
+
+// Match describes a rule that submitted code matched
+type Match struct {
+	Rule   string
+	Action Action
+
+	// The matching part of the code
+	Excerpt string
+}
+
+// rule is a compiled rule
+type rule struct {
+	name   string
+	action Action
+
+	// Canonical names of the languages the rule applies to; empty for all
+	languages map[string]bool
+
+	// Matched against the whole source; nil if the rule has only tokens
+	pattern *regexp.Regexp
+
+	// Matched against each token of the source and its base name,
+	// ignoring case
+	tokens map[string]bool
+}
+
+// Engine checks code against the built-in rules, then the configured rules
+// in order, then the command allowlists
+type Engine struct {
+	rules []rule
+
+	// Commands shell code may run, by canonical language name
+	allowlists map[string]map[string]bool
+}
+
+// New compiles the policy configuration
+func New(cfg config.PolicyConfig) (*Engine, error) {
+	e := &Engine{allowlists: make(map[string]map[string]bool)}
+
+	e.rules = append(e.rules, builtinRules...)
+	for i, r := range cfg.Rules {
+		compiled, err := newRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	for i, allowlist := range cfg.Allowlists {
+		languages, err := canonicalLanguages(allowlist.Languages)
+		if err != nil {
+			return nil, fmt.Errorf("allowlists[%d]: %w", i, err)
+		}
+		for language := range languages {
+			if e.allowlists[language] == nil {
+				e.allowlists[language] = make(map[string]bool)
+			}
+			for _, command := range allowlist.Commands {
+				e.allowlists[language][command] = true
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// newRule compiles a configured rule
+func newRule(cfg config.PolicyRule) (rule, error) {
+	languages, err := canonicalLanguages(cfg.Languages)
+	if err != nil {
+		return rule{}, err
+	}
+	r := rule{name: cfg.Name, action: Action(cfg.Action), languages: languages, tokens: make(map[string]bool)}
+
+	if cfg.Pattern != "" {
+		if r.pattern, err = regexp.Compile(cfg.Pattern); err != nil {
+			return rule{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	for _, token := range cfg.Tokens {
+		r.tokens[strings.ToLower(token)] = true
+	}
+	return r, nil
+}
+
+// canonicalLanguages resolves language names and aliases
+func canonicalLanguages(names []string) (map[string]bool, error) {
+	languages := make(map[string]bool)
+	for _, name := range names {
+		lang, ok := executor.LookupLanguage(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported language %q", name)
+		}
+		languages[lang.Name] = true
+	}
+	return languages, nil
+}
+
+// Check screens source in the given language. It returns the first block
+// match, or else the first flag match, or nil when the code may run
+// unremarked. An allow match skips the configured rules after it but not
+// the built-in rules before it or the allowlists.
+func (e *Engine) Check(language, source string) *Match {
+	if lang, ok := executor.LookupLanguage(language); ok {
+		language = lang.Name
+	}
+	tokens := words(source)
+
+	var flagged *Match
+	for i := range e.rules {
+		m := e.rules[i].match(language, source, tokens)
+		if m == nil {
+			continue
+		}
+		if m.Action == ActionAllow {
+			break
+		}
+		if m.Action == ActionBlock {
+			return m
+		}
+		if flagged == nil {
+			flagged = m
+		}
+	}
+
+	if allowed, ok := e.allowlists[language]; ok {
+		for _, command := range commands(source) {
+			if !allowed[command] {
+				return &Match{Rule: allowlistRule, Action: ActionBlock, Excerpt: command}
+			}
+		}
+	}
+
+	return flagged
+}
+
+// match returns the rule's match in source, if any
+func (r *rule) match(language, source string, tokens []string) *Match {
+	if len(r.languages) > 0 && !r.languages[language] {
+		return nil
+	}
+	if r.pattern != nil {
+		if found := r.pattern.FindString(source); found != "" {
+			return &Match{Rule: r.name, Action: r.action, Excerpt: excerpt(found)}
+		}
+	}
+	for _, token := range tokens {
+		token = strings.ToLower(token)
+		if r.tokens[token] || r.tokens[path.Base(token)] {
+			return &Match{Rule: r.name, Action: r.action, Excerpt: excerpt(token)}
+		}
+	}
+	return nil
+}
+
+// excerpt shortens matching code for logs and replies
+func excerpt(s string) string {
+	runes := []rune(strings.Join(strings.Fields(s), " "))
+	if len(runes) > maxExcerpt {
+		return string(runes[:maxExcerpt]) + "…"
+	}
+	return string(runes)
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/anchitjain1234/discord-command-executor/internal/config"
+)
+
+func TestBuiltinRules(t *testing.T) {
+	e, err := New(config.PolicyConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		source   string
+		rule     string
+	}{
+		{name: "classic fork bomb", language: "bash", source: ":(){ :|:& };:", rule: "fork-bomb"},
+		{name: "named fork bomb", language: "sh", source: "bomb() {\n  bomb | bomb &\n}\nbomb", rule: "fork-bomb"},
+		{name: "fork bomb via system", language: "python", source: `os.system(":(){ :|: & };:")`, rule: "fork-bomb"},
+		{name: "python fork loop", language: "python", source: "import os\nwhile True:\n    os.fork()", rule: "fork-loop"},
+		{name: "c fork loop", language: "c", source: "int main() { for (;;) fork(); }", rule: "fork-loop"},
+		{name: "perl fork loop", language: "bash", source: "perl -e 'fork while fork'", rule: "fork-loop"},
+		{name: "miner binary", language: "bash", source: "curl -sL x.sh | sh && ./xmrig -o pool", rule: "crypto-miner"},
+		{name: "quoted miner name", language: "bash", source: `x"mr"ig --donate-level 1`, rule: "crypto-miner"},
+		{name: "miner path", language: "python", source: `subprocess.run(["/tmp/XMRig"])`, rule: "crypto-miner"},
+		{
+			name:     "stratum url",
+			language: "javascript",
+			source:   `connect("stratum+tcp://pool.example:3333")`,
+			rule:     "crypto-miner",
+		},
+		{name: "harmless", language: "bash", source: "for i in 1 2 3; do echo $i | cat & done; wait"},
+		{name: "harmless fork", language: "python", source: "pid = os.fork()\nif pid == 0:\n    print('child')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := e.Check(tt.language, tt.source)
+			if tt.rule == "" {
+				if m != nil {
+					t.Fatalf("Expected no match, got %+v", m)
+				}
+				return
+			}
+			if m == nil || m.Rule != tt.rule || m.Action != ActionBlock {
+				t.Fatalf("Expected block by %s, got %+v", tt.rule, m)
+			}
+		})
+	}
+}
+
+func TestConfiguredRules(t *testing.T) {
+	e, err := New(config.PolicyConfig{Rules: []config.PolicyRule{
+		{Name: "trusted-netcat", Pattern: `nc -z localhost`, Action: "allow"},
+		{Name: "sockets", Languages: []string{"py"}, Pattern: `import\s+socket`, Action: "flag"},
+		{Name: "netcat", Languages: []string{"bash"}, Tokens: []string{"nc", "ncat"}, Action: "block"},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		source   string
+		rule     string
+		action   Action
+	}{
+		{name: "flagged", language: "python", source: "import socket", rule: "sockets", action: ActionFlag},
+		{name: "other language", language: "javascript", source: "// import socket"},
+		{name: "token by path", language: "shell", source: "/usr/bin/NC -l 4444", rule: "netcat", action: ActionBlock},
+		{name: "allowed before block", language: "bash", source: "nc -z localhost 80"},
+		{name: "allow keeps built-ins", language: "bash", source: "nc -z localhost 80; xmrig", rule: "crypto-miner",
+			action: ActionBlock},
+		{name: "token within word", language: "bash", source: "echo sync"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := e.Check(tt.language, tt.source)
+			if tt.rule == "" {
+				if m != nil {
+					t.Fatalf("Expected no match, got %+v", m)
+				}
+				return
+			}
+			if m == nil || m.Rule != tt.rule || m.Action != tt.action {
+				t.Fatalf("Expected %s by %s, got %+v", tt.action, tt.rule, m)
+			}
+		})
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	e, err := New(config.PolicyConfig{Allowlists: []config.CommandAllowlist{
+		{Languages: []string{"bash"}, Commands: []string{"echo", "ls", "cat", "grep"}},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if m := e.Check("bash", "X=1 ls -la | grep main\nif echo ok; then cat f; fi"); m != nil {
+		t.Errorf("Expected allowed commands to pass, got %+v", m)
+	}
+	for _, source := range []string{"rm -rf /tmp/x", "echo $(curl x)", "{ wget x; }", "ls && /bin/sh"} {
+		if m := e.Check("bash", source); m == nil || m.Rule != allowlistRule {
+			t.Errorf("Expected allowlist block for %q, got %+v", source, m)
+		}
+	}
+	if m := e.Check("python", "import shutil"); m != nil {
+		t.Errorf("Expected the allowlist to apply only to its languages, got %+v", m)
+	}
+}
+
+func TestNewRejectsUnknownLanguages(t *testing.T) {
+	_, err := New(config.PolicyConfig{Rules: []config.PolicyRule{
+		{Name: "x", Languages: []string{"cobol"}, Tokens: []string{"x"}, Action: "block"},
+	}})
+	if err == nil {
+		t.Error("Expected an unknown language to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"path"
+	"strings"
+)
+
+// Shell keywords that precede a command, as in "if grep ..." or "do rm ..."
+var prefixKeywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "while": true, "until": true,
+	"do": true, "!": true, "time": true, "exec": true, "command": true, "builtin": true,
+	"function": true, "{": true,
+}
+
+// Shell keywords whose segment names no command, as in "for i in 1 2" or "done"
+var segmentKeywords = map[string]bool{
+	"for": true, "select": true, "case": true, "esac": true, "in": true,
+	"fi": true, "done": true, "}": true,
+}
+
+// words splits source into tokens: runs of characters that can form a
+// command name, path, URL or option, with quotes removed
+func words(source string) []string {
+	return strings.FieldsFunc(stripQuotes(source), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// isWordRune reports whether r can be part of a token
+func isWordRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("_-./+:@%~", r)
+}
+
+// stripQuotes removes quote characters and backslashes so that quoting
+// cannot split a name, as in x"mr"ig
+func stripQuotes(source string) string {
+	return strings.NewReplacer(`"`, "", "'", "", `\`, "").Replace(source)
+}
+
+// commands returns the names of the commands shell source runs, as base
+// names without directories. It is a best-effort reading of the source:
+// anything it cannot follow becomes a command name, so allowlists err
+// towards blocking.
+func commands(source string) []string {
+	source = stripQuotes(source)
+	// Brace groups need a blank after the brace, unlike ${var}
+	separators := []string{"$(", "&&", "||", ";", "&", "|", "(", ")", "`", "{ ", "{\t", "\n"}
+	for _, separator := range separators {
+		source = strings.ReplaceAll(source, separator, "\n")
+	}
+
+	var names []string
+	for _, segment := range strings.Split(source, "\n") {
+		if name := segmentCommand(strings.Fields(segment)); name != "" {
+			names = append(names, path.Base(name))
+		}
+	}
+	return names
+}
+
+// segmentCommand returns the command a simple command's fields run, or ""
+// if there is none: leading keywords and variable assignments are skipped
+func segmentCommand(fields []string) string {
+	for _, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "#"):
+			return ""
+		case segmentKeywords[field]:
+			return ""
+		case prefixKeywords[field]:
+			continue
+		case isAssignment(field):
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+// isAssignment reports whether a field is a variable assignment like X=1
+func isAssignment(field string) bool {
+	name, _, ok := strings.Cut(field, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && (i == 0 || !(r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
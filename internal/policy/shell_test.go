@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		source   string
+		commands []string
+	}{
+		{source: "echo hi", commands: []string{"echo"}},
+		{source: "LANG=C /usr/bin/sort -u f | uniq -c", commands: []string{"sort", "uniq"}},
+		{source: "if test -f x; then rm x; else touch x; fi", commands: []string{"test", "rm", "touch"}},
+		{source: "for f in *.go; do wc -l $f; done", commands: []string{"wc"}},
+		{source: "x=$(date) && echo `whoami`", commands: []string{"date", "echo", "whoami"}},
+		{source: "# comment only\nfunction f { id; }", commands: []string{"f", "id"}},
+		{source: `"cu"rl example.com`, commands: []string{"curl"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := commands(tt.source); !slices.Equal(got, tt.commands) {
+				t.Errorf("Expected commands %v, got %v", tt.commands, got)
+			}
+		})
+	}
+}
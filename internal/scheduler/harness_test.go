@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// simulation drives a scheduler through scripted contention on a virtual
+// clock. It never blocks or starts goroutines, so a script always produces
+// the same grants in the same order.
+type simulation struct {
+	t     *testing.T
+	start time.Time
+	now   time.Time
+	sched *Scheduler
+
+	pending []*job
+	running []*job
+	done    []*job
+
+	// Labels of jobs in the order they were granted
+	order []string
+
+	// Jobs submitted per user, for labels
+	submitted map[string]int
+}
+
+// job is one scripted request
+type job struct {
+	label    string
+	req      Request
+	arrive   time.Duration
+	duration time.Duration
+
+	ticket  *Ticket
+	granted time.Duration
+}
+
+// wait returns how long the job queued before its slot was granted
+func (j *job) wait() time.Duration {
+	return j.granted - j.arrive
+}
+
+// newSimulation creates a simulation of a scheduler with cfg
+func newSimulation(t *testing.T, cfg Config) *simulation {
+	t.Helper()
+	sim := &simulation{
+		t:         t,
+		start:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		submitted: make(map[string]int),
+	}
+	sim.now = sim.start
+	sim.sched = New(cfg, func() time.Time { return sim.now })
+	return sim
+}
+
+// submit scripts count requests from user in guild arriving at the given
+// offset, each holding its slot for duration. Labels are user-index.
+func (sim *simulation) submit(guild, user string, weight float64, at, duration time.Duration, count int) {
+	for i := 0; i < count; i++ {
+		sim.pending = append(sim.pending, &job{
+			label:    fmt.Sprintf("%s-%d", user, sim.submitted[user]),
+			req:      Request{UserID: user, GuildID: guild, Weight: weight},
+			arrive:   at,
+			duration: duration,
+		})
+		sim.submitted[user]++
+	}
+}
+
+// jobsOf returns the scripted jobs of a user
+func (sim *simulation) jobsOf(user string) []*job {
+	var jobs []*job
+	for _, list := range [][]*job{sim.done, sim.running, sim.pending} {
+		for _, j := range list {
+			if j.req.UserID == user {
+				jobs = append(jobs, j)
+			}
+		}
+	}
+	return jobs
+}
+
+// run advances the clock in steps until every job has finished. Each step
+// releases finished jobs, then enqueues arrivals, then dispatches.
+func (sim *simulation) run(step time.Duration) {
+	sim.t.Helper()
+	queued := make(map[*job]bool)
+
+	for limit := 0; len(sim.pending)+len(sim.running) > 0; limit++ {
+		if limit > 100000 {
+			sim.t.Fatal("Simulation did not finish")
+		}
+		elapsed := sim.now.Sub(sim.start)
+
+		s := sim.sched
+		s.mu.Lock()
+		var stillRunning []*job
+		for _, j := range sim.running {
+			if j.granted+j.duration <= elapsed {
+				s.release(j.ticket)
+				sim.done = append(sim.done, j)
+			} else {
+				stillRunning = append(stillRunning, j)
+			}
+		}
+		sim.running = stillRunning
+
+		byTicket := make(map[*Ticket]*job)
+		for _, j := range sim.pending {
+			if j.arrive <= elapsed && !queued[j] {
+				j.ticket = s.enqueue(j.req)
+				queued[j] = true
+			}
+			if queued[j] {
+				byTicket[j.ticket] = j
+			}
+		}
+
+		for _, t := range s.dispatch() {
+			j := byTicket[t]
+			j.granted = elapsed
+			sim.order = append(sim.order, j.label)
+			sim.running = append(sim.running, j)
+			sim.pending = removeJob(sim.pending, j)
+		}
+		s.mu.Unlock()
+
+		sim.now = sim.now.Add(step)
+	}
+}
+
+// removeJob returns jobs without j
+func removeJob(jobs []*job, j *job) []*job {
+	for i, candidate := range jobs {
+		if candidate == j {
+			return append(jobs[:i], jobs[i+1:]...)
+		}
+	}
+	return jobs
+}
+
+// grants counts grants per user among the first n grants
+func (sim *simulation) grants(n int) map[string]int {
+	counts := make(map[string]int)
+	for _, label := range sim.order[:min(n, len(sim.order))] {
+		for _, j := range sim.done {
+			if j.label == label {
+				counts[j.req.UserID]++
+				break
+			}
+		}
+	}
+	return counts
+}
+
+// maxWait returns the longest wait among a user's jobs
+func (sim *simulation) maxWait(user string) time.Duration {
+	var longest time.Duration
+	for _, j := range sim.jobsOf(user) {
+		longest = max(longest, j.wait())
+	}
+	return longest
+}
+
+// peakRunning replays the simulation's grants and returns the most jobs
+// running at once for which key returns id
+func (sim *simulation) peakRunning(key func(*job) string, id string) int {
+	type event struct {
+		at    time.Duration
+		delta int
+	}
+	var events []event
+	for _, j := range sim.done {
+		if key(j) == id {
+			events = append(events, event{at: j.granted, delta: 1}, event{at: j.granted + j.duration, delta: -1})
+		}
+	}
+	sort.Slice(events, func(a, b int) bool {
+		if events[a].at != events[b].at {
+			return events[a].at < events[b].at
+		}
+		return events[a].delta < events[b].delta
+	})
+
+	peak, current := 0, 0
+	for _, e := range events {
+		current += e.delta
+		peak = max(peak, current)
+	}
+	return peak
+}
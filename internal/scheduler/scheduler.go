@@ -0,0 +1,334 @@
+// Package scheduler shares a fixed number of execution slots fairly
+// between users and guilds.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Config controls how slots are shared
+type Config struct {
+	// Number of slots, at least 1
+	Slots int
+
+	// Maximum slots held at once per user and per guild; 0 is unlimited
+	MaxPerUser  int
+	MaxPerGuild int
+
+	// How long a request may wait before it is served ahead of fair order;
+	// 0 disables starvation protection
+	StarvationAge time.Duration
+}
+
+// Request identifies who is asking for a slot
+type Request struct {
+	UserID string
+
+	// Guild the request comes from; empty for direct messages, which are
+	// scheduled as a guild of their own per user
+	GuildID string
+
+	// Share of the guild's slots relative to its other users; values below
+	// 1 count as 1
+	Weight float64
+}
+
+// Ticket is a request's place in the queue and, once granted, its slot
+type Ticket struct {
+	req Request
+
+	// The guild, the user within the guild, and the user across guilds
+	guild  *key
+	member *key
+	user   *key
+
+	arrived time.Time
+	seq     uint64
+
+	// Closed when the slot is granted
+	ready   chan struct{}
+	granted bool
+}
+
+// key tracks the share and slots of one guild, user or user within a guild
+type key struct {
+	id string
+
+	// Virtual time: service received so far, scaled by weight
+	vtime float64
+
+	// For guilds, the virtual time of the latest member served; members
+	// becoming busy start here so idle time cannot be saved up
+	clock float64
+
+	running int
+	waiting int
+}
+
+// Scheduler grants slots using weighted fair queuing: among requests that
+// can run, it serves the guild that has received the least weighted
+// service, then that guild's user with the least, then that user's oldest
+// request. Requests waiting longer than the starvation age go first, and
+// per-user and per-guild caps hold requests back even when slots are free.
+type Scheduler struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	running int
+	queue   []*Ticket
+	seq     uint64
+	guilds  map[string]*key
+	members map[string]*key
+	users   map[string]*key
+
+	// Virtual time of the latest guild served; guilds becoming busy start
+	// here so idle time cannot be saved up
+	clock float64
+}
+
+// New creates a scheduler reading time from now
+func New(cfg Config, now func() time.Time) *Scheduler {
+	return &Scheduler{
+		cfg:     cfg,
+		now:     now,
+		guilds:  make(map[string]*key),
+		members: make(map[string]*key),
+		users:   make(map[string]*key),
+	}
+}
+
+// Acquire waits for a slot. It returns ctx's error, holding no slot, if ctx
+// is done first.
+func (s *Scheduler) Acquire(ctx context.Context, req Request) (*Ticket, error) {
+	s.mu.Lock()
+	t := s.enqueue(req)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return t, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.granted {
+		s.release(t)
+	} else {
+		s.withdraw(t)
+	}
+	s.dispatch()
+	return nil, ctx.Err()
+}
+
+// TryAcquire grants a slot only if one is free for req right away and no
+// one is waiting, so long-lived holders never jump the queue
+func (s *Scheduler) TryAcquire(req Request) (*Ticket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) > 0 || s.running >= s.cfg.Slots {
+		return nil, false
+	}
+	t := s.enqueue(req)
+	if !s.eligible(t) {
+		s.withdraw(t)
+		return nil, false
+	}
+	s.grant(t)
+	return t, true
+}
+
+// Release returns a granted slot
+func (s *Scheduler) Release(t *Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release(t)
+	s.dispatch()
+}
+
+// Waiting returns the number of queued requests
+func (s *Scheduler) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// enqueue adds a request to the queue; the caller holds mu
+func (s *Scheduler) enqueue(req Request) *Ticket {
+	guildID := "guild:" + req.GuildID
+	if req.GuildID == "" {
+		guildID = "dm:" + req.UserID
+	}
+
+	s.seq++
+	t := &Ticket{req: req, arrived: s.now(), seq: s.seq, ready: make(chan struct{})}
+	t.guild = lookup(s.guilds, guildID, s.clock)
+	t.member = lookup(s.members, guildID+"/"+req.UserID, t.guild.clock)
+	t.user = lookup(s.users, req.UserID, 0)
+
+	for _, k := range t.keys() {
+		k.waiting++
+	}
+	s.queue = append(s.queue, t)
+	return t
+}
+
+// lookup returns the key for id, creating it at virtual time start
+func lookup(keys map[string]*key, id string, start float64) *key {
+	k, ok := keys[id]
+	if !ok {
+		k = &key{id: id, vtime: start, clock: start}
+		keys[id] = k
+	}
+	return k
+}
+
+// keys returns the keys a ticket counts against
+func (t *Ticket) keys() []*key {
+	return []*key{t.guild, t.member, t.user}
+}
+
+// dispatch grants free slots to waiting requests; the caller holds mu.
+// It returns the tickets granted.
+func (s *Scheduler) dispatch() []*Ticket {
+	var granted []*Ticket
+	for s.running < s.cfg.Slots {
+		t := s.next()
+		if t == nil {
+			break
+		}
+		s.grant(t)
+		granted = append(granted, t)
+	}
+	return granted
+}
+
+// next picks the request to serve next, or nil if none can run. Starving
+// requests go first, oldest first, in fair order when they arrived together.
+// The caller holds mu.
+func (s *Scheduler) next() *Ticket {
+	var best, starved *Ticket
+	now := s.now()
+
+	for _, t := range s.queue {
+		if !s.eligible(t) {
+			continue
+		}
+		if s.starving(t, now) {
+			// The queue is in arrival order, so later requests are never older
+			if starved == nil || (t.arrived.Equal(starved.arrived) && fairer(t, starved)) {
+				starved = t
+			}
+			continue
+		}
+		if best == nil || fairer(t, best) {
+			best = t
+		}
+	}
+	if starved != nil {
+		return starved
+	}
+	return best
+}
+
+// fairer reports whether a should be served before b: lower guild virtual
+// time first, then lower virtual time of the user within the guild, then
+// earlier arrival
+func fairer(a, b *Ticket) bool {
+	if a.guild.vtime != b.guild.vtime {
+		return a.guild.vtime < b.guild.vtime
+	}
+	if a.member.vtime != b.member.vtime {
+		return a.member.vtime < b.member.vtime
+	}
+	return a.seq < b.seq
+}
+
+// eligible reports whether t's user and guild are below their caps; the
+// caller holds mu
+func (s *Scheduler) eligible(t *Ticket) bool {
+	if s.cfg.MaxPerUser > 0 && t.user.running >= s.cfg.MaxPerUser {
+		return false
+	}
+	if s.cfg.MaxPerGuild > 0 && t.guild.running >= s.cfg.MaxPerGuild {
+		return false
+	}
+	return true
+}
+
+// starving reports whether t has waited past the starvation age
+func (s *Scheduler) starving(t *Ticket, now time.Time) bool {
+	return s.cfg.StarvationAge > 0 && now.Sub(t.arrived) >= s.cfg.StarvationAge
+}
+
+// grant gives t a slot and charges its guild and user; the caller holds mu
+func (s *Scheduler) grant(t *Ticket) {
+	s.remove(t)
+	for _, k := range t.keys() {
+		k.waiting--
+		k.running++
+	}
+	s.running++
+
+	// Each run costs one unit of service. Weights only boost a user's share
+	// within the guild, so every guild keeps an equal share.
+	s.clock = max(s.clock, t.guild.vtime)
+	t.guild.clock = max(t.guild.clock, t.member.vtime)
+	t.guild.vtime++
+	t.member.vtime += 1 / max(t.req.Weight, 1)
+
+	t.granted = true
+	close(t.ready)
+}
+
+// release frees t's slot; the caller holds mu
+func (s *Scheduler) release(t *Ticket) {
+	if !t.granted {
+		return
+	}
+	t.granted = false
+	for _, k := range t.keys() {
+		k.running--
+	}
+	s.running--
+	s.forget(t)
+}
+
+// withdraw removes a request that gave up waiting; the caller holds mu
+func (s *Scheduler) withdraw(t *Ticket) {
+	s.remove(t)
+	for _, k := range t.keys() {
+		k.waiting--
+	}
+	s.forget(t)
+}
+
+// remove takes t out of the queue; the caller holds mu
+func (s *Scheduler) remove(t *Ticket) {
+	for i, queued := range s.queue {
+		if queued == t {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// forget drops idle keys; they restart at the virtual clock when next
+// used, so nothing is lost. The caller holds mu.
+func (s *Scheduler) forget(t *Ticket) {
+	drop(s.guilds, t.guild)
+	drop(s.members, t.member)
+	drop(s.users, t.user)
+}
+
+// drop deletes k from keys if it has nothing running or waiting
+func drop(keys map[string]*key, k *key) {
+	if k.running == 0 && k.waiting == 0 {
+		delete(keys, k.id)
+	}
+}
@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpammerDoesNotStarveOthers(t *testing.T) {
+	sim := newSimulation(t, Config{Slots: 2})
+	sim.submit("g1", "spammer", 1, 0, 5*time.Second, 20)
+	sim.submit("g1", "neighbour", 1, time.Second, 5*time.Second, 1)
+	sim.submit("g2", "stranger", 1, time.Second, 5*time.Second, 1)
+	sim.run(time.Second)
+
+	for _, user := range []string{"neighbour", "stranger"} {
+		if wait := sim.maxWait(user); wait > 4*time.Second {
+			t.Errorf("Expected %s to run in the first free slot, waited %s", user, wait)
+		}
+	}
+	if sim.order[2] != "stranger-0" || sim.order[3] != "neighbour-0" {
+		t.Errorf("Expected the other guild, then the quieter user, to go next; got %v", sim.order[:4])
+	}
+}
+
+func TestGuildsShareSlotsEqually(t *testing.T) {
+	sim := newSimulation(t, Config{Slots: 1})
+	for _, user := range []string{"a1", "a2", "a3"} {
+		sim.submit("busy", user, 1, 0, time.Second, 10)
+	}
+	sim.submit("quiet", "b1", 5, 0, time.Second, 10)
+	sim.run(time.Second)
+
+	counts := sim.grants(20)
+	if counts["b1"] != 10 {
+		t.Errorf("Expected the quiet guild to get half of the first 20 slots, got %d", counts["b1"])
+	}
+	for _, user := range []string{"a1", "a2", "a3"} {
+		if counts[user] < 3 || counts[user] > 4 {
+			t.Errorf("Expected %s to get a third of its guild's share, got %d", user, counts[user])
+		}
+	}
+}
+
+func TestWeightBoostsShareWithinGuild(t *testing.T) {
+	sim := newSimulation(t, Config{Slots: 1})
+	sim.submit("g1", "booster", 2, 0, time.Second, 20)
+	sim.submit("g1", "regular", 1, 0, time.Second, 20)
+	sim.run(time.Second)
+
+	counts := sim.grants(15)
+	if counts["booster"] != 10 || counts["regular"] != 5 {
+		t.Errorf("Expected a 2:1 split of the first 15 slots, got %v", counts)
+	}
+}
+
+func TestPerUserCap(t *testing.T) {
+	sim := newSimulation(t, Config{Slots: 3, MaxPerUser: 1})
+	sim.submit("g1", "greedy", 1, 0, 2*time.Second, 5)
+	sim.submit("g1", "other", 1, time.Second, 2*time.Second, 1)
+	sim.run(time.Second)
+
+	byUser := func(j *job) string { return j.req.UserID }
+	if peak := sim.peakRunning(byUser, "greedy"); peak != 1 {
+		t.Errorf("Expected at most 1 run at once for a capped user, got %d", peak)
+	}
+	if wait := sim.maxWait("other"); wait != 0 {
+		t.Errorf("Expected a free slot for another user right away, waited %s", wait)
+	}
+}
+
+func TestPerGuildCap(t *testing.T) {
+	sim := newSimulation(t, Config{Slots: 4, MaxPerGuild: 2})
+	for _, user := range []string{"a1", "a2", "a3"} {
+		sim.submit("g1", user, 1, 0, 2*time.Second, 2)
+	}
+	sim.submit("", "dm-user", 1, 0, 2*time.Second, 2)
+	sim.run(time.Second)
+
+	byGuild := func(j *job) string { return j.req.GuildID }
+	if peak := sim.peakRunning(byGuild, "g1"); peak != 2 {
+		t.Errorf("Expected at most 2 runs at once for a capped guild, got %d", peak)
+	}
+	if peak := sim.peakRunning(byGuild, ""); peak != 2 {
+		t.Errorf("Expected direct messages to be capped per user, got %d at once", peak)
+	}
+}
+
+func TestStarvationProtection(t *testing.T) {
+	script := func(age time.Duration) *simulation {
+		sim := newSimulation(t, Config{Slots: 1, StarvationAge: age})
+		// The heavy user keeps a backlog and adds a request every second
+		sim.submit("g1", "heavy", 100, 0, time.Second, 5)
+		for i := 1; i <= 50; i++ {
+			sim.submit("g1", "heavy", 100, time.Duration(i)*time.Second, time.Second, 1)
+		}
+		sim.submit("g1", "light", 1, 0, time.Second, 2)
+		sim.run(time.Second)
+		return sim
+	}
+
+	if wait := script(0).maxWait("light"); wait < 40*time.Second {
+		t.Fatalf("Expected the light user to wait behind the heavy one without protection, waited %s", wait)
+	}
+	if wait := script(10 * time.Second).maxWait("light"); wait > 10*time.Second {
+		t.Errorf("Expected the light user to run once starving after 10s, waited %s", wait)
+	}
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	script := func() []string {
+		sim := newSimulation(t, Config{Slots: 3, MaxPerUser: 2, StarvationAge: 5 * time.Second})
+		sim.submit("g1", "a", 1, 0, 3*time.Second, 8)
+		sim.submit("g1", "b", 3, time.Second, 2*time.Second, 8)
+		sim.submit("g2", "c", 1, 2*time.Second, time.Second, 8)
+		sim.run(time.Second)
+		return sim.order
+	}
+
+	first, second := script(), script()
+	if len(first) != 24 {
+		t.Fatalf("Expected 24 grants, got %d", len(first))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected identical grant order, runs differ at %d: %v vs %v", i, first, second)
+		}
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	s := New(Config{Slots: 2, MaxPerUser: 1}, time.Now)
+
+	held, ok := s.TryAcquire(Request{UserID: "u1", GuildID: "g1"})
+	if !ok {
+		t.Fatal("Expected a free slot to be granted")
+	}
+	if _, ok := s.TryAcquire(Request{UserID: "u1", GuildID: "g1"}); ok {
+		t.Fatal("Expected the user cap to refuse a second slot")
+	}
+	if waiting := s.Waiting(); waiting != 0 {
+		t.Fatalf("Expected a refused request not to stay queued, %d waiting", waiting)
+	}
+
+	// A capped waiter leaves a slot free but still comes first
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	acquired := make(chan *Ticket)
+	go func() {
+		ticket, _ := s.Acquire(ctx, Request{UserID: "u1", GuildID: "g1"})
+		acquired <- ticket
+	}()
+	waitForQueue(t, s, 1)
+
+	if _, ok := s.TryAcquire(Request{UserID: "u2", GuildID: "g1"}); ok {
+		t.Error("Expected a request to wait behind queued ones")
+	}
+
+	s.Release(held)
+	s.Release(<-acquired)
+	if len(s.guilds)+len(s.members)+len(s.users) != 0 {
+		t.Error("Expected idle keys to be dropped")
+	}
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	s := New(Config{Slots: 1}, time.Now)
+	held, err := s.Acquire(context.Background(), Request{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("Expected a free slot to be granted, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, Request{UserID: "u2"})
+		errs <- err
+	}()
+	waitForQueue(t, s, 1)
+	cancel()
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a canceled wait, got %v", err)
+	}
+	if waiting := s.Waiting(); waiting != 0 {
+		t.Errorf("Expected the canceled request to leave the queue, %d waiting", waiting)
+	}
+
+	s.Release(held)
+	if _, ok := s.TryAcquire(Request{UserID: "u3"}); !ok {
+		t.Error("Expected the released slot to be free")
+	}
+}
+
+// waitForQueue waits until n requests are queued
+func waitForQueue(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Waiting() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d queued requests", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Execution is a past run kept in a user's history
+type Execution struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	ChannelID string    `json:"channel_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// What ran; uploaded files are listed by name but their contents are not kept
+	Language string   `json:"language"`
+	Code     string   `json:"code,omitempty"`
+	Stdin    string   `json:"stdin,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	Files    []string `json:"files,omitempty"`
+	Tier     string   `json:"tier,omitempty"`
+	Network  string   `json:"network,omitempty"`
+
+	// Output of the run phase, or of compilation when it failed, cut to the
+	// history output limit
+	Output        string `json:"output"`
+	Truncated     bool   `json:"truncated,omitempty"`
+	CompileFailed bool   `json:"compile_failed,omitempty"`
+
+	// How the phase ended and what it used
+	Reason      string        `json:"reason"`
+	ExitCode    int           `json:"exit_code"`
+	Duration    time.Duration `json:"duration"`
+	CPUTime     time.Duration `json:"cpu_time"`
+	PeakMemory  uint64        `json:"peak_memory,omitempty"`
+	MemoryLimit uint64        `json:"memory_limit,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+}
+
+// Limit cuts the output to at most n bytes without splitting a character
+func (e *Execution) Limit(n int) {
+	if len(e.Output) <= n {
+		return
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(e.Output[cut]) {
+		cut--
+	}
+	e.Output = e.Output[:cut]
+	e.Truncated = true
+}
+
+// Summary returns the first non-blank line of the code, or the uploaded
+// file names when there is no inline code
+func (e *Execution) Summary() string {
+	for _, line := range strings.Split(e.Code, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return strings.Join(e.Files, ", ")
+}
+
+// SaveExecution adds e to its user's history, replacing any record with
+// the same ID
+func (s *Store) SaveExecution(e *Execution) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT OR REPLACE INTO executions (id, user_id, created_at, record) VALUES (?, ?, ?, ?)",
+		e.ID, e.UserID, e.CreatedAt.UnixMilli(), data)
+	if err != nil {
+		return fmt.Errorf("failed to save execution: %w", err)
+	}
+	return nil
+}
+
+// Execution returns the record with the given ID
+func (s *Store) Execution(id string) (*Execution, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT record FROM executions WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution: %w", err)
+	}
+	return decodeExecution(data)
+}
+
+// Executions returns up to limit of a user's records, newest first, after
+// skipping offset of them, together with how many the user has in total
+func (s *Store) Executions(userID string, offset, limit int) ([]*Execution, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM executions WHERE user_id = ?", userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count executions: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT record FROM executions WHERE user_id = ? ORDER BY created_at DESC, rowid DESC LIMIT ? OFFSET ?",
+		userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list executions: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*Execution
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, err
+		}
+		e, err := decodeExecution(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, e)
+	}
+	return records, total, rows.Err()
+}
+
+// PruneExecutions deletes records created before the given time, unless it
+// is zero, and all but each user's newest maxPerUser records, unless it is
+// 0. It returns how many records were deleted.
+func (s *Store) PruneExecutions(before time.Time, maxPerUser int) (int64, error) {
+	var deleted int64
+	if !before.IsZero() {
+		res, err := s.db.Exec("DELETE FROM executions WHERE created_at < ?", before.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune executions: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if maxPerUser > 0 {
+		res, err := s.db.Exec(`DELETE FROM executions WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY user_id ORDER BY created_at DESC, rowid DESC) AS n
+				FROM executions)
+			WHERE n > ?)`, maxPerUser)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to prune executions: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// decodeExecution decodes a stored record
+func decodeExecution(data []byte) (*Execution, error) {
+	var e Execution
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("malformed execution record: %w", err)
+	}
+	return &e, nil
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openStore opens a store in a temporary directory
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// saveExecutions saves n runs by user, one minute apart from start
+func saveExecutions(t *testing.T, s *Store, user string, start time.Time, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := s.SaveExecution(&Execution{
+			ID:        fmt.Sprintf("%s-%d", user, i),
+			UserID:    user,
+			ChannelID: "c1",
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+			Language:  "python",
+			Code:      fmt.Sprintf("print(%d)", i),
+			Output:    fmt.Sprintf("%d\n", i),
+			Reason:    "success",
+		})
+		if err != nil {
+			t.Fatalf("Failed to save execution: %v", err)
+		}
+	}
+}
+
+func TestExecutionsPaginateNewestFirst(t *testing.T) {
+	s := openStore(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	saveExecutions(t, s, "u1", start, 5)
+	saveExecutions(t, s, "u2", start, 2)
+
+	page, total, err := s.Executions("u1", 2, 2)
+	if err != nil {
+		t.Fatalf("Failed to list executions: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("Expected 5 executions for u1, got %d", total)
+	}
+	if len(page) != 2 || page[0].ID != "u1-2" || page[1].ID != "u1-1" {
+		t.Errorf("Expected the second page to hold u1-2 and u1-1, got %v", page)
+	}
+
+	e, err := s.Execution("u1-4")
+	if err != nil {
+		t.Fatalf("Failed to read execution: %v", err)
+	}
+	if e.Code != "print(4)" || e.Output != "4\n" || !e.CreatedAt.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("Expected the stored record back, got %+v", e)
+	}
+	if _, err := s.Execution("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown ID, got %v", err)
+	}
+}
+
+func TestPruneExecutions(t *testing.T) {
+	s := openStore(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	saveExecutions(t, s, "u1", start, 6)
+	saveExecutions(t, s, "u2", start, 2)
+
+	deleted, err := s.PruneExecutions(start.Add(time.Minute), 3)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	// u1-0 and u2-0 are too old, then u1-1 and u1-2 exceed the per-user limit
+	if deleted != 4 {
+		t.Errorf("Expected 4 records pruned, got %d", deleted)
+	}
+	for user, want := range map[string]int{"u1": 3, "u2": 1} {
+		if _, total, _ := s.Executions(user, 0, 10); total != want {
+			t.Errorf("Expected %d records left for %s, got %d", want, user, total)
+		}
+	}
+}
+
+func TestForgetUser(t *testing.T) {
+	s := openStore(t)
+	saveExecutions(t, s, "u1", time.Now(), 3)
+	saveExecutions(t, s, "u2", time.Now(), 1)
+
+	forgotten, err := s.ForgetUser("u1")
+	if err != nil {
+		t.Fatalf("Failed to forget user: %v", err)
+	}
+	if forgotten != (Forgotten{Executions: 3}) {
+		t.Errorf("Expected 3 runs deleted, got %+v", forgotten)
+	}
+	if _, total, _ := s.Executions("u2", 0, 10); total != 1 {
+		t.Errorf("Expected other users' history to be kept, got %d records", total)
+	}
+}
+
+func TestExecutionLimit(t *testing.T) {
+	e := &Execution{Output: "héllo"}
+	e.Limit(2)
+	if e.Output != "h" || !e.Truncated {
+		t.Errorf("Expected the output cut before a split character, got %q", e.Output)
+	}
+}
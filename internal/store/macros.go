@@ -0,0 +1,86 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Macro kinds
+const (
+	// Aliases standing for a command line
+	MacroAlias = "alias"
+
+	// Templates wrapping submitted code
+	MacroTemplate = "template"
+)
+
+// Macro is an alias or template an admin added to a guild
+type Macro struct {
+	GuildID string
+	Kind    string
+	Name    string
+
+	// Who added the macro; empty once they asked to be forgotten
+	AuthorID  string
+	CreatedAt time.Time
+
+	// Language of a template; empty for aliases
+	Language string
+
+	// Command an alias stands for, or a template's source
+	Body string
+}
+
+// SaveMacro adds a macro, replacing one of the same kind and name in its guild
+func (s *Store) SaveMacro(m *Macro) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO macros (guild_id, kind, name, author_id, created_at, language, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.GuildID, m.Kind, m.Name, m.AuthorID, m.CreatedAt.UnixMilli(), m.Language, m.Body)
+	if err != nil {
+		return fmt.Errorf("failed to save macro: %w", err)
+	}
+	return nil
+}
+
+// Macros returns every guild's macros by guild, kind and name
+func (s *Store) Macros() ([]*Macro, error) {
+	rows, err := s.db.Query(`SELECT guild_id, kind, name, author_id, created_at, language, body FROM macros
+		ORDER BY guild_id, kind, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list macros: %w", err)
+	}
+	defer rows.Close()
+
+	var macros []*Macro
+	for rows.Next() {
+		var m Macro
+		var created int64
+		if err := rows.Scan(&m.GuildID, &m.Kind, &m.Name, &m.AuthorID, &created, &m.Language, &m.Body); err != nil {
+			return nil, err
+		}
+		m.CreatedAt = time.UnixMilli(created).UTC()
+		macros = append(macros, &m)
+	}
+	return macros, rows.Err()
+}
+
+// CountMacros returns how many macros of any kind a guild has
+func (s *Store) CountMacros(guildID string) (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM macros WHERE guild_id = ?", guildID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count macros: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteMacro deletes a guild's macro, returning ErrNotFound if there is none
+func (s *Store) DeleteMacro(guildID, kind, name string) error {
+	res, err := s.db.Exec("DELETE FROM macros WHERE guild_id = ? AND kind = ? AND name = ?", guildID, kind, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete macro: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMacros(t *testing.T) {
+	s := openStore(t)
+	for _, m := range []*Macro{
+		{GuildID: "g1", Kind: MacroAlias, Name: "py", AuthorID: "u1", Body: "run python"},
+		{GuildID: "g1", Kind: MacroTemplate, Name: "py", AuthorID: "u1", Language: "python", Body: "{{code}}"},
+		{GuildID: "g2", Kind: MacroAlias, Name: "js", AuthorID: "u2", Body: "run javascript"},
+		{GuildID: "g1", Kind: MacroAlias, Name: "py", AuthorID: "u2", Body: "run python --tier=large"},
+	} {
+		m.CreatedAt = time.Now()
+		if err := s.SaveMacro(m); err != nil {
+			t.Fatalf("Failed to save macro: %v", err)
+		}
+	}
+
+	macros, err := s.Macros()
+	if err != nil {
+		t.Fatalf("Failed to list macros: %v", err)
+	}
+	if len(macros) != 3 || macros[0].Body != "run python --tier=large" || macros[1].Kind != MacroTemplate {
+		t.Errorf("Expected replaced aliases and templates kept apart, got %+v", macros)
+	}
+	if n, _ := s.CountMacros("g1"); n != 2 {
+		t.Errorf("Expected 2 macros in g1, got %d", n)
+	}
+
+	if err := s.DeleteMacro("g1", MacroAlias, "py"); err != nil {
+		t.Errorf("Failed to delete macro: %v", err)
+	}
+	if err := s.DeleteMacro("g1", MacroAlias, "py"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a deleted macro, got %v", err)
+	}
+
+	if _, err := s.ForgetUser("u1"); err != nil {
+		t.Fatalf("Failed to forget user: %v", err)
+	}
+	macros, _ = s.Macros()
+	if len(macros) != 2 || macros[0].AuthorID != "" {
+		t.Errorf("Expected macros kept without an author, got %+v", macros)
+	}
+}
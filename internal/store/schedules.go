@@ -0,0 +1,154 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule runs a snippet or inline code repeatedly, posting the results
+// to a channel
+type Schedule struct {
+	ID int64 `json:"-"`
+
+	// Who created the schedule; runs are checked against their permissions
+	// and limits
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Cron expression or interval, as given
+	Spec string `json:"spec"`
+
+	// Snippet run each time, looked up as its creator would; empty when
+	// the schedule holds its own code
+	Snippet       string `json:"snippet,omitempty"`
+	SnippetShared bool   `json:"snippet_shared,omitempty"`
+
+	Language string   `json:"language,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Args     []string `json:"args,omitempty"`
+
+	Paused  bool      `json:"paused"`
+	NextRun time.Time `json:"next_run"`
+
+	// Outcome of the latest run and how many runs in a row have failed
+	LastRun    time.Time `json:"last_run"`
+	LastStatus string    `json:"last_status,omitempty"`
+	Failures   int       `json:"failures,omitempty"`
+}
+
+// AddSchedule stores a new schedule, setting its ID
+func (s *Store) AddSchedule(sc *Schedule) error {
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec("INSERT INTO schedules (guild_id, user_id, paused, next_run, record) VALUES (?, ?, ?, ?, ?)",
+		sc.GuildID, sc.UserID, sc.Paused, sc.NextRun.UnixMilli(), data)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	sc.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateSchedule stores changes to an existing schedule
+func (s *Store) UpdateSchedule(sc *Schedule) error {
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec("UPDATE schedules SET paused = ?, next_run = ?, record = ? WHERE id = ?",
+		sc.Paused, sc.NextRun.UnixMilli(), data, sc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Schedule returns the schedule with the given ID
+func (s *Store) Schedule(id int64) (*Schedule, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT record FROM schedules WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	return decodeSchedule(id, data)
+}
+
+// Schedules returns a guild's schedules in the order they were created
+func (s *Store) Schedules(guildID string) ([]*Schedule, error) {
+	return s.querySchedules("SELECT id, record FROM schedules WHERE guild_id = ? ORDER BY id", guildID)
+}
+
+// DueSchedules returns the schedules that are not paused and due at now,
+// the longest overdue first
+func (s *Store) DueSchedules(now time.Time) ([]*Schedule, error) {
+	return s.querySchedules("SELECT id, record FROM schedules WHERE paused = 0 AND next_run <= ? ORDER BY next_run",
+		now.UnixMilli())
+}
+
+// CountSchedules returns how many schedules a guild has
+func (s *Store) CountSchedules(guildID string) (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM schedules WHERE guild_id = ?", guildID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count schedules: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteSchedule deletes a schedule
+func (s *Store) DeleteSchedule(id int64) error {
+	res, err := s.db.Exec("DELETE FROM schedules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// querySchedules runs a query selecting the ID and record of schedules
+func (s *Store) querySchedules(query string, args ...any) ([]*Schedule, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*Schedule
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		sc, err := decodeSchedule(id, data)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sc)
+	}
+	return schedules, rows.Err()
+}
+
+// decodeSchedule decodes a stored schedule
+func decodeSchedule(id int64, data []byte) (*Schedule, error) {
+	var sc Schedule
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("malformed schedule %d: %w", id, err)
+	}
+	sc.ID = id
+	return &sc, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDueSchedules(t *testing.T) {
+	s := openStore(t)
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	schedules := []*Schedule{
+		{GuildID: "g1", UserID: "u1", Spec: "@every 1h", Snippet: "late", NextRun: now.Add(-time.Hour)},
+		{GuildID: "g1", UserID: "u1", Spec: "@every 1h", Snippet: "due", NextRun: now},
+		{GuildID: "g1", UserID: "u2", Spec: "@every 1h", Snippet: "paused", NextRun: now, Paused: true},
+		{GuildID: "g2", UserID: "u1", Spec: "@every 1h", Snippet: "future", NextRun: now.Add(time.Minute)},
+	}
+	for _, sc := range schedules {
+		if err := s.AddSchedule(sc); err != nil {
+			t.Fatalf("Failed to add schedule: %v", err)
+		}
+	}
+
+	due, err := s.DueSchedules(now)
+	if err != nil {
+		t.Fatalf("Failed to list due schedules: %v", err)
+	}
+	if len(due) != 2 || due[0].Snippet != "late" || due[1].Snippet != "due" {
+		t.Errorf("Expected the unpaused due schedules, most overdue first, got %+v", due)
+	}
+	if due[0].ID != schedules[0].ID {
+		t.Errorf("Expected IDs to be read back, got %d", due[0].ID)
+	}
+
+	due[0].NextRun = now.Add(time.Hour)
+	due[0].Failures = 2
+	if err := s.UpdateSchedule(due[0]); err != nil {
+		t.Fatalf("Failed to update schedule: %v", err)
+	}
+	if sc, _ := s.Schedule(due[0].ID); sc.Failures != 2 || !sc.NextRun.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected the update to be stored, got %+v", sc)
+	}
+	if n, _ := s.CountSchedules("g1"); n != 3 {
+		t.Errorf("Expected 3 schedules in g1, got %d", n)
+	}
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	s := openStore(t)
+	sc := &Schedule{GuildID: "g1", UserID: "u1", Spec: "0 9 * * *", Language: "python", Code: "print(1)"}
+	if err := s.AddSchedule(sc); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteSchedule(sc.ID); err != nil {
+		t.Fatalf("Failed to delete schedule: %v", err)
+	}
+	if _, err := s.Schedule(sc.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the schedule to be gone, got %v", err)
+	}
+	if err := s.UpdateSchedule(sc); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected updating a deleted schedule to fail, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Snippet scopes
+const (
+	// Snippets only their owner can see and run
+	ScopeUser = "user"
+
+	// Snippets shared with everyone in a guild
+	ScopeGuild = "guild"
+)
+
+// Namespace is a set of snippets with unique names: a user's own or a guild's
+type Namespace struct {
+	Scope string
+
+	// User or guild ID
+	Owner string
+}
+
+// UserNamespace returns the namespace of a user's own snippets
+func UserNamespace(userID string) Namespace {
+	return Namespace{Scope: ScopeUser, Owner: userID}
+}
+
+// GuildNamespace returns the namespace of a guild's shared snippets
+func GuildNamespace(guildID string) Namespace {
+	return Namespace{Scope: ScopeGuild, Owner: guildID}
+}
+
+// Snippet is one version of saved code. Saving under an existing name adds
+// a version rather than replacing the code.
+type Snippet struct {
+	Namespace Namespace `json:"-"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+
+	// Who saved this version; empty once they asked to be forgotten
+	AuthorID  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Language string   `json:"language"`
+	Code     string   `json:"code"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// snippetColumns are selected by every snippet query, in scanSnippet's order
+const snippetColumns = "scope, owner, name, version, author_id, created_at, language, code, tags"
+
+// SaveSnippet stores sn as the next version of its name, setting its
+// version, and deletes versions beyond the newest maxVersions unless it is 0
+func (s *Store) SaveSnippet(sn *Snippet, maxVersions int) error {
+	tags, err := json.Marshal(sn.Tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save snippet: %w", err)
+	}
+	defer tx.Rollback()
+
+	ns := sn.Namespace
+	err = tx.QueryRow("SELECT COALESCE(MAX(version), 0) + 1 FROM snippets WHERE scope = ? AND owner = ? AND name = ?",
+		ns.Scope, ns.Owner, sn.Name).Scan(&sn.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save snippet: %w", err)
+	}
+	_, err = tx.Exec("INSERT INTO snippets ("+snippetColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		ns.Scope, ns.Owner, sn.Name, sn.Version, sn.AuthorID, sn.CreatedAt.UnixMilli(), sn.Language, sn.Code, tags)
+	if err != nil {
+		return fmt.Errorf("failed to save snippet: %w", err)
+	}
+	if maxVersions > 0 {
+		_, err = tx.Exec("DELETE FROM snippets WHERE scope = ? AND owner = ? AND name = ? AND version <= ?",
+			ns.Scope, ns.Owner, sn.Name, sn.Version-maxVersions)
+		if err != nil {
+			return fmt.Errorf("failed to prune snippet versions: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Snippet returns a version of a snippet, or the latest when version is 0
+func (s *Store) Snippet(ns Namespace, name string, version int) (*Snippet, error) {
+	query := "SELECT " + snippetColumns + " FROM snippets WHERE scope = ? AND owner = ? AND name = ?"
+	args := []any{ns.Scope, ns.Owner, name}
+	if version > 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+	query += " ORDER BY version DESC LIMIT 1"
+
+	sn, err := scanSnippet(s.db.QueryRow(query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippet: %w", err)
+	}
+	return sn, nil
+}
+
+// SnippetVersions returns the stored version numbers of a snippet, newest first
+func (s *Store) SnippetVersions(ns Namespace, name string) ([]int, error) {
+	rows, err := s.db.Query(
+		"SELECT version FROM snippets WHERE scope = ? AND owner = ? AND name = ? ORDER BY version DESC",
+		ns.Scope, ns.Owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippet versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// Snippets returns the latest version of every snippet in a namespace by
+// name, keeping only those tagged with tag unless it is empty
+func (s *Store) Snippets(ns Namespace, tag string) ([]*Snippet, error) {
+	snippets, err := s.querySnippets(`SELECT `+snippetColumns+` FROM snippets AS s
+		WHERE scope = ? AND owner = ? AND version = (
+			SELECT MAX(version) FROM snippets WHERE scope = s.scope AND owner = s.owner AND name = s.name)
+		ORDER BY name`, ns.Scope, ns.Owner)
+	if err != nil || tag == "" {
+		return snippets, err
+	}
+	return slices.DeleteFunc(snippets, func(sn *Snippet) bool { return !slices.Contains(sn.Tags, tag) }), nil
+}
+
+// AllSnippets returns every version of every snippet in a namespace, by
+// name and then version
+func (s *Store) AllSnippets(ns Namespace) ([]*Snippet, error) {
+	return s.querySnippets("SELECT "+snippetColumns+" FROM snippets WHERE scope = ? AND owner = ? ORDER BY name, version",
+		ns.Scope, ns.Owner)
+}
+
+// CountSnippets returns how many snippet names a namespace holds
+func (s *Store) CountSnippets(ns Namespace) (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(DISTINCT name) FROM snippets WHERE scope = ? AND owner = ?",
+		ns.Scope, ns.Owner).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count snippets: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteSnippet deletes every version of a snippet, returning how many
+// were removed
+func (s *Store) DeleteSnippet(ns Namespace, name string) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM snippets WHERE scope = ? AND owner = ? AND name = ?", ns.Scope, ns.Owner, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete snippet: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// querySnippets runs a query selecting snippetColumns
+func (s *Store) querySnippets(query string, args ...any) ([]*Snippet, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snippets: %w", err)
+	}
+	defer rows.Close()
+
+	var snippets []*Snippet
+	for rows.Next() {
+		sn, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, sn)
+	}
+	return snippets, rows.Err()
+}
+
+// scanSnippet reads a row of snippetColumns
+func scanSnippet(row interface{ Scan(...any) error }) (*Snippet, error) {
+	var sn Snippet
+	var created int64
+	var tags []byte
+	err := row.Scan(&sn.Namespace.Scope, &sn.Namespace.Owner, &sn.Name, &sn.Version, &sn.AuthorID, &created,
+		&sn.Language, &sn.Code, &tags)
+	if err != nil {
+		return nil, err
+	}
+	sn.CreatedAt = time.UnixMilli(created).UTC()
+	if err := json.Unmarshal(tags, &sn.Tags); err != nil {
+		return nil, fmt.Errorf("malformed tags of snippet %q: %w", sn.Name, err)
+	}
+	return &sn, nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// saveSnippet saves code under name in ns
+func saveSnippet(t *testing.T, s *Store, ns Namespace, name, code string, tags ...string) *Snippet {
+	t.Helper()
+	sn := &Snippet{
+		Namespace: ns,
+		Name:      name,
+		AuthorID:  "u1",
+		CreatedAt: time.Now(),
+		Language:  "python",
+		Code:      code,
+		Tags:      tags,
+	}
+	if err := s.SaveSnippet(sn, 3); err != nil {
+		t.Fatalf("Failed to save snippet: %v", err)
+	}
+	return sn
+}
+
+func TestSnippetVersions(t *testing.T) {
+	s := openStore(t)
+	mine := UserNamespace("u1")
+	for i, code := range []string{"v1", "v2", "v3", "v4"} {
+		if sn := saveSnippet(t, s, mine, "diag", code); sn.Version != i+1 {
+			t.Fatalf("Expected version %d, got %d", i+1, sn.Version)
+		}
+	}
+
+	latest, err := s.Snippet(mine, "diag", 0)
+	if err != nil || latest.Code != "v4" || latest.Version != 4 {
+		t.Fatalf("Expected the latest version, got %+v, %v", latest, err)
+	}
+	if old, err := s.Snippet(mine, "diag", 2); err != nil || old.Code != "v2" {
+		t.Errorf("Expected version 2 on request, got %+v, %v", old, err)
+	}
+	// Only the three newest versions are kept
+	if _, err := s.Snippet(mine, "diag", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the oldest version to be pruned, got %v", err)
+	}
+	if versions, _ := s.SnippetVersions(mine, "diag"); len(versions) != 3 || versions[0] != 4 {
+		t.Errorf("Expected versions 4, 3 and 2, got %v", versions)
+	}
+	if _, err := s.Snippet(GuildNamespace("g1"), "diag", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected namespaces to be separate, got %v", err)
+	}
+}
+
+func TestSnippetsByTag(t *testing.T) {
+	s := openStore(t)
+	shared := GuildNamespace("g1")
+	saveSnippet(t, s, shared, "net", "old", "network")
+	saveSnippet(t, s, shared, "net", "new", "network", "debug")
+	saveSnippet(t, s, shared, "disk", "df", "debug")
+	saveSnippet(t, s, shared, "cpu", "top")
+
+	all, err := s.Snippets(shared, "")
+	if err != nil {
+		t.Fatalf("Failed to list snippets: %v", err)
+	}
+	if len(all) != 3 || all[0].Name != "cpu" || all[2].Code != "new" {
+		t.Errorf("Expected the latest version of each snippet by name, got %+v", all)
+	}
+	debug, _ := s.Snippets(shared, "debug")
+	if len(debug) != 2 || debug[0].Name != "disk" || debug[1].Name != "net" {
+		t.Errorf("Expected the snippets tagged debug, got %+v", debug)
+	}
+	if n, _ := s.CountSnippets(shared); n != 3 {
+		t.Errorf("Expected 3 snippet names, got %d", n)
+	}
+	if n, _ := s.DeleteSnippet(shared, "net"); n != 2 {
+		t.Errorf("Expected both versions deleted, got %d", n)
+	}
+}
+
+func TestForgetUserSnippets(t *testing.T) {
+	s := openStore(t)
+	saveSnippet(t, s, UserNamespace("u1"), "mine", "1")
+	saveSnippet(t, s, GuildNamespace("g1"), "shared", "2")
+
+	forgotten, err := s.ForgetUser("u1")
+	if err != nil {
+		t.Fatalf("Failed to forget user: %v", err)
+	}
+	if forgotten.Snippets != 1 || forgotten.Unattributed != 1 {
+		t.Errorf("Expected one snippet deleted and one unattributed, got %+v", forgotten)
+	}
+	if _, err := s.Snippet(UserNamespace("u1"), "mine", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected personal snippets to be deleted, got %v", err)
+	}
+	shared, err := s.Snippet(GuildNamespace("g1"), "shared", 0)
+	if err != nil || shared.AuthorID != "" {
+		t.Errorf("Expected shared snippets kept without an author, got %+v, %v", shared, err)
+	}
+}
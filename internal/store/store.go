@@ -0,0 +1,138 @@
+// Package store keeps user data the bot needs across restarts, such as
+// execution history, saved snippets, schedules and guild macros, in an
+// embedded SQLite database.
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	// Registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// schema creates the tables of every feature backed by the store
+const schema = `
+CREATE TABLE IF NOT EXISTS executions (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	record     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS executions_by_user ON executions (user_id, created_at);
+CREATE INDEX IF NOT EXISTS executions_by_time ON executions (created_at);
+CREATE TABLE IF NOT EXISTS snippets (
+	scope      TEXT NOT NULL,
+	owner      TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	version    INTEGER NOT NULL,
+	author_id  TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	language   TEXT NOT NULL,
+	code       TEXT NOT NULL,
+	tags       TEXT NOT NULL,
+	PRIMARY KEY (scope, owner, name, version)
+);
+CREATE INDEX IF NOT EXISTS snippets_by_author ON snippets (author_id);
+CREATE TABLE IF NOT EXISTS schedules (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id TEXT NOT NULL,
+	user_id  TEXT NOT NULL,
+	paused   INTEGER NOT NULL,
+	next_run INTEGER NOT NULL,
+	record   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS schedules_by_guild ON schedules (guild_id);
+CREATE INDEX IF NOT EXISTS schedules_due ON schedules (paused, next_run);
+CREATE TABLE IF NOT EXISTS macros (
+	guild_id   TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	author_id  TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	language   TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	PRIMARY KEY (guild_id, kind, name)
+);
+CREATE INDEX IF NOT EXISTS macros_by_author ON macros (author_id);
+`
+
+// Store is an embedded database of user data
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database at path
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	// SQLite allows one writer at a time; a single connection avoids
+	// busy errors between the bot's goroutines
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create store tables: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Forgotten counts what ForgetUser removed
+type Forgotten struct {
+	// Run history records deleted
+	Executions int64
+
+	// Personal snippet versions deleted
+	Snippets int64
+
+	// Schedules the user created, deleted
+	Schedules int64
+
+	// Guild snippets, aliases and templates kept with their author cleared
+	Unattributed int64
+}
+
+// ForgetUser deletes everything stored about a user. Snippets they shared
+// with a guild and aliases and templates they added stay for its other
+// members, but no longer name them as author.
+func (s *Store) ForgetUser(userID string) (Forgotten, error) {
+	var forgotten Forgotten
+	tx, err := s.db.Begin()
+	if err != nil {
+		return forgotten, fmt.Errorf("failed to delete user data: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, change := range []struct {
+		stmt  string
+		count *int64
+	}{
+		{"DELETE FROM executions WHERE user_id = ?", &forgotten.Executions},
+		{"DELETE FROM snippets WHERE scope = '" + ScopeUser + "' AND owner = ?", &forgotten.Snippets},
+		{"DELETE FROM schedules WHERE user_id = ?", &forgotten.Schedules},
+		{"UPDATE snippets SET author_id = '' WHERE author_id = ?", &forgotten.Unattributed},
+		{"UPDATE macros SET author_id = '' WHERE author_id = ?", &forgotten.Unattributed},
+	} {
+		res, err := tx.Exec(change.stmt, userID)
+		if err != nil {
+			return Forgotten{}, fmt.Errorf("failed to delete user data: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		*change.count += n
+	}
+	return forgotten, tx.Commit()
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}